@@ -0,0 +1,109 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package espeak
+
+import (
+	"sort"
+	"time"
+	"unicode/utf8"
+)
+
+// WordTiming timing of a word of the synthesized text.
+type WordTiming struct {
+	// Text of the word, as found in the input.
+	Text string `json:"text"`
+	// Offset byte offset of the word in the input.
+	Offset int `json:"offset"`
+	// RuneOffset offset of the word in the input, in runes.
+	RuneOffset int `json:"rune_offset"`
+	// Start index of the first sample of the word.
+	Start int `json:"start"`
+	// End index of the sample after the word's last.
+	End int `json:"end"`
+	// StartTime time at which the word starts.
+	StartTime time.Duration `json:"start_time"`
+	// EndTime time at which the word ends.
+	EndTime time.Duration `json:"end_time"`
+}
+
+// AlignWords builds the word timings of text, from the events espeak
+// reported while synthesizing it into nsamples samples at sampleRate. A word
+// ends where the next word, clause or message does.
+func AlignWords(text string, events []Event, sampleRate int32, nsamples int) []WordTiming {
+	idx := newTextIndex(text)
+	words := make([]WordTiming, 0)
+	for i, e := range events {
+		if e.Type != WordEvent {
+			continue
+		}
+		start := idx.byteOffset(e.TextPosition)
+		end := idx.byteOffset(e.TextPosition + e.Length)
+		w := WordTiming{
+			Text:       text[start:end],
+			Offset:     start,
+			RuneOffset: utf8.RuneCountInString(text[:start]),
+			Start:      e.Sample,
+			End:        nsamples,
+		}
+		for _, next := range events[i+1:] {
+			if next.Sample <= e.Sample {
+				continue
+			}
+			if next.Type == WordEvent || next.Type == EndEvent || next.Type == MsgTerminatedEvent {
+				w.End = next.Sample
+				break
+			}
+		}
+		if w.End > nsamples {
+			w.End = nsamples
+		}
+		w.StartTime = samplesToDuration(w.Start, sampleRate)
+		w.EndTime = samplesToDuration(w.End, sampleRate)
+		words = append(words, w)
+	}
+	return words
+}
+
+// textIndex maps espeak's character positions in a text into byte offsets.
+// espeak counts a character per UTF-8 sequence, or per byte where the text
+// isn't valid UTF-8, which is how ranging over a string decodes it.
+type textIndex []int
+
+func newTextIndex(text string) textIndex {
+	idx := make(textIndex, 0, len(text)+1)
+	for i := range text {
+		idx = append(idx, i)
+	}
+	return append(idx, len(text))
+}
+
+// byteOffset returns the byte offset of the character at pos, counted from
+// 1 as espeak does. Positions out of range are clamped.
+func (idx textIndex) byteOffset(pos int) int {
+	switch {
+	case pos < 1:
+		return 0
+	case pos > len(idx):
+		return idx[len(idx)-1]
+	default:
+		return idx[pos-1]
+	}
+}
+
+// position returns the position of the character at byte offset off,
+// counted from 1 as espeak does.
+func (idx textIndex) position(off int) int {
+	return sort.SearchInts(idx, off) + 1
+}
+
+func samplesToDuration(n int, sampleRate int32) time.Duration {
+	if sampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(n) * time.Second / time.Duration(sampleRate)
+}
+
+func durationToSamples(d time.Duration, sampleRate int32) int64 {
+	return int64(d) * int64(sampleRate) / int64(time.Second)
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package espeak
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAlignWords(t *testing.T) {
+	// "¡Hola" and "niño" have multibyte runes; espeak counts characters.
+	text := "¡Hola, señor niño!"
+	events := []Event{
+		{Type: SentenceEvent, TextPosition: 1, Sample: 0},
+		{Type: WordEvent, TextPosition: 1, Length: 5, Sample: 0},
+		{Type: EndEvent, TextPosition: 6, Sample: 1000},
+		{Type: WordEvent, TextPosition: 8, Length: 5, Sample: 1500},
+		{Type: WordEvent, TextPosition: 14, Length: 5, Sample: 3000},
+		{Type: MsgTerminatedEvent, Sample: 5000},
+	}
+	want := []WordTiming{
+		{Text: "¡Hola", Offset: 0, RuneOffset: 0, Start: 0, End: 1000},
+		{Text: "señor", Offset: 8, RuneOffset: 7, Start: 1500, End: 3000},
+		{Text: "niño!", Offset: 15, RuneOffset: 13, Start: 3000, End: 5000},
+	}
+	got := AlignWords(text, events, 1000, 6000)
+	if len(got) != len(want) {
+		t.Fatalf("expected %d words got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		want[i].StartTime = time.Duration(want[i].Start) * time.Millisecond
+		want[i].EndTime = time.Duration(want[i].End) * time.Millisecond
+		if got[i] != want[i] {
+			t.Errorf("word %d: expected %+v got %+v", i, want[i], got[i])
+		}
+	}
+
+	t.Run("invalid UTF-8", func(t *testing.T) {
+		// 8 bit text, one character per byte.
+		text := "ni\xf1o bien"
+		events := []Event{
+			{Type: WordEvent, TextPosition: 1, Length: 4, Sample: 0},
+			{Type: WordEvent, TextPosition: 6, Length: 4, Sample: 10},
+		}
+		got := AlignWords(text, events, 1000, 20)
+		if len(got) != 2 || got[0].Text != "ni\xf1o" || got[1].Text != "bien" {
+			t.Errorf("unexpected words: %+v", got)
+		}
+	})
+	t.Run("positions out of range", func(t *testing.T) {
+		events := []Event{{Type: WordEvent, TextPosition: 40, Length: 4, Sample: 0}}
+		got := AlignWords("short", events, 1000, 20)
+		if len(got) != 1 || got[0].Text != "" || got[0].End != 20 {
+			t.Errorf("unexpected words: %+v", got)
+		}
+	})
+}
+
+func TestSynthesize_words(t *testing.T) {
+	text := "Él habló con el niño"
+	r, err := Synthesize(context.Background(), text, ESSpainMale, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Él", "habló", "con", "el", "niño"}
+	if len(r.Words) != len(want) {
+		t.Fatalf("expected %d words got %d: %+v", len(want), len(r.Words), r.Words)
+	}
+	for i, w := range r.Words {
+		if w.Text != want[i] {
+			t.Errorf("word %d: expected %q got %q", i, want[i], w.Text)
+		}
+		if text[w.Offset:w.Offset+len(w.Text)] != w.Text {
+			t.Errorf("word %d: offset %d doesn't point to %q", i, w.Offset, w.Text)
+		}
+		if w.Start >= w.End || w.End > len(r.Samples) {
+			t.Errorf("word %d: bad sample range [%d, %d)", i, w.Start, w.End)
+		}
+	}
+}
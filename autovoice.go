@@ -0,0 +1,145 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package espeak
+
+import (
+	"strings"
+
+	"github.com/djangulo/go-espeak/langid"
+	"github.com/djangulo/go-espeak/textmap"
+)
+
+// AutoVoice picks the voice of each call by the language its text is
+// written in, identified with package langid, among the installed voices.
+type AutoVoice struct {
+	// Languages the text may be in, e.g. "en", "es". Default any langid
+	// identifies.
+	Languages []string
+	// MinConfidence of the identified language, 0 to 1, below which
+	// Fallback is used. Default 0.
+	MinConfidence float64
+	// Fallback voice, used when the language can't be identified with
+	// MinConfidence, or no installed voice speaks it. Default the call's
+	// voice.
+	Fallback *Voice
+}
+
+// DetectVoice returns the installed voice of the language text is written
+// in, along with the language identified and the confidence in it. The
+// synthesizer's voice is preferred if it speaks the language. a may be nil,
+// see AutoVoice for the voice returned when there's none to pick.
+func (s *Synthesizer) DetectVoice(text string, a *AutoVoice) (*Voice, langid.Guess, error) {
+	voice, _, err := s.resolve(nil, nil)
+	if err != nil {
+		return nil, langid.Guess{}, err
+	}
+	if a == nil {
+		a = &AutoVoice{}
+	}
+	return s.detectVoice(text, CharsAuto, voice, a)
+}
+
+// DetectVoice returns the installed voice of the language text is written
+// in. See Synthesizer.DetectVoice.
+func DetectVoice(text string, a *AutoVoice) (*Voice, langid.Guess, error) {
+	s, err := defaultSynthesizer()
+	if err != nil {
+		return nil, langid.Guess{}, err
+	}
+	return s.DetectVoice(text, a)
+}
+
+// resolveText is resolve, with the voice picked by the language of text
+// when params has AutoVoice.
+func (s *Synthesizer) resolveText(text string, flags FlagType, voice *Voice, params *Parameters) (*Voice, *Parameters, error) {
+	voice, params, err := s.resolve(voice, params)
+	if err != nil || params.AutoVoice == nil {
+		return voice, params, err
+	}
+	voice, _, err = s.detectVoice(text, flags, voice, params.AutoVoice)
+	return voice, params, err
+}
+
+// detectVoice returns the voice of the language of text, preferring voice,
+// which is also the fallback of last resort. With the SSML flag, markup is
+// left out of the identification.
+func (s *Synthesizer) detectVoice(text string, flags FlagType, voice *Voice, a *AutoVoice) (*Voice, langid.Guess, error) {
+	if flags&SSML != 0 {
+		var b strings.Builder
+		rewriteContent(text, true, func(content string) (string, *textmap.Map, error) {
+			b.WriteString(content)
+			return content, nil, nil
+		})
+		text = b.String()
+	}
+	var guess langid.Guess
+	if guesses := langid.New(a.Languages...).Detect(text); len(guesses) > 0 {
+		guess = guesses[0]
+	}
+	found, err := s.guessVoice(guess, voice, a)
+	return found, guess, err
+}
+
+// guessVoice returns the voice of the language of guess, see detectVoice.
+func (s *Synthesizer) guessVoice(guess langid.Guess, voice *Voice, a *AutoVoice) (*Voice, error) {
+	fallback := a.Fallback
+	if fallback == nil {
+		fallback = voice
+	}
+	if guess.Language == "" || guess.Confidence < a.MinConfidence {
+		return fallback, nil
+	}
+	if primaryLanguage(voice.Language()) == guess.Language {
+		return voice, nil
+	}
+	found, err := s.languageVoice(guess.Language)
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return fallback, nil
+	}
+	return found, nil
+}
+
+// languageVoice returns the installed voice espeak prefers for language,
+// e.g. "es" or "es-la", nil if there's none.
+func (s *Synthesizer) languageVoice(language string) (*Voice, error) {
+	language = strings.ToLower(language)
+	s.mu.Lock()
+	voice, ok := s.languageVoices[language]
+	s.mu.Unlock()
+	if ok {
+		return voice, nil
+	}
+	voices, err := s.ListVoices(&Voice{Languages: language})
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range voices {
+		// the spec matches by prefix, e.g. "es" to "es-la", but not "en"
+		// to "eo".
+		if l := strings.ToLower(v.Language()); l == language || strings.HasPrefix(l, language+"-") {
+			voice = v
+			break
+		}
+	}
+	s.mu.Lock()
+	if s.languageVoices == nil {
+		s.languageVoices = make(map[string]*Voice)
+	}
+	s.languageVoices[language] = voice
+	s.mu.Unlock()
+	return voice, nil
+}
+
+// primaryLanguage returns the primary language of code, e.g. "en" for
+// "en-us".
+func primaryLanguage(code string) string {
+	code = strings.ToLower(code)
+	if i := strings.IndexAny(code, "-_"); i > 0 {
+		code = code[:i]
+	}
+	return code
+}
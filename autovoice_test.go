@@ -0,0 +1,73 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package espeak
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSynthesizer_DetectVoice(t *testing.T) {
+	s, err := NewSynthesizer(Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, tt := range []struct {
+		name string
+		text string
+		a    *AutoVoice
+		want string
+		lang string
+	}{
+		{"spanish", "Hola, ¿cómo estás? Hace mucho frío esta mañana.", nil, "spanish", "es"},
+		{"german", "Guten Tag, wie geht es dir?", nil, "german", "de"},
+		{"own voice", "Hello, how are you today?", nil, s.Voice().Name, "en"},
+		{"no voice", "Ciao, come stai?", nil, s.Voice().Name, "it"},
+		{"no voice fallback", "Ciao, come stai?", &AutoVoice{Fallback: FRFranceMale}, "french", "it"},
+		{"allowed", "Hola, ¿cómo estás?", &AutoVoice{Languages: []string{"en", "fr"}}, s.Voice().Name, "en"},
+		{"low confidence", "Hola", &AutoVoice{MinConfidence: 1.1, Fallback: ESLatinMale}, "spanish-latin-am", "es"},
+		{"no letters", "1, 2, 3", &AutoVoice{Fallback: ESLatinMale}, "spanish-latin-am", ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			voice, guess, err := s.DetectVoice(tt.text, tt.a)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if voice.Name != tt.want {
+				t.Errorf("expected %q got %q", tt.want, voice.Name)
+			}
+			if guess.Language != tt.lang {
+				t.Errorf("expected %q got %q", tt.lang, guess.Language)
+			}
+		})
+	}
+}
+
+func TestParameters_WithAutoVoice(t *testing.T) {
+	s, err := NewSynthesizer(Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	text := "Hola, ¿cómo estás? <mark name=\"uno\"/> Hace mucho frío esta mañana."
+	want, err := s.synthesizeResult(context.Background(), text, CharsAuto|SSML, ESSpainMale, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.synthesizeResult(context.Background(), text, CharsAuto|SSML, ENUSMale, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Samples[0] == want.Samples[0] {
+		t.Fatalf("expected voices to sound different")
+	}
+	params := NewParameters().WithAutoVoice(&AutoVoice{})
+	got, err := s.synthesizeResult(context.Background(), text, CharsAuto|SSML, ENUSMale, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Samples) != len(want.Samples) || got.Samples[0] != want.Samples[0] {
+		t.Errorf("expected the samples of %q", ESSpainMale.Name)
+	}
+}
@@ -0,0 +1,594 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package espeak
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/djangulo/go-espeak/wav"
+)
+
+// BatchIndex the file, in params.Dir, where SynthBatch records the hash of
+// each file written, to skip them while they're up to date. It's written
+// every so many files, and once the batch ends, so an interrupted batch
+// keeps most of its work.
+const BatchIndex = ".espeak-batch.json"
+
+// batchIndexEvery files, or batchIndexInterval, whichever comes first,
+// between writes of the BatchIndex. Those written since the last one are
+// synthesized again if the process dies.
+var (
+	batchIndexEvery    = 100
+	batchIndexInterval = 5 * time.Second
+)
+
+// ManifestFormat the format of a batch manifest, or report.
+type ManifestFormat int
+
+const (
+	// ManifestCSV comma separated values, with a header naming the columns.
+	ManifestCSV ManifestFormat = iota
+	// ManifestJSONL a JSON object per line.
+	ManifestJSONL
+)
+
+// BatchItem a text to synthesize to a file of its own, a row of a manifest.
+// Nil overrides keep the batch's parameters.
+type BatchItem struct {
+	// ID of the item, unique in the batch.
+	ID string
+	// Text to synthesize.
+	Text string
+	// Voice name, identifier or language, e.g. "english-us", "europe/es" or
+	// "fr". Default the batch's.
+	Voice string
+	// Output file name, relative to params.Dir, appended with .wav. Default
+	// the ID, with characters other than letters, digits, '-', '_' and '.'
+	// replaced by '_'.
+	Output string
+
+	Rate, Volume, Pitch, Range, WordGap *int
+	AnnouncePunctuation                 *PunctType
+	AnnounceCapitals                    *Capitals
+	PunctuationList                     *string
+	SampleRate                          *int32
+}
+
+// ReadManifest reads the items of a manifest. Its columns, or keys, are
+// id, text, voice, output, rate, volume, pitch, range, word_gap,
+// punctuation and capitals, see PunctType.UnmarshalText and
+// Capitals.UnmarshalText, punctuation_list and sample_rate; id
+// and text are required. Empty values, and JSON nulls, are left unset.
+// Errors wrap ErrManifest, and tell the row, or line, they were found in.
+func ReadManifest(r io.Reader, format ManifestFormat) ([]BatchItem, error) {
+	var items []BatchItem
+	add := func(where string, fields map[string]string) error {
+		item, err := manifestItem(fields)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrManifest, where, err)
+		}
+		items = append(items, item)
+		return nil
+	}
+
+	switch format {
+	case ManifestCSV:
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		header, err := cr.Read()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrManifest, err)
+		}
+		for i := range header {
+			header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+		}
+		if len(header) > 0 {
+			header[0] = strings.TrimPrefix(header[0], "\ufeff")
+		}
+		for row := 1; ; row++ {
+			record, err := cr.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrManifest, err)
+			}
+			if len(record) > len(header) {
+				return nil, fmt.Errorf("%w: row %d: %d values for %d columns", ErrManifest, row, len(record), len(header))
+			}
+			fields := make(map[string]string, len(record))
+			for i, v := range record {
+				fields[header[i]] = v
+			}
+			if err := add(fmt.Sprintf("row %d", row), fields); err != nil {
+				return nil, err
+			}
+		}
+	case ManifestJSONL:
+		sc := bufio.NewScanner(r)
+		sc.Buffer(nil, 1<<20)
+		for line := 1; sc.Scan(); line++ {
+			data := strings.TrimSpace(sc.Text())
+			if data == "" {
+				continue
+			}
+			d := json.NewDecoder(strings.NewReader(data))
+			d.UseNumber()
+			var obj map[string]interface{}
+			if err := d.Decode(&obj); err != nil {
+				return nil, fmt.Errorf("%w: line %d: %v", ErrManifest, line, err)
+			}
+			fields := make(map[string]string, len(obj))
+			for k, v := range obj {
+				switch v := v.(type) {
+				case nil:
+				case string:
+					fields[k] = v
+				case json.Number:
+					fields[k] = v.String()
+				default:
+					return nil, fmt.Errorf("%w: line %d: %s is not a string or number", ErrManifest, line, k)
+				}
+			}
+			if err := add(fmt.Sprintf("line %d", line), fields); err != nil {
+				return nil, err
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown format %d", ErrManifest, format)
+	}
+	return items, nil
+}
+
+// manifestItem returns the item of the fields of a manifest row.
+func manifestItem(fields map[string]string) (BatchItem, error) {
+	var item BatchItem
+	integer := func(name, v string) (*int, error) {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s %q is not an integer", name, v)
+		}
+		return &n, nil
+	}
+	// sorted, for the error reported not to vary from run to run.
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v := fields[name]
+		if name != "text" && name != "punctuation_list" {
+			v = strings.TrimSpace(v)
+		}
+		if v == "" {
+			continue
+		}
+		var err error
+		switch name {
+		case "id":
+			item.ID = v
+		case "text":
+			item.Text = v
+		case "voice":
+			item.Voice = v
+		case "output":
+			item.Output = v
+		case "rate":
+			item.Rate, err = integer(name, v)
+		case "volume":
+			item.Volume, err = integer(name, v)
+		case "pitch":
+			item.Pitch, err = integer(name, v)
+		case "range":
+			item.Range, err = integer(name, v)
+		case "word_gap":
+			item.WordGap, err = integer(name, v)
+		case "punctuation":
+			item.AnnouncePunctuation = new(PunctType)
+			err = item.AnnouncePunctuation.UnmarshalText([]byte(v))
+		case "capitals":
+			item.AnnounceCapitals = new(Capitals)
+			err = item.AnnounceCapitals.UnmarshalText([]byte(v))
+		case "punctuation_list":
+			item.PunctuationList = &v
+		case "sample_rate":
+			var n *int
+			if n, err = integer(name, v); err == nil {
+				if *n < 0 {
+					return item, fmt.Errorf("negative sample_rate %d", *n)
+				}
+				rate := int32(*n)
+				item.SampleRate = &rate
+			}
+		default:
+			return item, fmt.Errorf("unknown column %q", name)
+		}
+		if err != nil {
+			return item, err
+		}
+	}
+	if item.ID == "" {
+		return item, fmt.Errorf("no id")
+	}
+	if strings.TrimSpace(item.Text) == "" {
+		return item, fmt.Errorf("no text")
+	}
+	return item, nil
+}
+
+// parameters returns params with the overrides of item.
+func (item *BatchItem) parameters(params *Parameters) *Parameters {
+	p := *params
+	set := func(dst *int, src *int) {
+		if src != nil {
+			*dst = *src
+		}
+	}
+	set(&p.Rate, item.Rate)
+	set(&p.Volume, item.Volume)
+	set(&p.Pitch, item.Pitch)
+	set(&p.Range, item.Range)
+	set(&p.WordGap, item.WordGap)
+	if item.AnnouncePunctuation != nil {
+		p.AnnouncePunctuation = *item.AnnouncePunctuation
+	}
+	if item.AnnounceCapitals != nil {
+		p.AnnounceCapitals = *item.AnnounceCapitals
+	}
+	if item.PunctuationList != nil {
+		p.punctList = *item.PunctuationList
+	}
+	if item.SampleRate != nil {
+		p.SampleRate = *item.SampleRate
+	}
+	return &p
+}
+
+// output returns the file name of item.
+func (item *BatchItem) output() (string, error) {
+	name := item.Output
+	if name == "" {
+		name = strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+				return r
+			}
+			return '_'
+		}, item.ID)
+	}
+	name = filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(name) || name == "." || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: output %q is outside the directory", ErrManifest, item.Output)
+	}
+	return ensureWavSuffix(name), nil
+}
+
+// BatchOptions how a batch is synthesized.
+type BatchOptions struct {
+	// Workers items synthesized at once. espeak synthesizes one at a time,
+	// more workers overlap that with resampling and writing. Default 1.
+	Workers int
+	// Force synthesizes every item, up to date or not.
+	Force bool
+	// Progress is called with the result of each item, as it's done, one at
+	// a time.
+	Progress func(BatchResult)
+}
+
+// BatchResult the outcome of an item of a batch.
+type BatchResult struct {
+	// ID of the item.
+	ID string
+	// Output path of the file, in params.Dir.
+	Output string
+	// Voice the item was spoken with.
+	Voice string
+	// Skipped the file was up to date.
+	Skipped bool
+	// Samples in the file, at SampleRate, lasting Duration.
+	Samples    int
+	SampleRate int32
+	Duration   time.Duration
+	// Elapsed synthesizing and writing the file.
+	Elapsed time.Duration
+	// Err synthesizing the item, if any.
+	Err error
+}
+
+// batchEntry the record of a file in the BatchIndex.
+type batchEntry struct {
+	Hash       string `json:"hash"`
+	Size       int64  `json:"size"`
+	Samples    int    `json:"samples"`
+	SampleRate int32  `json:"sample_rate"`
+}
+
+// SynthBatch synthesizes each of items, with GenSamples, to a .wav file in
+// params.Dir, named after its Output or ID. An item whose file was written
+// by an earlier call, from the same text, voice and parameters, is skipped,
+// see BatchIndex. Results are in the order of items, each with its own
+// error; the error returned is for the batch as a whole, e.g. ctx.Err() if
+// ctx is done before every item is.
+func (s *Synthesizer) SynthBatch(ctx context.Context, items []BatchItem, voice *Voice, params *Parameters, opts *BatchOptions) ([]BatchResult, error) {
+	voice, params, err := s.resolve(voice, params)
+	if err != nil {
+		return nil, err
+	}
+	if s.plays() {
+		return nil, ErrOutputMode
+	}
+	if opts == nil {
+		opts = &BatchOptions{}
+	}
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	if err := os.MkdirAll(params.Dir, 0755); err != nil {
+		return nil, err
+	}
+	index := make(map[string]batchEntry)
+	ipath := filepath.Join(params.Dir, BatchIndex)
+	if data, err := ioutil.ReadFile(ipath); err == nil {
+		// a broken index only costs synthesizing again.
+		json.Unmarshal(data, &index)
+	}
+
+	results := make([]BatchResult, len(items))
+	todo := make([]int, 0, len(items))
+	ids := make(map[string]int)
+	outputs := make(map[string]int)
+	for i, item := range items {
+		r := &results[i]
+		r.ID = item.ID
+		name, err := item.output()
+		if err != nil {
+			r.Err = err
+			continue
+		}
+		r.Output = filepath.Join(params.Dir, name)
+		if j, ok := ids[item.ID]; ok {
+			r.Err = fmt.Errorf("%w: id %q of item %d", ErrManifest, item.ID, j+1)
+			continue
+		}
+		if j, ok := outputs[name]; ok {
+			r.Err = fmt.Errorf("%w: output %q of item %d", ErrManifest, name, j+1)
+			continue
+		}
+		ids[item.ID] = i
+		outputs[name] = i
+		todo = append(todo, i)
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		jobs     = make(chan int)
+		indexErr error
+		// entries not yet written, and when the index last was.
+		unwritten int
+		written   = time.Now()
+	)
+	writeIndex := func() {
+		if err := writeJournal(ipath, index); err != nil && indexErr == nil {
+			indexErr = err
+		}
+		unwritten, written = 0, time.Now()
+	}
+	done := func(i int, name string, entry *batchEntry) {
+		mu.Lock()
+		defer mu.Unlock()
+		if entry != nil && !results[i].Skipped {
+			index[name] = *entry
+			unwritten++
+			if unwritten >= batchIndexEvery || time.Since(written) >= batchIndexInterval {
+				writeIndex()
+			}
+		}
+		if opts.Progress != nil {
+			opts.Progress(results[i])
+		}
+	}
+	for n := 0; n < workers; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				name, _ := items[i].output()
+				mu.Lock()
+				prev, ok := index[name]
+				mu.Unlock()
+				if opts.Force {
+					ok = false
+				}
+				entry := s.batchItem(ctx, &items[i], &results[i], voice, params, prev, ok)
+				done(i, name, entry)
+			}
+		}()
+	}
+	for _, i := range todo {
+		if ctx.Err() != nil {
+			results[i].Err = ctx.Err()
+			continue
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	if unwritten > 0 {
+		writeIndex()
+	}
+	if indexErr != nil {
+		return results, indexErr
+	}
+	return results, ctx.Err()
+}
+
+// SynthBatch synthesizes a batch through the default synthesizer. See
+// Synthesizer.SynthBatch.
+func SynthBatch(ctx context.Context, items []BatchItem, voice *Voice, params *Parameters, opts *BatchOptions) ([]BatchResult, error) {
+	s, err := defaultSynthesizer()
+	if err != nil {
+		return nil, err
+	}
+	return s.SynthBatch(ctx, items, voice, params, opts)
+}
+
+// batchItem synthesizes item into r, unless prev, if ok, is its file's
+// entry in the index and it's up to date. Returns the entry of the file, nil
+// if it wasn't written.
+func (s *Synthesizer) batchItem(ctx context.Context, item *BatchItem, r *BatchResult, voice *Voice, params *Parameters, prev batchEntry, ok bool) *batchEntry {
+	start := time.Now()
+	defer func() {
+		r.Elapsed = time.Since(start)
+	}()
+	if item.Voice != "" {
+		if voice, r.Err = s.FindVoice(item.Voice); r.Err != nil {
+			return nil
+		}
+	}
+	params = item.parameters(params)
+	voice, params, r.Err = s.resolveText(item.Text, CharsAuto, voice, params)
+	if r.Err != nil {
+		return nil
+	}
+	// the voice is picked, and part of the hash.
+	p := *params
+	p.AutoVoice = nil
+	r.Voice = voice.Name
+	rate := s.outputRate(&p)
+	hash := batchHash(item.Text, voice, &p, rate)
+	if ok && prev.Hash == hash {
+		if fi, err := os.Stat(r.Output); err == nil && fi.Size() == prev.Size {
+			r.Skipped = true
+			r.Samples = prev.Samples
+			r.SampleRate = prev.SampleRate
+			r.Duration = samplesToDuration(prev.Samples, prev.SampleRate)
+			return &prev
+		}
+	}
+
+	samples, err := s.GenSamplesContext(ctx, item.Text, voice, &p)
+	if err != nil {
+		r.Err = err
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(r.Output), 0755); err != nil {
+		r.Err = err
+		return nil
+	}
+	// written aside, so an interrupted write never passes for the file.
+	tmp := r.Output + ".tmp"
+	var size uint64
+	err = writeFile(tmp, func(f *os.File) error {
+		w := wav.NewWriter(f, rate)
+		if _, err := w.WriteSamples(samples); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		size = w.Size()
+		return nil
+	})
+	if err == nil {
+		err = os.Rename(tmp, r.Output)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		r.Err = err
+		return nil
+	}
+	r.Samples = len(samples)
+	r.SampleRate = rate
+	r.Duration = samplesToDuration(len(samples), rate)
+	return &batchEntry{Hash: hash, Size: int64(size), Samples: len(samples), SampleRate: rate}
+}
+
+// batchHash returns the hash of text synthesized with voice and p to rate.
+func batchHash(text string, voice *Voice, p *Parameters, rate int32) string {
+	h := sha256.New()
+	hashParameters(h, voice, p, rate)
+	io.WriteString(h, text)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// WriteBatchReport writes results in format, a row, or object, per result,
+// with the columns id, output, voice, status (written, skipped or failed),
+// samples, sample_rate, duration and elapsed, in seconds, and error.
+func WriteBatchReport(w io.Writer, results []BatchResult, format ManifestFormat) error {
+	columns := []string{"id", "output", "voice", "status", "samples", "sample_rate", "duration", "elapsed", "error"}
+	row := func(r *BatchResult) []string {
+		status, msg := "written", ""
+		switch {
+		case r.Err != nil:
+			status, msg = "failed", r.Err.Error()
+		case r.Skipped:
+			status = "skipped"
+		}
+		return []string{
+			r.ID, r.Output, r.Voice, status,
+			strconv.Itoa(r.Samples), strconv.Itoa(int(r.SampleRate)),
+			strconv.FormatFloat(r.Duration.Seconds(), 'f', 3, 64),
+			strconv.FormatFloat(r.Elapsed.Seconds(), 'f', 3, 64),
+			msg,
+		}
+	}
+
+	switch format {
+	case ManifestCSV:
+		cw := csv.NewWriter(w)
+		cw.Write(columns)
+		for i := range results {
+			cw.Write(row(&results[i]))
+		}
+		cw.Flush()
+		return cw.Error()
+	case ManifestJSONL:
+		enc := json.NewEncoder(w)
+		for i := range results {
+			r := &results[i]
+			values := row(r)
+			obj := map[string]interface{}{}
+			for j, c := range columns {
+				obj[c] = values[j]
+			}
+			obj["samples"] = r.Samples
+			obj["sample_rate"] = r.SampleRate
+			obj["duration"] = r.Duration.Seconds()
+			obj["elapsed"] = r.Elapsed.Seconds()
+			if r.Err == nil {
+				delete(obj, "error")
+			}
+			if err := enc.Encode(obj); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown format %d", ErrManifest, format)
+	}
+}
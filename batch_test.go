@@ -0,0 +1,260 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package espeak
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/djangulo/go-espeak/lexicon"
+)
+
+func TestReadManifest(t *testing.T) {
+	rate, punct, srate := 150, PunctSome, int32(8000)
+	want := []BatchItem{
+		{ID: "greeting", Text: "Hello, world", Voice: "en"},
+		{ID: "menu/1", Text: "Press one", Output: "menu-1", Rate: &rate, AnnouncePunctuation: &punct, SampleRate: &srate},
+	}
+	for _, tt := range []struct {
+		name     string
+		format   ManifestFormat
+		manifest string
+	}{
+		{"csv", ManifestCSV, "id,text,voice,output,rate,punctuation,sample_rate\n" +
+			"greeting,\"Hello, world\",en,,,,\n" +
+			"menu/1,Press one,,menu-1,150,some,8000\n"},
+		{"jsonl", ManifestJSONL, `{"id": "greeting", "text": "Hello, world", "voice": "en"}` + "\n\n" +
+			`{"id": "menu/1", "text": "Press one", "output": "menu-1", "rate": 150, "punctuation": "2", "sample_rate": 8000, "volume": null}` + "\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadManifest(strings.NewReader(tt.manifest), tt.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			gj, _ := json.Marshal(got)
+			wj, _ := json.Marshal(want)
+			if !bytes.Equal(gj, wj) {
+				t.Errorf("expected %s got %s", wj, gj)
+			}
+		})
+	}
+
+	for _, tt := range []struct {
+		name     string
+		format   ManifestFormat
+		manifest string
+		want     string
+	}{
+		{"no id", ManifestCSV, "id,text\n,Hello\n", "row 1: no id"},
+		{"no text", ManifestJSONL, `{"id": "a"}`, "line 1: no text"},
+		{"unknown column", ManifestCSV, "id,text,speed\na,Hello,2\n", `row 1: unknown column "speed"`},
+		{"not an integer", ManifestJSONL, `{"id": "a", "text": "Hello", "rate": "fast"}`, `rate "fast" is not an integer`},
+		{"bad punctuation", ManifestCSV, "id,text,punctuation\na,Hello,most\n", `unknown punctuation "most"`},
+		{"too many values", ManifestCSV, "id,text\na,Hello,extra\n", "row 1: 3 values for 2 columns"},
+		{"not an object", ManifestJSONL, `["a", "Hello"]`, "line 1"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadManifest(strings.NewReader(tt.manifest), tt.format)
+			if !errors.Is(err, ErrManifest) {
+				t.Fatalf("expected %v got %v", ErrManifest, err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected %q in %q", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSynthesizer_SynthBatch(t *testing.T) {
+	s, err := NewSynthesizer(Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	dir, err := ioutil.TempDir("", "go-espeak-batch-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	params := NewParameters(WithDir(dir))
+	ctx := context.Background()
+
+	rate, srate := 120, s.SampleRate()/2
+	items := []BatchItem{
+		{ID: "greeting", Text: "Hello world"},
+		{ID: "menu/1", Text: "Hola", Voice: "es", Rate: &rate, SampleRate: &srate},
+		{ID: "custom", Text: "Goodbye", Output: "sub/bye"},
+		{ID: "greeting", Text: "Again"},
+		{ID: "escape", Text: "Out", Output: "../out"},
+		{ID: "klingon", Text: "Qapla", Voice: "klingon"},
+	}
+	var progress int
+	results, err := s.SynthBatch(ctx, items, nil, params, &BatchOptions{
+		Workers:  3,
+		Progress: func(BatchResult) { progress++ },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != len(items) {
+		t.Fatalf("expected %d got %d", len(items), len(results))
+	}
+	if progress != 4 {
+		t.Errorf("expected %d got %d", 4, progress)
+	}
+	for i, want := range []string{"greeting.wav", "menu_1.wav", filepath.Join("sub", "bye.wav")} {
+		r := results[i]
+		if r.Err != nil {
+			t.Errorf("%s: unexpected error: %v", r.ID, r.Err)
+			continue
+		}
+		if r.Output != filepath.Join(dir, want) {
+			t.Errorf("expected %q got %q", filepath.Join(dir, want), r.Output)
+		}
+		if _, err := os.Stat(r.Output); err != nil {
+			t.Errorf("%s: %v", r.ID, err)
+		}
+		if r.Samples == 0 || r.Duration == 0 || r.Skipped {
+			t.Errorf("%s: expected samples, got %+v", r.ID, r)
+		}
+	}
+	if results[1].SampleRate != srate || results[1].Voice != "spanish" {
+		t.Errorf("expected %d and %q got %d and %q", srate, "spanish", results[1].SampleRate, results[1].Voice)
+	}
+	for _, i := range []int{3, 4} {
+		if !errors.Is(results[i].Err, ErrManifest) {
+			t.Errorf("%s: expected %v got %v", results[i].ID, ErrManifest, results[i].Err)
+		}
+	}
+	if !errors.Is(results[5].Err, EErrNotFound) {
+		t.Errorf("expected %v got %v", EErrNotFound, results[5].Err)
+	}
+
+	// up to date, but for the item changed.
+	items[2].Text = "Farewell"
+	again, err := s.SynthBatch(ctx, items[:3], nil, params, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, want := range []bool{true, true, false} {
+		if again[i].Skipped != want {
+			t.Errorf("%s: expected skipped %t got %t", again[i].ID, want, again[i].Skipped)
+		}
+		if again[i].Samples == 0 {
+			t.Errorf("%s: expected samples", again[i].ID)
+		}
+	}
+	if again[0].Samples != results[0].Samples {
+		t.Errorf("expected %d got %d", results[0].Samples, again[0].Samples)
+	}
+
+	forced, err := s.SynthBatch(ctx, items[:1], nil, params, &BatchOptions{Force: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if forced[0].Skipped {
+		t.Errorf("expected %s to be synthesized", forced[0].ID)
+	}
+
+	// a lexicon of other entries makes other audio.
+	lex, err := lexicon.New(lexicon.Entry{Word: "Hello", Replacement: "Hallo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withLexicon := NewParameters(WithDir(dir))
+	withLexicon.Lexicon = lex
+	relexed, err := s.SynthBatch(ctx, items[:1], nil, withLexicon, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if relexed[0].Skipped {
+		t.Errorf("expected %s to be synthesized with the lexicon", relexed[0].ID)
+	}
+	lex, err = lexicon.New(lexicon.Entry{Word: "Hello", Replacement: "Hullo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withLexicon.Lexicon = lex
+	if relexed, err = s.SynthBatch(ctx, items[:1], nil, withLexicon, nil); err != nil || relexed[0].Skipped {
+		t.Errorf("expected %s to be synthesized with the other lexicon, got %v", relexed[0].ID, err)
+	}
+
+	// the index is written every batchIndexEvery files, and at the end.
+	defer func(every int) { batchIndexEvery = every }(batchIndexEvery)
+	batchIndexEvery = 2
+	incremental := NewParameters(WithDir(filepath.Join(dir, "incremental")))
+	ipath := filepath.Join(incremental.Dir, BatchIndex)
+	indexed := func() int {
+		data, err := ioutil.ReadFile(ipath)
+		if os.IsNotExist(err) {
+			return 0
+		}
+		var index map[string]batchEntry
+		if err == nil {
+			err = json.Unmarshal(data, &index)
+		}
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		return len(index)
+	}
+	var counts []int
+	_, err = s.SynthBatch(ctx, items[:3], nil, incremental, &BatchOptions{
+		Progress: func(BatchResult) { counts = append(counts, indexed()) },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fmt.Sprint(counts) != "[0 2 2]" {
+		t.Errorf("expected [0 2 2] files indexed got %v", counts)
+	}
+	if got := indexed(); got != 3 {
+		t.Errorf("expected 3 files indexed got %d", got)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	results, err = s.SynthBatch(cancelled, items[:1], nil, NewParameters(WithDir(filepath.Join(dir, "cancelled"))), nil)
+	if !errors.Is(err, context.Canceled) || !errors.Is(results[0].Err, context.Canceled) {
+		t.Errorf("expected %v got %v and %v", context.Canceled, err, results[0].Err)
+	}
+}
+
+func TestWriteBatchReport(t *testing.T) {
+	results := []BatchResult{
+		{ID: "a", Output: "a.wav", Voice: "english", Samples: 22050, SampleRate: 22050, Duration: 1e9},
+		{ID: "b", Output: "b.wav", Voice: "english", Skipped: true},
+		{ID: "c", Output: "c.wav", Err: ErrEmptyText},
+	}
+	for _, tt := range []struct {
+		name   string
+		format ManifestFormat
+		want   string
+	}{
+		{"csv", ManifestCSV, "id,output,voice,status,samples,sample_rate,duration,elapsed,error\n" +
+			"a,a.wav,english,written,22050,22050,1.000,0.000,\n" +
+			"b,b.wav,english,skipped,0,0,0.000,0.000,\n" +
+			"c,c.wav,,failed,0,0,0.000,0.000,text is empty\n"},
+		{"jsonl", ManifestJSONL, `{"duration":1,"elapsed":0,"id":"a","output":"a.wav","sample_rate":22050,"samples":22050,"status":"written","voice":"english"}` + "\n" +
+			`{"duration":0,"elapsed":0,"id":"b","output":"b.wav","sample_rate":0,"samples":0,"status":"skipped","voice":"english"}` + "\n" +
+			`{"duration":0,"elapsed":0,"error":"text is empty","id":"c","output":"c.wav","sample_rate":0,"samples":0,"status":"failed","voice":""}` + "\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := WriteBatchReport(&b, results, tt.format); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("expected %q got %q", tt.want, b.String())
+			}
+		})
+	}
+}
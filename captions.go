@@ -0,0 +1,246 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package espeak
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Caption defaults.
+const (
+	// DefaultMaxLineLength runes per caption line.
+	DefaultMaxLineLength = 42
+	// DefaultMaxLines per cue.
+	DefaultMaxLines = 2
+	// DefaultMaxCueDuration a cue is shown for.
+	DefaultMaxCueDuration = 7 * time.Second
+)
+
+// CaptionOptions how the words of a text are laid out into cues.
+type CaptionOptions struct {
+	// MaxLineLength runes per line. A longer word gets a line of its own.
+	// Default DefaultMaxLineLength.
+	MaxLineLength int
+	// MaxLines per cue. Default DefaultMaxLines.
+	MaxLines int
+	// MaxDuration a cue is shown for. A cue ends before the word that would
+	// make it longer. Default DefaultMaxCueDuration.
+	MaxDuration time.Duration
+	// Sentences cues end at the end of sentences, none spans two.
+	Sentences bool
+	// Karaoke WebVTT cues have a timestamp before each word, see
+	// WriteWebVTT.
+	Karaoke bool
+}
+
+// Cue a caption, shown from Start to End.
+type Cue struct {
+	Start, End time.Duration
+	// Lines of the cue, the words on each. The Text of a word includes the
+	// punctuation around it.
+	Lines [][]WordTiming
+}
+
+// Text returns the lines of c, separated by newlines.
+func (c *Cue) Text() string {
+	lines := make([]string, len(c.Lines))
+	for i, line := range c.Lines {
+		words := make([]string, len(line))
+		for j, w := range line {
+			words[j] = w.Text
+		}
+		lines[i] = strings.Join(words, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Captions lays the words of text, in language, out into cues, as timed by
+// words, see AlignWords. opts may be nil.
+func Captions(text, language string, words []WordTiming, opts *CaptionOptions) []Cue {
+	if opts == nil {
+		opts = &CaptionOptions{}
+	}
+	maxLen, maxLines, maxDur := opts.MaxLineLength, opts.MaxLines, opts.MaxDuration
+	if maxLen <= 0 {
+		maxLen = DefaultMaxLineLength
+	}
+	if maxLines <= 0 {
+		maxLines = DefaultMaxLines
+	}
+	if maxDur <= 0 {
+		maxDur = DefaultMaxCueDuration
+	}
+	var breaks []textBreak
+	if opts.Sentences {
+		breaks = sentenceBreaks(text, language)
+	}
+
+	var (
+		cues []Cue
+		cur  *Cue
+		// length in runes of the last line of cur.
+		lineLen int
+		// sentence the last word of cur ended a sentence.
+		sentence bool
+	)
+	for i, w := range words {
+		// the word, with the punctuation up to the next one.
+		start, end := w.Offset, len(text)
+		if i == 0 {
+			start = 0
+		}
+		if i+1 < len(words) {
+			end = words[i+1].Offset
+		}
+		token := strings.Join(strings.Fields(text[start:end]), " ")
+		if token == "" {
+			continue
+		}
+		w.Text = token
+		n := utf8.RuneCountInString(token)
+
+		if cur != nil && (sentence || w.EndTime-cur.Start > maxDur) {
+			cues = append(cues, *cur)
+			cur = nil
+		}
+		switch {
+		case cur == nil:
+			cur = &Cue{Start: w.StartTime, Lines: [][]WordTiming{{w}}}
+			lineLen = n
+		case lineLen+1+n <= maxLen:
+			last := len(cur.Lines) - 1
+			cur.Lines[last] = append(cur.Lines[last], w)
+			lineLen += 1 + n
+		case len(cur.Lines) < maxLines:
+			cur.Lines = append(cur.Lines, []WordTiming{w})
+			lineLen = n
+		default:
+			cues = append(cues, *cur)
+			cur = &Cue{Start: w.StartTime, Lines: [][]WordTiming{{w}}}
+			lineLen = n
+		}
+		cur.End = w.EndTime
+		if cur.End-cur.Start > maxDur {
+			// a single word longer than a cue.
+			cur.End = cur.Start + maxDur
+		}
+
+		sentence = false
+		for _, br := range breaks {
+			if br.off > w.Offset && br.off <= end {
+				sentence = true
+				break
+			}
+		}
+	}
+	if cur != nil {
+		cues = append(cues, *cur)
+	}
+	return cues
+}
+
+// WriteSRT writes cues to w as a SubRip (.srt) file.
+func WriteSRT(w io.Writer, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	for i, c := range cues {
+		fmt.Fprintf(bw, "%d\n%s --> %s\n%s\n\n", i+1, captionTime(c.Start, ','), captionTime(c.End, ','), c.Text())
+	}
+	return bw.Flush()
+}
+
+// WriteWebVTT writes cues to w as a WebVTT (.vtt) file. If karaoke, each
+// word of a cue but the first is preceded by a timestamp of when it's
+// spoken, for players to highlight it.
+func WriteWebVTT(w io.Writer, cues []Cue, karaoke bool) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("WEBVTT\n\n")
+	for _, c := range cues {
+		fmt.Fprintf(bw, "%s --> %s\n", captionTime(c.Start, '.'), captionTime(c.End, '.'))
+		if !karaoke {
+			bw.WriteString(webVTTEscaper.Replace(c.Text()) + "\n\n")
+			continue
+		}
+		for i, line := range c.Lines {
+			for j, word := range line {
+				if j > 0 {
+					bw.WriteByte(' ')
+				}
+				if i > 0 || j > 0 {
+					fmt.Fprintf(bw, "<%s>", captionTime(word.StartTime, '.'))
+				}
+				bw.WriteString(webVTTEscaper.Replace(word.Text))
+			}
+			bw.WriteByte('\n')
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+var webVTTEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// captionTime formats d as hh:mm:ss followed by sep and milliseconds.
+func captionTime(d time.Duration, sep byte) string {
+	if d < 0 {
+		d = 0
+	}
+	ms := d / time.Millisecond
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// synthCaptions synthesizes text, returning its samples and cues.
+func (s *Synthesizer) synthCaptions(ctx context.Context, text string, voice *Voice, params *Parameters, opts *CaptionOptions) ([]int16, []Cue, error) {
+	r, err := s.Synthesize(ctx, text, voice, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	// in the language of the voice AutoVoice picked, if set.
+	return r.Samples, Captions(text, r.Voice.Language(), r.Words, opts), nil
+}
+
+// GenSamplesSRT is like GenSamplesContext, but also writes captions of text
+// to captions, as a SubRip file. opts may be nil.
+func (s *Synthesizer) GenSamplesSRT(ctx context.Context, text string, voice *Voice, params *Parameters, captions io.Writer, opts *CaptionOptions) ([]int16, error) {
+	samples, cues, err := s.synthCaptions(ctx, text, voice, params, opts)
+	if err != nil {
+		return nil, err
+	}
+	return samples, WriteSRT(captions, cues)
+}
+
+// GenSamplesWebVTT is like GenSamplesContext, but also writes captions of
+// text to captions, as a WebVTT file. opts may be nil.
+func (s *Synthesizer) GenSamplesWebVTT(ctx context.Context, text string, voice *Voice, params *Parameters, captions io.Writer, opts *CaptionOptions) ([]int16, error) {
+	samples, cues, err := s.synthCaptions(ctx, text, voice, params, opts)
+	if err != nil {
+		return nil, err
+	}
+	return samples, WriteWebVTT(captions, cues, opts != nil && opts.Karaoke)
+}
+
+// GenSamplesSRT generates samples and SubRip captions through the default
+// synthesizer. See Synthesizer.GenSamplesSRT.
+func GenSamplesSRT(ctx context.Context, text string, voice *Voice, params *Parameters, captions io.Writer, opts *CaptionOptions) ([]int16, error) {
+	s, err := defaultSynthesizer()
+	if err != nil {
+		return nil, err
+	}
+	return s.GenSamplesSRT(ctx, text, voice, params, captions, opts)
+}
+
+// GenSamplesWebVTT generates samples and WebVTT captions through the
+// default synthesizer. See Synthesizer.GenSamplesWebVTT.
+func GenSamplesWebVTT(ctx context.Context, text string, voice *Voice, params *Parameters, captions io.Writer, opts *CaptionOptions) ([]int16, error) {
+	s, err := defaultSynthesizer()
+	if err != nil {
+		return nil, err
+	}
+	return s.GenSamplesWebVTT(ctx, text, voice, params, captions, opts)
+}
@@ -0,0 +1,168 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package espeak
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// timeWords returns the timings of the words of text, spoken one every
+// 500ms.
+func timeWords(text string) []WordTiming {
+	var words []WordTiming
+	off := 0
+	for _, f := range strings.Fields(text) {
+		off += strings.Index(text[off:], f)
+		start := time.Duration(len(words)) * 500 * time.Millisecond
+		words = append(words, WordTiming{Text: strings.Trim(f, `".,!?`), Offset: off, StartTime: start, EndTime: start + 500*time.Millisecond})
+		off += len(f)
+	}
+	return words
+}
+
+func TestCaptions(t *testing.T) {
+	text := `"Hello there," she said. It is a fine day for a walk in the park, isn't it?`
+	for _, tt := range []struct {
+		name string
+		opts *CaptionOptions
+		want []string
+	}{
+		{"defaults", nil, []string{
+			// 8.5s of words, longer than a cue.
+			"\"Hello there,\" she said. It is a fine day\nfor a walk in the",
+			"park, isn't it?",
+		}},
+		{"line length", &CaptionOptions{MaxLineLength: 20}, []string{
+			"\"Hello there,\" she\nsaid. It is a fine",
+			"day for a walk in\nthe park, isn't it?",
+		}},
+		{"one line", &CaptionOptions{MaxLineLength: 20, MaxLines: 1}, []string{
+			"\"Hello there,\" she",
+			"said. It is a fine",
+			"day for a walk in",
+			"the park, isn't it?",
+		}},
+		{"duration", &CaptionOptions{MaxDuration: 2 * time.Second}, []string{
+			"\"Hello there,\" she said.",
+			"It is a fine",
+			"day for a walk",
+			"in the park, isn't",
+			"it?",
+		}},
+		{"sentences", &CaptionOptions{Sentences: true}, []string{
+			"\"Hello there,\" she said.",
+			"It is a fine day for a walk in the park,\nisn't it?",
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cues := Captions(text, "en", timeWords(text), tt.opts)
+			if len(cues) != len(tt.want) {
+				t.Fatalf("expected %d cues got %d: %+v", len(tt.want), len(cues), cues)
+			}
+			for i, c := range cues {
+				if got := c.Text(); got != tt.want[i] {
+					t.Errorf("cue %d: expected %q got %q", i, tt.want[i], got)
+				}
+				if c.Start != c.Lines[0][0].StartTime || c.End <= c.Start {
+					t.Errorf("cue %d: bad timing %v --> %v", i, c.Start, c.End)
+				}
+			}
+		})
+	}
+
+	t.Run("long word", func(t *testing.T) {
+		words := []WordTiming{{Text: "Hmmm", EndTime: 10 * time.Second}}
+		cues := Captions("Hmmm", "en", words, nil)
+		if len(cues) != 1 || cues[0].End != DefaultMaxCueDuration {
+			t.Errorf("expected a cue ending at %v got %+v", DefaultMaxCueDuration, cues)
+		}
+	})
+}
+
+func TestWriteSRT(t *testing.T) {
+	text := "Hello world. Bye now."
+	cues := Captions(text, "en", timeWords(text), &CaptionOptions{Sentences: true})
+	cues[1].End = time.Hour + 2*time.Minute + 3*time.Second + 45*time.Millisecond
+	var buf bytes.Buffer
+	if err := WriteSRT(&buf, cues); err != nil {
+		t.Fatal(err)
+	}
+	want := "1\n00:00:00,000 --> 00:00:01,000\nHello world.\n\n" +
+		"2\n00:00:01,000 --> 01:02:03,045\nBye now.\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("expected %q got %q", want, got)
+	}
+}
+
+func TestWriteWebVTT(t *testing.T) {
+	text := "AT&T <b> rocks. Fast."
+	cues := Captions(text, "en", timeWords(text), &CaptionOptions{MaxLineLength: 10, Sentences: true})
+	for _, tt := range []struct {
+		name    string
+		karaoke bool
+		want    string
+	}{
+		{"plain", false, "WEBVTT\n\n" +
+			"00:00:00.000 --> 00:00:01.500\nAT&amp;T &lt;b&gt;\nrocks.\n\n" +
+			"00:00:01.500 --> 00:00:02.000\nFast.\n\n"},
+		{"karaoke", true, "WEBVTT\n\n" +
+			"00:00:00.000 --> 00:00:01.500\nAT&amp;T <00:00:00.500>&lt;b&gt;\n<00:00:01.000>rocks.\n\n" +
+			"00:00:01.500 --> 00:00:02.000\nFast.\n\n"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteWebVTT(&buf, cues, tt.karaoke); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("expected %q got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSynthesizer_GenSamplesWebVTT(t *testing.T) {
+	s, err := NewSynthesizer(Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	text := "Captions for a short video. They follow the audio."
+	var buf bytes.Buffer
+	samples, err := s.GenSamplesWebVTT(context.Background(), text, nil, nil, &buf, &CaptionOptions{Sentences: true, Karaoke: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(samples) == 0 {
+		t.Error("expected samples")
+	}
+	got := buf.String()
+	if !strings.HasPrefix(got, "WEBVTT\n\n00:00:00.") || strings.Count(got, " --> ") != 2 {
+		t.Errorf("unexpected captions %q", got)
+	}
+	if !strings.Contains(got, "<00:00:") || !strings.Contains(got, "audio.") {
+		t.Errorf("expected karaoke timestamps in %q", got)
+	}
+}
+
+func TestSynthesizer_GenSamplesSRT_autoVoice(t *testing.T) {
+	s, err := NewSynthesizer(Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	text := "La Sra. García habla español con su hija. Ellas viven en Madrid."
+	params := NewParameters(WithAutoVoice(&AutoVoice{Languages: []string{"en", "es"}}))
+	var buf bytes.Buffer
+	if _, err := s.GenSamplesSRT(context.Background(), text, nil, params, &buf, &CaptionOptions{Sentences: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Sra. doesn't end a sentence in Spanish.
+	if got := strings.Count(buf.String(), " --> "); got != 2 {
+		t.Errorf("expected 2 cues got %d: %q", got, buf.String())
+	}
+}
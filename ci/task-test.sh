@@ -6,5 +6,5 @@ rootdir=$(dirname $(dirname $0))
 cd $rootdir
 
 go vet ./...
-go test -v ./...
+go test -v -race ./...
 
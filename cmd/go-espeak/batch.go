@@ -0,0 +1,197 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/djangulo/go-espeak"
+)
+
+// batch synthesizes each item of a manifest, or line of a text file, to a
+// .wav file of its own.
+func batch(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	var (
+		f       synthFlags
+		format  string
+		prefix  string
+		workers int
+		force   bool
+		report  string
+	)
+	f.register(fs)
+	fs.StringVar(&format, "format", "auto", "`format` of the input: csv, jsonl, lines, or auto, by its extension, lines for others")
+	fs.StringVar(&prefix, "prefix", "", "`prefix` of the ids of lines, numbered after it")
+	fs.IntVar(&workers, "workers", 1, "`number` of items synthesized at once")
+	fs.BoolVar(&force, "force", false, "synthesize every item, even those up to date")
+	fs.StringVar(&report, "report", "", "write a report of the results to `file`, .csv or .jsonl, - for standard output")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), `Usage: go-espeak batch [flags] [file]
+
+Synthesizes each item of a manifest, or line of text, of file or standard
+input, to -dir/<output or id>.wav, writing the path of each to standard
+output. Items up to date, from an earlier run, are skipped.
+
+A manifest is CSV, with a header, or JSON lines, of the columns id, text,
+voice, output, rate, volume, pitch, range, word_gap, punctuation, capitals,
+punctuation_list and sample_rate. Lines of text get the id <prefix><line>.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	if err := parse(fs, e, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return usagef("too many arguments")
+	}
+	if f.ssml {
+		return usagef("-m is not supported by batch")
+	}
+	if workers < 1 {
+		return usagef("-workers must be positive")
+	}
+	name := fs.Arg(0)
+	if format == "auto" {
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".csv":
+			format = "csv"
+		case ".jsonl", ".ndjson":
+			format = "jsonl"
+		default:
+			format = "lines"
+		}
+	}
+	var reportFormat espeak.ManifestFormat
+	if report != "" && report != "-" {
+		switch strings.ToLower(filepath.Ext(report)) {
+		case ".csv":
+			reportFormat = espeak.ManifestCSV
+		case ".jsonl", ".ndjson":
+			reportFormat = espeak.ManifestJSONL
+		default:
+			return usagef("-report must be a .csv or .jsonl file")
+		}
+	}
+
+	var in io.Reader = e.stdin
+	if name != "" && name != "-" {
+		fh, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer fh.Close()
+		in = fh
+	}
+	var (
+		items []espeak.BatchItem
+		err   error
+	)
+	switch format {
+	case "csv":
+		items, err = espeak.ReadManifest(in, espeak.ManifestCSV)
+	case "jsonl":
+		items, err = espeak.ReadManifest(in, espeak.ManifestJSONL)
+	case "lines":
+		items, err = readLines(in, prefix)
+	default:
+		return usagef("unknown -format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	s, err := f.synthesizer(espeak.Synchronous)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	voice, err := findVoice(s, f.voice)
+	if err != nil {
+		return err
+	}
+	params, err := f.parameters(s)
+	if err != nil {
+		return err
+	}
+	opts := &espeak.BatchOptions{
+		Workers: workers,
+		Force:   force,
+		Progress: func(r espeak.BatchResult) {
+			switch {
+			case r.Err != nil:
+				fmt.Fprintf(e.stderr, "go-espeak batch: %s: %v\n", r.ID, r.Err)
+			case report != "-":
+				fmt.Fprintln(e.stdout, r.Output)
+			}
+		},
+	}
+	results, err := s.SynthBatch(ctx, items, voice, params, opts)
+	if err != nil && results == nil {
+		return err
+	}
+
+	if report != "" {
+		out := e.stdout
+		if report != "-" {
+			fh, err := os.Create(report)
+			if err != nil {
+				return err
+			}
+			defer fh.Close()
+			out = fh
+		}
+		w := bufio.NewWriter(out)
+		if err := espeak.WriteBatchReport(w, results, reportFormat); err != nil {
+			return err
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+	var (
+		failed int
+		first  *espeak.BatchResult
+	)
+	for i := range results {
+		if results[i].Err != nil {
+			if first == nil {
+				first = &results[i]
+			}
+			failed++
+		}
+	}
+	if first != nil {
+		return fmt.Errorf("%d of %d items failed, the first %q: %w", failed, len(results), first.ID, first.Err)
+	}
+	return nil
+}
+
+// readLines returns an item of each non-empty line of r, of id prefix and
+// the line number.
+func readLines(r io.Reader, prefix string) ([]espeak.BatchItem, error) {
+	var items []espeak.BatchItem
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		items = append(items, espeak.BatchItem{ID: fmt.Sprintf("%s%04d", prefix, line), Text: text})
+	}
+	return items, sc.Err()
+}
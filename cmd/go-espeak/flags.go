@@ -0,0 +1,194 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"strconv"
+	"strings"
+
+	"github.com/djangulo/go-espeak"
+	"github.com/djangulo/go-espeak/lexicon"
+	"github.com/djangulo/go-espeak/normalize"
+	"github.com/djangulo/go-espeak/resample"
+)
+
+// initFlags the flags of espeak's initialization, see espeak.Init.
+type initFlags struct {
+	path          string
+	buffer        int
+	phonemeEvents bool
+	ipa           bool
+	mbrola        bool
+}
+
+func (f *initFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.path, "path", "", "`dir`ectory of espeak-data, default espeak's")
+	fs.IntVar(&f.buffer, "buffer", 200, "length of the synthesis buffer, in `ms`")
+	fs.BoolVar(&f.phonemeEvents, "phoneme-events", false, "report phoneme events")
+	fs.BoolVar(&f.ipa, "phoneme-ipa", false, "report phoneme events in IPA, rather than espeak's mnemonics")
+	fs.BoolVar(&f.mbrola, "mbrola", false, "allow mbrola voices")
+}
+
+// options returns the InitOption of the flags.
+func (f *initFlags) options() espeak.InitOption {
+	var opts espeak.InitOption
+	if f.phonemeEvents {
+		opts |= espeak.PhonemeEvents
+	}
+	if f.ipa {
+		opts |= espeak.PhonemeIPA
+	}
+	if f.mbrola {
+		opts |= espeak.UseMbrola
+	}
+	return opts
+}
+
+// synthesizer returns a new *espeak.Synthesizer with output.
+func (f *initFlags) synthesizer(output espeak.AudioOutput) (*espeak.Synthesizer, error) {
+	if f.buffer <= 0 {
+		return nil, usagef("-buffer must be positive")
+	}
+	var path *string
+	if f.path != "" {
+		path = &f.path
+	}
+	return espeak.NewSynthesizer(output, f.buffer, path, f.options())
+}
+
+// synthFlags the flags of the commands that synthesize speech: a voice,
+// espeak.Parameters and the initialization.
+type synthFlags struct {
+	initFlags
+	voice         string
+	rate          int
+	volume        int
+	pitch         int
+	rng           int
+	wordGap       int
+	capitals      string
+	punct         string
+	punctList     string
+	sampleRate    int
+	quality       string
+	dir           string
+	lexicon       string
+	normalize     bool
+	autoVoice     bool
+	languages     string
+	minConfidence float64
+	fallback      string
+	ssml          bool
+}
+
+func (f *synthFlags) register(fs *flag.FlagSet) {
+	f.initFlags.register(fs)
+	d := espeak.DefaultParameters
+	fs.StringVar(&f.voice, "v", "", "`voice` name, identifier or language, e.g. \"english-us\", \"europe/es\" or \"fr\"")
+	fs.IntVar(&f.rate, "s", d.Rate, "speaking `rate`, in words per minute, 80 to 450")
+	fs.IntVar(&f.volume, "a", d.Volume, "`volume`, 0 to 200, 100 being normal")
+	fs.IntVar(&f.pitch, "p", d.Pitch, "base `pitch`, 0 to 100")
+	fs.IntVar(&f.rng, "range", d.Range, "pitch `range`, 0 (monotone) to 100")
+	fs.IntVar(&f.wordGap, "g", d.WordGap, "pause between words, in `units` of 10ms at the default rate")
+	fs.StringVar(&f.capitals, "k", strconv.Itoa(int(d.AnnounceCapitals)), "announce capitals: 0 or none, 1 or sound, 2 or spelling, 3 or pitch")
+	fs.StringVar(&f.punct, "punct", "", "announce punctuation: none, all or `some`, the characters of -punct-list")
+	fs.StringVar(&f.punctList, "punct-list", "", "`characters` of punctuation announced, implies -punct some")
+	fs.IntVar(&f.sampleRate, "sample-rate", 0, "sample `rate` of the audio, in Hz, default espeak's")
+	fs.StringVar(&f.quality, "quality", "default", "`quality` of the sample rate conversion: low, medium or high")
+	fs.StringVar(&f.dir, "dir", ".", "`directory` .wav files are written to")
+	fs.StringVar(&f.lexicon, "lexicon", "", "lexicon `file`, .json, .csv or .pls, of pronunciations applied to the text")
+	fs.BoolVar(&f.normalize, "normalize", false, "expand numbers, dates, currency and units into words")
+	fs.BoolVar(&f.autoVoice, "auto-voice", false, "pick the voice by the language of the text")
+	fs.StringVar(&f.languages, "languages", "", "comma separated `languages` the text may be in, for -auto-voice, default any")
+	fs.Float64Var(&f.minConfidence, "min-confidence", 0, "`confidence`, 0 to 1, in the language identified, below which -fallback is used")
+	fs.StringVar(&f.fallback, "fallback", "", "`voice` used when -auto-voice can't tell the language, default -v")
+	fs.BoolVar(&f.ssml, "m", false, "the text is SSML")
+}
+
+// parameters returns the espeak.Parameters of the flags.
+func (f *synthFlags) parameters(s *espeak.Synthesizer) (*espeak.Parameters, error) {
+	params := espeak.NewParameters(
+		espeak.WithRate(f.rate),
+		espeak.WithVolume(f.volume),
+		espeak.WithPitch(f.pitch),
+		espeak.WithRange(f.rng),
+		espeak.WithWordGap(f.wordGap),
+		espeak.WithDir(f.dir),
+		espeak.WithSampleRate(int32(f.sampleRate)),
+	)
+	if err := params.AnnounceCapitals.UnmarshalText([]byte(f.capitals)); err != nil {
+		return nil, usagef("unknown -k %q, one of 0 to 3, none, sound, spelling or pitch", f.capitals)
+	}
+	switch strings.ToLower(f.punct) {
+	case "":
+	case "none":
+		params.AnnouncePunctuation = espeak.PunctNone
+	case "all":
+		params.AnnouncePunctuation = espeak.PunctAll
+	case "some":
+		params.AnnouncePunctuation = espeak.PunctSome
+	default:
+		return nil, usagef("unknown -punct %q", f.punct)
+	}
+	params.SetPunctuationList(f.punctList)
+	if f.sampleRate < 0 {
+		return nil, usagef("-sample-rate must not be negative")
+	}
+	switch strings.ToLower(f.quality) {
+	case "default":
+		params.ResampleQuality = resample.Default
+	case "low":
+		params.ResampleQuality = resample.Low
+	case "medium":
+		params.ResampleQuality = resample.Medium
+	case "high":
+		params.ResampleQuality = resample.High
+	default:
+		return nil, usagef("unknown -quality %q", f.quality)
+	}
+	if f.lexicon != "" {
+		lex, err := lexicon.Load(f.lexicon)
+		if err != nil {
+			return nil, err
+		}
+		params.Lexicon = lex
+	}
+	if f.normalize {
+		params.Normalizer = normalize.New()
+	}
+	if f.autoVoice {
+		a := &espeak.AutoVoice{MinConfidence: f.minConfidence}
+		if f.languages != "" {
+			for _, l := range strings.Split(f.languages, ",") {
+				a.Languages = append(a.Languages, strings.TrimSpace(l))
+			}
+		}
+		fallback, err := findVoice(s, f.fallback)
+		if err != nil {
+			return nil, err
+		}
+		a.Fallback = fallback
+		params.AutoVoice = a
+	}
+	return params, nil
+}
+
+// flags returns the espeak.FlagType of the flags.
+func (f *synthFlags) flags() espeak.FlagType {
+	if f.ssml {
+		return espeak.CharsAuto | espeak.SSML
+	}
+	return espeak.CharsAuto
+}
+
+// findVoice returns the installed voice of s called name, see
+// espeak.Synthesizer.FindVoice. Returns nil, the synthesizer's voice, if
+// name is empty.
+func findVoice(s *espeak.Synthesizer, name string) (*espeak.Voice, error) {
+	if name == "" {
+		return nil, nil
+	}
+	return s.FindVoice(name)
+}
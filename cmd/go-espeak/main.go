@@ -0,0 +1,200 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+// Command go-espeak speaks text, lists voices and translates text to
+// phonemes, like espeak, through the go-espeak library, adding what it does
+// on the Go side: lexicons, normalization, resampling, language detection,
+// and serving speech over HTTP.
+//
+// Usage:
+//
+//	go-espeak say [flags] [text]
+//	go-espeak voices [flags] [language]
+//	go-espeak phonemes [flags] [text]
+//	go-espeak serve [flags]
+//	go-espeak batch [flags] [file]
+//
+// Text is read from the arguments, the file given with -f, or standard
+// input. Run go-espeak <command> -h for the flags of each command.
+//
+// The exit status is 0 on success, 2 on a usage error, 3, 4 and 5 when
+// espeak reports an internal error, a full buffer or something not found,
+// e.g. a voice, 6 on an espeak error of unknown code, and 1 on any other
+// error.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/djangulo/go-espeak"
+)
+
+// Exit codes.
+const (
+	exitOK = iota
+	exitError
+	exitUsage
+	exitInternal
+	exitBufferFull
+	exitNotFound
+	exitUnknown
+)
+
+// usageError an error in the command line.
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+// usagef returns a *usageError formatted as fmt.Errorf.
+func usagef(format string, args ...interface{}) error {
+	return &usageError{fmt.Errorf(format, args...)}
+}
+
+// exitCode returns the exit status of a command that returned err.
+func exitCode(err error) int {
+	var ue *usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &ue):
+		return exitUsage
+	case errors.Is(err, espeak.EErrInternal):
+		return exitInternal
+	case errors.Is(err, espeak.EErrBufferFull):
+		return exitBufferFull
+	case errors.Is(err, espeak.EErrNotFound):
+		return exitNotFound
+	case errors.Is(err, espeak.ErrUnknown):
+		return exitUnknown
+	default:
+		return exitError
+	}
+}
+
+// env the standard streams of a command.
+type env struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+}
+
+// command a subcommand, run with its arguments.
+type command struct {
+	name, summary string
+	run           func(ctx context.Context, e *env, args []string) error
+}
+
+var commands = []command{
+	{"say", "speak text, to a .wav file, standard output or the sound card", say},
+	{"voices", "list the installed voices", voices},
+	{"phonemes", "translate text to phonemes", phonemes},
+	{"serve", "serve speech over HTTP", serve},
+	{"batch", "speak each item of a manifest, or line of a file, to a .wav file of its own", batch},
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: go-espeak <command> [flags] [arguments]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(w, "\nRun go-espeak <command> -h for the flags of each command.\n")
+}
+
+// run runs the command line args, without the program name, returning the
+// exit status.
+func run(ctx context.Context, e *env, args []string) int {
+	if len(args) == 0 {
+		usage(e.stderr)
+		return exitUsage
+	}
+	if args[0] == "-h" || args[0] == "-help" || args[0] == "--help" || args[0] == "help" {
+		usage(e.stdout)
+		return exitOK
+	}
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		err := c.run(ctx, e, args[1:])
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		if err != nil {
+			fmt.Fprintf(e.stderr, "go-espeak %s: %v\n", c.name, err)
+		}
+		return exitCode(err)
+	}
+	fmt.Fprintf(e.stderr, "go-espeak: unknown command %q\n", args[0])
+	usage(e.stderr)
+	return exitUsage
+}
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		cancel()
+	}()
+	code := run(ctx, &env{os.Stdin, os.Stdout, os.Stderr}, os.Args[1:])
+	cancel()
+	espeak.Terminate()
+	os.Exit(code)
+}
+
+// parse parses args with fs, reporting errors as usage errors.
+func parse(fs *flag.FlagSet, e *env, args []string) error {
+	fs.SetOutput(e.stderr)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &usageError{err}
+	}
+	return nil
+}
+
+// readText returns the text of the arguments, of file if set, "-" being
+// standard input, or of standard input if there are neither.
+func readText(e *env, args []string, file string) (string, error) {
+	if file != "" && len(args) > 0 {
+		return "", usagef("text given both as arguments and with -f")
+	}
+	if len(args) > 0 {
+		return strings.Join(args, " "), nil
+	}
+	var r io.Reader = e.stdin
+	if file != "" && file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		r = f
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return "", usagef("no text to speak")
+	}
+	return string(data), nil
+}
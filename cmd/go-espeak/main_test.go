@@ -0,0 +1,189 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/djangulo/go-espeak/wav"
+)
+
+// runArgs runs args with stdin, returning the exit status and output.
+func runArgs(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), &env{strings.NewReader(stdin), &stdout, &stderr}, args)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"no command", nil, exitUsage},
+		{"unknown command", []string{"shout"}, exitUsage},
+		{"help", []string{"-h"}, exitOK},
+		{"command help", []string{"say", "-h"}, exitOK},
+		{"unknown flag", []string{"say", "-loud", "hi"}, exitUsage},
+		{"bad value", []string{"say", "-stdout", "-punct", "most", "hi"}, exitUsage},
+		{"bad capitals", []string{"say", "-stdout", "-k", "4", "hi"}, exitUsage},
+		{"capitals by name", []string{"say", "-stdout", "-k", "spelling", "hi"}, exitOK},
+		{"bad alias", []string{"serve", "-alias", "alloy"}, exitUsage},
+		{"exclusive", []string{"say", "-stdout", "-w", "hi.wav", "hi"}, exitUsage},
+		{"no text", []string{"say", "-stdout"}, exitUsage},
+		{"unknown voice", []string{"say", "-stdout", "-v", "klingon", "hi"}, exitNotFound},
+		{"missing file", []string{"say", "-stdout", "-f", "does-not-exist.txt"}, exitError},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _, stderr := runArgs("", tt.args...); got != tt.want {
+				t.Errorf("expected %d got %d: %s", tt.want, got, stderr)
+			}
+		})
+	}
+}
+
+func TestSay(t *testing.T) {
+	code, out, stderr := runArgs("Hello world", "say", "-stdout", "-sample-rate", "16000", "-punct-list", ",.", "-k", "2")
+	if code != exitOK {
+		t.Fatalf("expected %d got %d: %s", exitOK, code, stderr)
+	}
+	r, err := wav.NewReader(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.SampleRate() != 16000 {
+		t.Errorf("expected 16000 got %d", r.SampleRate())
+	}
+	samples, err := r.ReadSamples()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) == 0 {
+		t.Errorf("0 samples written")
+	}
+
+	dir, err := ioutil.TempDir("", "go-espeak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if code, _, stderr := runArgs("", "say", "-dir", dir, "-w", "hola.wav", "-v", "es", "Hola", "mundo"); code != exitOK {
+		t.Fatalf("expected %d got %d: %s", exitOK, code, stderr)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "hola.wav")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestVoices(t *testing.T) {
+	code, out, stderr := runArgs("", "voices", "-json", "es")
+	if code != exitOK {
+		t.Fatalf("expected %d got %d: %s", exitOK, code, stderr)
+	}
+	var got []voiceJSON
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) == 0 {
+		t.Fatalf("expected Spanish voices got none")
+	}
+	for _, v := range got {
+		if !strings.HasPrefix(v.Language, "es") {
+			t.Errorf("expected a Spanish voice got %+v", v)
+		}
+	}
+
+	code, out, _ = runArgs("", "voices")
+	if code != exitOK || !strings.HasPrefix(out, "NAME") || !strings.Contains(out, "english-us") {
+		t.Errorf("expected a table of voices got %d %q", code, out)
+	}
+}
+
+func TestPhonemes(t *testing.T) {
+	code, out, stderr := runArgs("", "phonemes", "-ipa", "-sep", "_", "hello")
+	if code != exitOK {
+		t.Fatalf("expected %d got %d: %s", exitOK, code, stderr)
+	}
+	if strings.TrimSpace(out) == "" {
+		t.Errorf("expected phonemes got none")
+	}
+	if code, _, _ := runArgs("", "phonemes", "-sep", "__", "hello"); code != exitUsage {
+		t.Errorf("expected %d got %d", exitUsage, code)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-espeak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	code, out, stderr := runArgs("Hello\n\nWorld\n", "batch", "-dir", dir, "-prefix", "line-")
+	if code != exitOK {
+		t.Fatalf("expected %d got %d: %s", exitOK, code, stderr)
+	}
+	want := filepath.Join(dir, "line-0001.wav") + "\n" + filepath.Join(dir, "line-0003.wav") + "\n"
+	if out != want {
+		t.Errorf("expected %q got %q", want, out)
+	}
+}
+
+func TestBatch_manifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go-espeak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	manifest := filepath.Join(dir, "prompts.csv")
+	data := "id,text,voice,rate\nwelcome,Welcome,en,150\nbienvenida,Bienvenido,es,\n"
+	if err := ioutil.WriteFile(manifest, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	report := filepath.Join(dir, "report.jsonl")
+	out := filepath.Join(dir, "out")
+	for _, want := range []string{"written", "skipped"} {
+		code, _, stderr := runArgs("", "batch", "-dir", out, "-workers", "2", "-report", report, manifest)
+		if code != exitOK {
+			t.Fatalf("expected %d got %d: %s", exitOK, code, stderr)
+		}
+		data, err := ioutil.ReadFile(report)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expected 2 results got %q", lines)
+		}
+		for _, line := range lines {
+			var r struct {
+				Status string `json:"status"`
+				Output string `json:"output"`
+			}
+			if err := json.Unmarshal([]byte(line), &r); err != nil {
+				t.Fatal(err)
+			}
+			if r.Status != want {
+				t.Errorf("expected %q got %q", want, r.Status)
+			}
+			if _, err := os.Stat(r.Output); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+
+	bad := filepath.Join(dir, "bad.jsonl")
+	if err := ioutil.WriteFile(bad, []byte(`{"id": "x", "text": "Qapla", "voice": "klingon"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if code, _, stderr := runArgs("", "batch", "-dir", out, bad); code != exitNotFound {
+		t.Errorf("expected %d got %d: %s", exitNotFound, code, stderr)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/djangulo/go-espeak"
+)
+
+// phonemes translates text to phonemes.
+func phonemes(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("phonemes", flag.ContinueOnError)
+	var (
+		f     initFlags
+		voice string
+		file  string
+		ipa   bool
+		sep   string
+		tie   bool
+	)
+	f.register(fs)
+	fs.StringVar(&voice, "v", "", "`voice` name, identifier or language, e.g. \"english-us\", \"europe/es\" or \"fr\"")
+	fs.StringVar(&file, "f", "", "`file` to read the text from, - for standard input")
+	fs.BoolVar(&ipa, "ipa", false, "write the phonemes in IPA, rather than espeak's mnemonics")
+	fs.StringVar(&sep, "sep", "", "`character` separating phonemes, e.g. _")
+	fs.BoolVar(&tie, "tie", false, "join the letters of multi-letter phonemes with -sep, or the IPA tie bar if not set")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: go-espeak phonemes [flags] [text]\n\nTranslates text, of the arguments, -f or standard input, to phonemes.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := parse(fs, e, args); err != nil {
+		return err
+	}
+	mode := espeak.Mnemonics
+	if ipa {
+		mode = espeak.IPA
+	}
+	var r rune
+	if sep != "" {
+		var size int
+		r, size = utf8.DecodeRuneInString(sep)
+		if size != len(sep) || r > 0xffff {
+			return usagef("-sep must be a single character of the Basic Multilingual Plane")
+		}
+	}
+	switch {
+	case tie && r == 0:
+		mode = mode.WithTie(espeak.TieBar)
+	case tie:
+		mode = mode.WithTie(r)
+	case r != 0:
+		mode = mode.WithSeparator(r)
+	}
+	text, err := readText(e, fs.Args(), file)
+	if err != nil {
+		return err
+	}
+
+	s, err := f.synthesizer(espeak.Synchronous)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	v, err := findVoice(s, voice)
+	if err != nil {
+		return err
+	}
+	out, err := s.Phonemize(text, v, mode)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(e.stdout, out)
+	return err
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/djangulo/go-espeak"
+)
+
+// say speaks text to a .wav file, standard output or the sound card.
+func say(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("say", flag.ContinueOnError)
+	var (
+		f       synthFlags
+		file    string
+		wavFile string
+		stdout  bool
+	)
+	f.register(fs)
+	fs.StringVar(&file, "f", "", "`file` to read the text from, - for standard input")
+	fs.StringVar(&wavFile, "w", "", "write the speech to a .wav `file`, relative to -dir, rather than play it")
+	fs.BoolVar(&stdout, "stdout", false, "write the speech to standard output, as .wav, rather than play it")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: go-espeak say [flags] [text]\n\nSpeaks text, of the arguments, -f or standard input.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := parse(fs, e, args); err != nil {
+		return err
+	}
+	if wavFile != "" && stdout {
+		return usagef("-w and -stdout are exclusive")
+	}
+	text, err := readText(e, fs.Args(), file)
+	if err != nil {
+		return err
+	}
+	play := wavFile == "" && !stdout
+	output := espeak.Synchronous
+	if play {
+		output = espeak.SynchPlayback
+	}
+	s, err := f.synthesizer(output)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	voice, err := findVoice(s, f.voice)
+	if err != nil {
+		return err
+	}
+	params, err := f.parameters(s)
+	if err != nil {
+		return err
+	}
+
+	if play {
+		if !f.ssml {
+			_, err := s.TextToSpeechContext(ctx, text, voice, "play", params)
+			return err
+		}
+		// markup is only read by espeak itself.
+		if voice != nil {
+			s.SetVoice(voice)
+		}
+		s.SetParameters(params)
+		if err := s.Synth(text, f.flags()|espeak.EndPause, 0, 0, espeak.Character, nil, nil); err != nil {
+			return err
+		}
+		return espeak.Synchronize()
+	}
+
+	out := bufio.NewWriter(e.stdout)
+	if wavFile != "" {
+		path := wavFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(params.Dir, path)
+		}
+		fh, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer fh.Close()
+		// unbuffered, for the header to be patched once done.
+		if _, err := s.SynthDocument(ctx, text, voice, fh, params, &espeak.DocumentOptions{SSML: f.ssml}); err != nil {
+			return err
+		}
+		return fh.Close()
+	}
+	if _, err := s.SynthDocument(ctx, text, voice, out, params, &espeak.DocumentOptions{SSML: f.ssml}); err != nil {
+		return err
+	}
+	return out.Flush()
+}
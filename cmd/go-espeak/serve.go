@@ -0,0 +1,101 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/djangulo/go-espeak"
+	"github.com/djangulo/go-espeak/espeakhttp"
+)
+
+// serve serves speech over HTTP, see package espeakhttp.
+func serve(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	var (
+		f       synthFlags
+		addr    string
+		maxText int
+		aliases = aliasFlag{}
+	)
+	f.register(fs)
+	fs.StringVar(&addr, "addr", ":8080", "`address` to listen at")
+	fs.IntVar(&maxText, "max-text", espeakhttp.DefaultMaxText, "`bytes` of text a request may have")
+	fs.Var(aliases, "alias", "`name=voice` the voice of name in /v1/audio/speech requests, on top of OpenAI's, may be repeated")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: go-espeak serve [flags]\n\nServes speech over HTTP, spoken with the voice and parameters of the flags, which requests may override:\n\n")
+		fmt.Fprintf(fs.Output(), "  GET|POST /speak    text, voice, format and parameters, to audio\n  GET      /voices   the installed voices, as JSON\n  GET      /healthz  whether the synthesizer works\n  POST     /v1/audio/speech\n                     OpenAI's speech API\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := parse(fs, e, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usagef("too many arguments")
+	}
+	if f.ssml {
+		return usagef("-m is not supported by serve, requests set ssml")
+	}
+	s, err := f.synthesizer(espeak.Synchronous)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	voice, err := findVoice(s, f.voice)
+	if err != nil {
+		return err
+	}
+	params, err := f.parameters(s)
+	if err != nil {
+		return err
+	}
+
+	for name, v := range espeakhttp.DefaultVoiceAliases {
+		if _, ok := aliases[name]; !ok {
+			aliases[name] = v
+		}
+	}
+	h := espeakhttp.New(s, &espeakhttp.Options{Voice: voice, Parameters: params, MaxText: maxText, VoiceAliases: aliases})
+
+	srv := &http.Server{Addr: addr, Handler: h}
+	done := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		done <- srv.Shutdown(shutdown)
+	}()
+	fmt.Fprintf(e.stderr, "go-espeak serve: listening at %s\n", addr)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return <-done
+}
+
+// aliasFlag voice aliases, set by name=voice flags.
+type aliasFlag map[string]string
+
+func (a aliasFlag) String() string {
+	var pairs []string
+	for name, voice := range a {
+		pairs = append(pairs, name+"="+voice)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (a aliasFlag) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i <= 0 || i == len(value)-1 {
+		return fmt.Errorf("%q is not name=voice", value)
+	}
+	a[strings.ToLower(value[:i])] = value[i+1:]
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/djangulo/go-espeak"
+)
+
+// voiceJSON a voice, as listed by voices -json.
+type voiceJSON struct {
+	Name       string        `json:"name"`
+	Language   string        `json:"language"`
+	Identifier string        `json:"identifier"`
+	Gender     espeak.Gender `json:"gender"`
+	Age        espeak.Age    `json:"age,omitempty"`
+}
+
+// voices lists the installed voices, of a language if given.
+func voices(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("voices", flag.ContinueOnError)
+	var (
+		f       initFlags
+		asJSON  bool
+		gender  string
+		matches []*espeak.Voice
+	)
+	f.register(fs)
+	fs.BoolVar(&asJSON, "json", false, "list the voices as JSON")
+	fs.StringVar(&gender, "gender", "", "only list the voices of `gender`, male or female")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: go-espeak voices [flags] [language]\n\nLists the installed voices, of language if given, e.g. \"es\", in espeak's order of preference.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := parse(fs, e, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return usagef("too many arguments")
+	}
+	want := espeak.Unspecified
+	switch strings.ToLower(gender) {
+	case "":
+	case "male", "m":
+		want = espeak.Male
+	case "female", "f":
+		want = espeak.Female
+	default:
+		return usagef("unknown -gender %q", gender)
+	}
+
+	s, err := f.synthesizer(espeak.Synchronous)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	var spec *espeak.Voice
+	if fs.NArg() == 1 {
+		spec = &espeak.Voice{Languages: strings.ToLower(fs.Arg(0))}
+	}
+	list, err := s.ListVoices(spec)
+	if err != nil {
+		return err
+	}
+	for _, v := range list {
+		if want == espeak.Unspecified || v.Gender == want {
+			matches = append(matches, v)
+		}
+	}
+
+	if asJSON {
+		out := make([]voiceJSON, len(matches))
+		for i, v := range matches {
+			out[i] = voiceJSON{v.Name, v.Language(), v.Identifier, v.Gender, v.Age}
+		}
+		enc := json.NewEncoder(e.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+	tw := tabwriter.NewWriter(e.stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tLANGUAGE\tIDENTIFIER\tGENDER\tAGE")
+	for _, v := range matches {
+		age := "-"
+		if v.Age > 0 {
+			age = fmt.Sprint(int(v.Age))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", v.Name, v.Language(), v.Identifier, v.Gender, age)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,425 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package espeak
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/djangulo/go-espeak/wav"
+)
+
+// DefaultMaxChunk bytes of a document synthesized at once, by default.
+const DefaultMaxChunk = 1000
+
+// DocumentOptions how a document is split, synthesized and joined.
+type DocumentOptions struct {
+	// MaxChunk bytes of text synthesized at once, see SplitDocument.
+	// Negative puts every sentence in a chunk of its own, as SplitDocument
+	// does with 0. Default DefaultMaxChunk.
+	MaxChunk int
+	// SSML the document is an SSML document.
+	SSML bool
+	// ParagraphPause silence added between paragraphs, on top of espeak's
+	// pause at the end of a sentence. Default 0.
+	ParagraphPause time.Duration
+	// Workers chunks synthesized ahead of the one being written. espeak
+	// synthesizes one chunk at a time, more workers overlap that with
+	// resampling and writing the previous ones. Default 1.
+	Workers int
+	// Progress is called after each chunk is written.
+	Progress func(Progress)
+	// Resume makes SynthDocumentFile pick up where an interrupted call,
+	// with the same document, voice, parameters and options, left off,
+	// rather than starting over. The partial file and its progress are kept
+	// if synthesis fails.
+	Resume bool
+}
+
+// Progress of a document's synthesis.
+type Progress struct {
+	// Done chunks, out of Total, resumed ones included.
+	Done, Total int
+	// Audio synthesized so far.
+	Audio time.Duration
+	// Elapsed since the call started, and ETA, the time left, estimated
+	// from the rate at which text was synthesized so far.
+	Elapsed, ETA time.Duration
+}
+
+// document a document being synthesized.
+type document struct {
+	chunks []DocumentChunk
+	// first chunk to synthesize, the ones before were resumed.
+	first int
+	flags FlagType
+	voice *Voice
+	// native params, synthesizing at espeak's sample rate, the writer
+	// converts to the output's.
+	native *Parameters
+	opts   *DocumentOptions
+	// audio resumed.
+	audio time.Duration
+	// done is called after chunk i is written.
+	done func(i int) error
+}
+
+// newDocument splits text for synthesis through s.
+func (s *Synthesizer) newDocument(text string, voice *Voice, params *Parameters, opts *DocumentOptions) *document {
+	if opts == nil {
+		opts = &DocumentOptions{}
+	}
+	maxChunk := opts.MaxChunk
+	if maxChunk == 0 {
+		maxChunk = DefaultMaxChunk
+	}
+	flags := documentFlags(opts)
+	native := *params
+	native.SampleRate = 0
+	// the voice is picked once, for the whole document.
+	native.AutoVoice = nil
+	return &document{
+		chunks: SplitDocument(text, voice.Language(), flags, maxChunk),
+		flags:  flags,
+		voice:  voice,
+		native: &native,
+		opts:   opts,
+	}
+}
+
+// documentFlags returns the flags chunks are synthesized with.
+func documentFlags(opts *DocumentOptions) FlagType {
+	flags := CharsAuto | EndPause
+	if opts != nil && opts.SSML {
+		flags |= SSML
+	}
+	return flags
+}
+
+// chunkVoice returns the voice c, a chunk of d, is spoken with: d's, or if
+// c is in another language, e.g. that of an SSML xml:lang, the installed
+// voice of it. d's if there's none.
+func (s *Synthesizer) chunkVoice(d *document, c DocumentChunk) (*Voice, error) {
+	lang := strings.ToLower(c.Language)
+	own := strings.ToLower(d.voice.Language())
+	if lang == "" || lang == own || strings.HasPrefix(own, lang+"-") {
+		return d.voice, nil
+	}
+	voice, err := s.languageVoice(lang)
+	if err != nil || voice == nil {
+		return d.voice, err
+	}
+	return voice, nil
+}
+
+// docResult the samples of a chunk.
+type docResult struct {
+	samples []int16
+	err     error
+}
+
+// synthDocument synthesizes the chunks of d into w, converting from s's
+// sample rate to w's.
+func (s *Synthesizer) synthDocument(ctx context.Context, d *document, w *wav.Writer) error {
+	if err := w.ResampleFrom(s.sampleRate, d.native.ResampleQuality); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	workers := d.opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	// each chunk's result is buffered, so workers never block on a
+	// cancelled call.
+	results := make([]chan docResult, len(d.chunks))
+	for i := range results {
+		results[i] = make(chan docResult, 1)
+	}
+	sem := make(chan struct{}, workers)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := d.first; i < len(d.chunks); i++ {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				voice, err := s.chunkVoice(d, d.chunks[i])
+				if err != nil {
+					results[i] <- docResult{err: err}
+					return
+				}
+				r, err := s.synthesizeResult(ctx, d.chunks[i].Text, d.flags, voice, d.native)
+				if err != nil {
+					results[i] <- docResult{err: err}
+					return
+				}
+				results[i] <- docResult{samples: r.Samples}
+			}(i)
+		}
+	}()
+
+	start := time.Now()
+	// samples written, at s's sample rate.
+	samples := 0
+	total, synthesized := 0, 0
+	for _, c := range d.chunks[d.first:] {
+		total += c.Length
+	}
+	pause := make([]int16, int64(d.opts.ParagraphPause)*int64(s.sampleRate)/int64(time.Second))
+	for i := d.first; i < len(d.chunks); i++ {
+		var res docResult
+		select {
+		case res = <-results[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		<-sem
+		if res.err != nil {
+			return res.err
+		}
+		if i > 0 && d.chunks[i].Paragraph && len(pause) > 0 {
+			if _, err := w.WriteSamples(pause); err != nil {
+				return err
+			}
+			samples += len(pause)
+		}
+		if _, err := w.WriteSamples(res.samples); err != nil {
+			return err
+		}
+		samples += len(res.samples)
+		if d.done != nil {
+			if err := d.done(i); err != nil {
+				return err
+			}
+		}
+		if d.opts.Progress != nil {
+			synthesized += d.chunks[i].Length
+			elapsed := time.Since(start)
+			p := Progress{Done: i + 1, Total: len(d.chunks), Audio: d.audio + samplesToDuration(samples, s.sampleRate), Elapsed: elapsed}
+			if synthesized > 0 {
+				p.ETA = time.Duration(float64(elapsed) * float64(total-synthesized) / float64(synthesized))
+			}
+			d.opts.Progress(p)
+		}
+	}
+	return nil
+}
+
+// SynthDocument synthesizes text, a document of any length, using voice,
+// modified by params, into out as a .wav file. The document is split into
+// chunks of sentences, see SplitDocument, synthesized one after the other,
+// so memory use doesn't grow with its length. Returns the number of bytes
+// written. opts may be nil. opts.Resume is ignored, see SynthDocumentFile.
+func (s *Synthesizer) SynthDocument(ctx context.Context, text string, voice *Voice, out io.Writer, params *Parameters, opts *DocumentOptions) (uint64, error) {
+	if text == "" {
+		return 0, ErrEmptyText
+	}
+	voice, params, err := s.resolveText(text, documentFlags(opts), voice, params)
+	if err != nil {
+		return 0, err
+	}
+	if s.plays() {
+		return 0, ErrOutputMode
+	}
+	d := s.newDocument(text, voice, params, opts)
+	w := wav.NewWriter(out, s.outputRate(params))
+	if err := s.synthDocument(ctx, d, w); err != nil {
+		return 0, err
+	}
+	if err := w.Close(); err != nil {
+		return 0, err
+	}
+	return w.Size(), nil
+}
+
+// documentJournal the progress of SynthDocumentFile, saved along the file
+// after each chunk, to resume it.
+type documentJournal struct {
+	// Hash of the document and everything else that shapes its audio.
+	Hash string `json:"hash"`
+	// Chunks written, Data bytes of the data chunk they took.
+	Chunks int    `json:"chunks"`
+	Data   uint64 `json:"data"`
+}
+
+// journalPath returns the path of the journal of the .wav file at path.
+func journalPath(path string) string {
+	return path + ".progress"
+}
+
+// hash returns the hash of d as synthesized to rate.
+func (d *document) hash(text string, rate int32) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00%d\x00%d\x00", d.flags, len(d.chunks), d.opts.ParagraphPause)
+	hashParameters(h, d.voice, d.native, rate)
+	io.WriteString(h, text)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashParameters writes to h everything about voice and p that shapes the
+// audio synthesized to rate. The rules of p.Normalizer are told apart by
+// their type and regular expression.
+func hashParameters(h io.Writer, voice *Voice, p *Parameters, rate int32) {
+	fmt.Fprintf(h, "%q\x00%q\x00%q\x00%d\x00%d\x00%d\x00", voice.Name, voice.Languages, voice.Identifier, voice.Gender, voice.Age, voice.Variant)
+	fmt.Fprintf(h, "%d\x00%d\x00%d\x00%d\x00", p.Rate, p.Volume, p.Pitch, p.Range)
+	fmt.Fprintf(h, "%d\x00%d\x00%d\x00%q\x00", p.AnnouncePunctuation, p.AnnounceCapitals, p.WordGap, p.punctList)
+	fmt.Fprintf(h, "%d\x00%d\x00", rate, p.ResampleQuality)
+	if p.Lexicon != nil {
+		for _, e := range p.Lexicon.Entries() {
+			fmt.Fprintf(h, "lexicon\x00%q\x00%q\x00%q\x00%q\x00%q\x00%q\x00%t\x00", e.Word, e.Regexp, e.Replacement, e.Phonemes, e.Alphabet, e.Language, e.CaseSensitive)
+		}
+	}
+	if p.Normalizer != nil {
+		for _, r := range p.Normalizer.Rules(voice.Language()) {
+			fmt.Fprintf(h, "normalizer\x00%T\x00%q\x00", r, r.Regexp())
+		}
+	}
+}
+
+// readJournal returns the journal at path, nil if there's none, or it's
+// not of hash.
+func readJournal(path, hash string) *documentJournal {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var j documentJournal
+	if err := json.Unmarshal(data, &j); err != nil || j.Hash != hash {
+		return nil
+	}
+	return &j
+}
+
+// writeJournal replaces the journal at path with v, as JSON.
+func writeJournal(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// SynthDocumentFile is like SynthDocument, but saves the audio to
+// params.Dir/outfile[.wav]. Its progress is saved along, in
+// outfile.wav.progress, after each chunk, so that with opts.Resume set, a
+// call interrupted by an error, a cancellation or a crash, picks up from
+// the last chunk written. The progress file is removed once done.
+func (s *Synthesizer) SynthDocumentFile(ctx context.Context, text string, voice *Voice, outfile string, params *Parameters, opts *DocumentOptions) (uint64, error) {
+	if text == "" {
+		return 0, ErrEmptyText
+	}
+	voice, params, err := s.resolveText(text, documentFlags(opts), voice, params)
+	if err != nil {
+		return 0, err
+	}
+	if s.plays() {
+		return 0, ErrOutputMode
+	}
+	d := s.newDocument(text, voice, params, opts)
+	rate := s.outputRate(params)
+	if err := os.MkdirAll(params.Dir, 0755); err != nil {
+		return 0, err
+	}
+	path := filepath.Join(params.Dir, ensureWavSuffix(outfile))
+	jpath := journalPath(path)
+	journal := &documentJournal{Hash: d.hash(text, rate)}
+
+	var (
+		fh *os.File
+		w  *wav.Writer
+	)
+	if j := readJournal(jpath, journal.Hash); d.opts.Resume && j != nil && j.Chunks <= len(d.chunks) {
+		if fh, err = os.OpenFile(path, os.O_RDWR, 0644); err == nil {
+			if w, err = wav.Resume(fh, wav.PCM16(rate), j.Data); err != nil {
+				fh.Close()
+			}
+		}
+		if err == nil {
+			journal = j
+			d.first = j.Chunks
+			d.audio = samplesToDuration(int(j.Data/2), rate)
+		}
+	}
+	if w == nil {
+		if fh, err = os.Create(path); err != nil {
+			return 0, err
+		}
+		w = wav.NewWriter(fh, rate)
+	}
+	d.done = func(i int) error {
+		// a resumed call can't get back the samples the resampler holds,
+		// chunks end in a pause anyway.
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if err := fh.Sync(); err != nil {
+			return err
+		}
+		journal.Chunks = i + 1
+		journal.Data = w.DataSize()
+		return writeJournal(jpath, journal)
+	}
+
+	err = s.synthDocument(ctx, d, w)
+	if err == nil {
+		err = w.Close()
+	}
+	if cerr := fh.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		if !d.opts.Resume {
+			os.Remove(path)
+			os.Remove(jpath)
+		}
+		return 0, err
+	}
+	os.Remove(jpath)
+	return w.Size(), nil
+}
+
+// SynthDocument synthesizes a document through the default synthesizer.
+// See Synthesizer.SynthDocument.
+func SynthDocument(ctx context.Context, text string, voice *Voice, out io.Writer, params *Parameters, opts *DocumentOptions) (uint64, error) {
+	s, err := defaultSynthesizer()
+	if err != nil {
+		return 0, err
+	}
+	return s.SynthDocument(ctx, text, voice, out, params, opts)
+}
+
+// SynthDocumentFile synthesizes a document to a file through the default
+// synthesizer. See Synthesizer.SynthDocumentFile.
+func SynthDocumentFile(ctx context.Context, text string, voice *Voice, outfile string, params *Parameters, opts *DocumentOptions) (uint64, error) {
+	s, err := defaultSynthesizer()
+	if err != nil {
+		return 0, err
+	}
+	return s.SynthDocumentFile(ctx, text, voice, outfile, params, opts)
+}
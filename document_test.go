@@ -0,0 +1,282 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package espeak
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/djangulo/go-espeak/lexicon"
+	"github.com/djangulo/go-espeak/normalize"
+	"github.com/djangulo/go-espeak/resample"
+	"github.com/djangulo/go-espeak/wav"
+)
+
+func TestSynthDocument(t *testing.T) {
+	s, err := NewSynthesizer(Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	text := strings.Repeat("One sentence here. Another one there.\n\n", 5)
+	chunks := SplitDocument(text, s.Voice().Language(), CharsAuto, 0)
+	var want []int16
+	for _, c := range chunks {
+		samples, err := s.GenSamples(c.Text, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, samples...)
+	}
+
+	for _, workers := range []int{1, 3} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			var progress []Progress
+			opts := &DocumentOptions{
+				MaxChunk: -1,
+				Workers:  workers,
+				Progress: func(p Progress) { progress = append(progress, p) },
+			}
+			var buf bytes.Buffer
+			n, err := s.SynthDocument(context.Background(), text, nil, &buf, nil, opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if n != uint64(buf.Len()) {
+				t.Errorf("expected %d bytes got %d", buf.Len(), n)
+			}
+			r, err := wav.NewReader(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := r.ReadSamples()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equalSamples(got, want) {
+				t.Errorf("expected %d samples got %d (or differing data)", len(want), len(got))
+			}
+			if len(progress) != len(chunks) {
+				t.Fatalf("expected %d progress reports got %d", len(chunks), len(progress))
+			}
+			last := progress[len(progress)-1]
+			if last.Done != len(chunks) || last.Total != len(chunks) || last.ETA != 0 {
+				t.Errorf("unexpected last progress %+v", last)
+			}
+			if want := samplesToDuration(len(want), s.SampleRate()); last.Audio != want {
+				t.Errorf("expected %v of audio got %v", want, last.Audio)
+			}
+		})
+	}
+
+	t.Run("paragraph pause", func(t *testing.T) {
+		var buf bytes.Buffer
+		opts := &DocumentOptions{MaxChunk: -1, ParagraphPause: 100 * time.Millisecond}
+		if _, err := s.SynthDocument(context.Background(), text, nil, &buf, nil, opts); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		r, _ := wav.NewReader(&buf)
+		got, _ := r.ReadSamples()
+		// a pause before each paragraph but the first.
+		pause := int(s.SampleRate()) / 10
+		if len(got) != len(want)+4*pause {
+			t.Errorf("expected %d samples got %d", len(want)+4*pause, len(got))
+		}
+	})
+}
+
+func TestSynthDocumentFile(t *testing.T) {
+	s, err := NewSynthesizer(Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	dir, err := ioutil.TempDir("", "go-espeak-document-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	params := NewParameters(WithDir(dir))
+	text := strings.Repeat("Some words to read. ", 6)
+
+	var full bytes.Buffer
+	if _, err := s.SynthDocument(context.Background(), text, nil, &full, params, &DocumentOptions{MaxChunk: -1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// interrupted after the third chunk.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts := &DocumentOptions{
+		MaxChunk: -1,
+		Resume:   true,
+		Progress: func(p Progress) {
+			if p.Done == 3 {
+				cancel()
+			}
+		},
+	}
+	if _, err := s.SynthDocumentFile(ctx, text, nil, "doc", params, opts); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v got %v", context.Canceled, err)
+	}
+	path := filepath.Join(dir, "doc.wav")
+	if _, err := os.Stat(journalPath(path)); err != nil {
+		t.Fatalf("expected a progress file: %v", err)
+	}
+
+	// as if it crashed mid-write, past the last chunk recorded.
+	fh, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fh.Write([]byte{1, 2, 3})
+	fh.Close()
+
+	var resumed []int
+	opts.Progress = func(p Progress) { resumed = append(resumed, p.Done) }
+	n, err := s.SynthDocumentFile(context.Background(), text, nil, "doc", params, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resumed) != 3 || resumed[0] != 4 {
+		t.Errorf("expected chunks 4 to 6 to be synthesized, got %v", resumed)
+	}
+	if _, err := os.Stat(journalPath(path)); !os.IsNotExist(err) {
+		t.Errorf("expected the progress file to be removed, got %v", err)
+	}
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// full couldn't seek back to fix the sizes in its header.
+	if n != uint64(len(got)) || !bytes.Equal(got[44:], full.Bytes()[44:]) {
+		t.Errorf("expected the resumed file to match an uninterrupted synthesis")
+	}
+	if size := binary.LittleEndian.Uint32(got[40:]); int(size) != len(got)-44 {
+		t.Errorf("expected a data chunk of %d bytes got %d", len(got)-44, size)
+	}
+}
+
+func TestDocument_hash(t *testing.T) {
+	text := "Dr. Smith paid $5."
+	hash := func(voice *Voice, params *Parameters) string {
+		var s Synthesizer
+		return s.newDocument(text, voice, params, nil).hash(text, 22050)
+	}
+	if a, b := hash(ENUSMale, NewParameters()), hash(ENUSMale, NewParameters()); a != b {
+		t.Errorf("expected %q got %q", a, b)
+	}
+	lex, err := lexicon.New(lexicon.Entry{Word: "Smith", Replacement: "Smyth"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := lexicon.New(lexicon.Entry{Word: "Smith", Replacement: "Smithe"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	norm := normalize.New()
+	norm.Add("en", normalize.MustRule(`\$5`, func(*normalize.Match) (string, bool) { return "five bucks", true }))
+
+	// every one shapes the audio differently.
+	seen := make(map[string]string)
+	for _, tt := range []struct {
+		name   string
+		voice  *Voice
+		params *Parameters
+	}{
+		{"defaults", ENUSMale, NewParameters()},
+		{"voice identifier", &Voice{Name: ENUSMale.Name, Languages: "en-us", Identifier: "en-us+f3"}, NewParameters()},
+		{"voice gender", &Voice{Name: ENUSMale.Name, Languages: "en-us", Identifier: "en-us", Gender: Female}, NewParameters()},
+		{"resample quality", ENUSMale, NewParameters(func(p *Parameters) { p.ResampleQuality = resample.High })},
+		{"lexicon", ENUSMale, NewParameters(func(p *Parameters) { p.Lexicon = lex })},
+		{"other lexicon", ENUSMale, NewParameters(func(p *Parameters) { p.Lexicon = other })},
+		{"normalizer", ENUSMale, NewParameters(func(p *Parameters) { p.Normalizer = normalize.New() })},
+		{"normalizer rules", ENUSMale, NewParameters(func(p *Parameters) { p.Normalizer = norm })},
+	} {
+		got := hash(tt.voice, tt.params)
+		if name, ok := seen[got]; ok {
+			t.Errorf("expected %s and %s to hash differently", name, tt.name)
+		}
+		seen[got] = tt.name
+	}
+}
+
+func TestSynthDocumentFile_resampled(t *testing.T) {
+	s, err := NewSynthesizer(Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	dir, err := ioutil.TempDir("", "go-espeak-document-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	params := NewParameters(WithDir(dir), WithSampleRate(16000))
+	text := strings.Repeat("Some words to read. ", 4)
+	if _, err := s.SynthDocumentFile(context.Background(), text, nil, "full", params, &DocumentOptions{MaxChunk: -1}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opts := &DocumentOptions{
+		MaxChunk: -1,
+		Resume:   true,
+		Progress: func(p Progress) {
+			if p.Done == 2 {
+				cancel()
+			}
+		},
+	}
+	if _, err := s.SynthDocumentFile(ctx, text, nil, "doc", params, opts); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v got %v", context.Canceled, err)
+	}
+	opts.Progress = nil
+	if _, err := s.SynthDocumentFile(context.Background(), text, nil, "doc", params, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	full, err := ioutil.ReadFile(filepath.Join(dir, "full.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(filepath.Join(dir, "doc.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the samples held by the resampler at the interruption aren't lost.
+	if !bytes.Equal(got, full) {
+		t.Errorf("expected the resumed file to match an uninterrupted synthesis, %d and %d bytes", len(got), len(full))
+	}
+}
+
+func TestSynthesizer_chunkVoice(t *testing.T) {
+	s, err := NewSynthesizer(Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	d := s.newDocument(`<speak xml:lang="en-us">Hello. <p xml:lang="es">Hola.</p><p xml:lang="en">Bye.</p><p xml:lang="xx">Hm.</p></speak>`, ENUSMale, NewParameters(), &DocumentOptions{SSML: true})
+	want := []string{"english-us", "spanish", "english-us", "english-us"}
+	if len(d.chunks) != len(want) {
+		t.Fatalf("expected %d chunks got %+v", len(want), d.chunks)
+	}
+	for i, c := range d.chunks {
+		voice, err := s.chunkVoice(d, c)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if voice.Name != want[i] {
+			t.Errorf("chunk %d, %q: expected %q got %q", i, c.Language, want[i], voice.Name)
+		}
+	}
+}
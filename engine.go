@@ -0,0 +1,72 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package espeak
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// engine owns the espeak library. libespeak is not reentrant, so every call
+// into it is made by a single goroutine locked to its OS thread, which runs
+// the work other goroutines queue through do.
+type engine struct {
+	once  sync.Once
+	queue chan func()
+}
+
+var eng = &engine{}
+
+func (e *engine) run() {
+	runtime.LockOSThread()
+	for fn := range e.queue {
+		fn()
+	}
+}
+
+// do runs fn on the engine's thread and returns its error. Calls are run one
+// at a time, in the order they're queued. fn must not call do.
+func (e *engine) do(fn func() error) error {
+	return e.doContext(context.Background(), fn)
+}
+
+// doContext is like do, but gives up waiting for the engine, returning
+// ctx.Err(), if ctx is done before fn starts. Once started, fn is waited on.
+func (e *engine) doContext(ctx context.Context, fn func() error) error {
+	e.once.Do(func() {
+		e.queue = make(chan func())
+		go e.run()
+	})
+	errc := make(chan error, 1)
+	select {
+	case e.queue <- func() { errc <- fn() }:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return <-errc
+}
+
+// cancelOnDone calls Cancel if ctx is done before stop is called. It covers
+// the output modes where espeak synthesizes on its own threads, outside the
+// reach of the synth callback.
+func cancelOnDone(ctx context.Context) (stop func()) {
+	if ctx.Done() == nil {
+		return func() {}
+	}
+	stopc := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			Cancel()
+		case <-stopc:
+		}
+	}()
+	return func() {
+		close(stopc)
+		<-exited
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package espeak
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestEngine_concurrency is meant to be run with -race.
+func TestEngine_concurrency(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping stress test in short mode")
+	}
+	type job struct {
+		text   string
+		voice  *Voice
+		params *Parameters
+	}
+	var jobs []job
+	for _, v := range []*Voice{ENUSMale, ESSpainMale, ESLatinMale, FRFranceMale} {
+		for _, rate := range []int{120, 175, 300} {
+			for _, pitch := range []int{20, 80} {
+				jobs = append(jobs, job{
+					text:   fmt.Sprintf("%s %d %d", v.Name, rate, pitch),
+					voice:  v,
+					params: NewParameters(WithRate(rate), WithPitch(pitch)),
+				})
+			}
+		}
+	}
+	// sequential run, for reference
+	want := make([][]int16, len(jobs))
+	for i, j := range jobs {
+		samples, err := GenSamples(j.text, j.voice, j.params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[i] = samples
+	}
+
+	s, err := NewSynthesizer(Synchronous, 100, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	const n = 400
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			k := i % len(jobs)
+			j := jobs[k]
+			gen := GenSamples
+			if i%2 == 0 {
+				// also interleave a synthesizer with a different config
+				gen = s.GenSamples
+			}
+			got, err := gen(j.text, j.voice, j.params)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if !equalSamples(got, want[k]) {
+				errs <- fmt.Errorf("call %d (%s): got %d samples, want %d (or differing data)",
+					i, j.text, len(got), len(want[k]))
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func equalSamples(a, b []int16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
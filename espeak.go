@@ -27,18 +27,20 @@ extern int processSamples(short *wav, int numsamples, espeak_EVENT *events);
 import "C"
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math/rand"
 	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 	"unsafe"
 
-	"github.com/djangulo/go-espeak/wav"
+	"github.com/djangulo/go-espeak/lexicon"
+	"github.com/djangulo/go-espeak/normalize"
+	"github.com/djangulo/go-espeak/resample"
 )
 
 func init() {
@@ -123,75 +125,38 @@ func (v *Voice) String() string {
 // VoiceFromSpec returns a random Voice from the group of voices that matches
 // spec. Is spec is nil, returns a random voice.
 func VoiceFromSpec(spec *Voice) (*Voice, error) {
-	candidates, err := ListVoices(spec)
+	s, err := defaultSynthesizer()
 	if err != nil {
 		return nil, err
 	}
-	if len(candidates) == 0 {
-		return nil, EErrNotFound
-	}
-	res := make([]*Voice, 0)
-	if !useMbrola {
-		for _, c := range candidates {
-			if !strings.HasPrefix(c.Identifier, "mb") {
-				res = append(res, c)
-			}
-		}
-	} else {
-		res = candidates
-	}
+	return s.VoiceFromSpec(spec)
+}
 
-	rand.Seed(time.Now().Unix())
-	i := rand.Intn(len(res))
-	return res[i], nil
+// FindVoice returns the installed voice called name, of identifier name, or
+// of language name. See Synthesizer.FindVoice.
+func FindVoice(name string) (*Voice, error) {
+	s, err := defaultSynthesizer()
+	if err != nil {
+		return nil, err
+	}
+	return s.FindVoice(name)
 }
 
 // ListVoices reads the voice files from espeak-data/voices and returns them
 // in a []*Voice object. If spec is nil, all available voices are listed.
 // If spec is given, then only the voices which are compatible with the spec
 // are listed, and they are listed in preference order.
-// Init must have been called.
-func ListVoices(spec *Voice) (voices []*Voice, err error) {
-	if !initialized {
-		return nil, ErrNotInitialized
-	}
-	var voiceSpec *C.espeak_VOICE
-	if spec != nil {
-		voiceSpec = spec.cptr()
-	}
-	// out is Ctype const espeak_VOICE ** (pointer to array)
-	out := C.espeak_ListVoices(voiceSpec)
-	defer C.free(unsafe.Pointer(out))
-	// slice-ification of a C.espeak_VOICE ** into a slice
-	//     (*C.espeak_VOICE)(unsafe.Pointer(out)) gets the actual array
-	//     length is fixed at a 1000 as we don't know how many return
-	//     espeak_ListVoices returns a NULL terminated array
-	cVoices := (*[1 << 28]*C.espeak_VOICE)(
-		unsafe.Pointer(
-			(*C.espeak_VOICE)(unsafe.Pointer(out))))[:1000:1000]
-	defer func() {
-		cVoices = nil
-		out = nil
-	}() // garbage collect
-	voices = make([]*Voice, 0)
-	for _, cv := range cVoices {
-		if cv == nil {
-			break
-		}
-		if useMbrola {
-			voices = append(voices, voiceFromCptr(unsafe.Pointer(cv)))
-		} else {
-			if !strings.HasPrefix(C.GoString(cv.identifier), "mb") {
-				voices = append(voices, voiceFromCptr(unsafe.Pointer(cv)))
-			}
-		}
+func ListVoices(spec *Voice) ([]*Voice, error) {
+	s, err := defaultSynthesizer()
+	if err != nil {
+		return nil, err
 	}
-
-	return voices, nil
+	return s.ListVoices(spec)
 }
 
 func (v *Voice) cptr() *C.espeak_VOICE {
-	cv := &cVoice{
+	// a whole espeak_VOICE, cVoice lacks its trailing fields.
+	return &C.espeak_VOICE{
 		name:       C.CString(v.Name),
 		languages:  C.CString(v.Languages),
 		identifier: C.CString(v.Identifier),
@@ -199,7 +164,6 @@ func (v *Voice) cptr() *C.espeak_VOICE {
 		age:        C.uchar(int(v.Age)),
 		variant:    C.uchar(int(v.Variant)),
 	}
-	return (*C.espeak_VOICE)(unsafe.Pointer(cv))
 }
 
 func voiceFromCptr(ptr unsafe.Pointer) *Voice {
@@ -283,6 +247,22 @@ func (p PunctType) String() string {
 	}[p]
 }
 
+// UnmarshalText implements the encoding.TextUnmarshaler interface, reading
+// "none", "all", "some", or their number.
+func (p *PunctType) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "none", "0":
+		*p = PunctNone
+	case "all", "1":
+		*p = PunctAll
+	case "some", "2":
+		*p = PunctSome
+	default:
+		return fmt.Errorf("unknown punctuation %q", text)
+	}
+	return nil
+}
+
 // Capitals setting to announce capital letters by.
 type Capitals int
 
@@ -306,6 +286,24 @@ func (c Capitals) String() string {
 	}[c]
 }
 
+// UnmarshalText implements the encoding.TextUnmarshaler interface, reading
+// "none", "sound", "spelling", "pitch", or their number.
+func (c *Capitals) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "none", "0":
+		*c = CapitalNone
+	case "sound", "sound-icon", "1":
+		*c = CapitalSoundIcon
+	case "spelling", "2":
+		*c = CapitalSpelling
+	case "pitch", "pitch-raise", "3":
+		*c = CapitalPitchRaise
+	default:
+		return fmt.Errorf("unknown capitals %q", text)
+	}
+	return nil
+}
+
 // Parameters espeak voice parameters.
 type Parameters struct {
 	// Rate speaking speed in word per minute.  Values 80 to 450. Default 175.
@@ -318,14 +316,30 @@ type Parameters struct {
 	Pitch int
 	// Range pitch range, range 0-100. 0-monotone, 50=normal. Default 50 (normal).
 	Range int
-	// AnnouncePunctuation settings. See PunctType for details. Default None (0).
+	// AnnouncePunctuation settings. See PunctType for details. Default Some,
+	// which announces the characters of PunctuationList, none unless set.
 	AnnouncePunctuation PunctType
 	// AnnounceCapitals settings. See Capitals for details. Default None (0).
 	AnnounceCapitals Capitals
 	// WordGap pause between words, units of 10mS (at the default speed).
 	WordGap int
 	// Dir directory path to save .wav files. Default os.TempDir()
-	Dir       string
+	Dir string
+	// SampleRate of the synthesized audio, converted from espeak's. Ignored
+	// when playing. Default 0, espeak's sample rate.
+	SampleRate int32
+	// ResampleQuality of the conversion to SampleRate. Default
+	// resample.Default.
+	ResampleQuality resample.Quality
+	// Lexicon applied to the text before it's synthesized, in the voice's
+	// language. Events keep referring to the original text. Default nil.
+	Lexicon *lexicon.Lexicon
+	// Normalizer expands numbers, dates, currency and units into words,
+	// after Lexicon, in the voice's language. Default nil.
+	Normalizer *normalize.Normalizer
+	// AutoVoice picks the voice by the language of the text, overriding
+	// the one given. Default nil.
+	AutoVoice *AutoVoice
 	punctList string
 }
 
@@ -342,6 +356,11 @@ func (p *Parameters) SetPunctuationList(chars string) {
 // SetVoiceParams calls espeak_SetParameter for each of the *Parameters
 // fields.
 func (p *Parameters) SetVoiceParams() error {
+	return eng.do(p.setVoiceParams)
+}
+
+// setVoiceParams is SetVoiceParams, to be called from the engine.
+func (p *Parameters) setVoiceParams() error {
 	var ee C.espeak_ERROR
 	ee = C.espeak_SetParameter(C.espeakRATE, C.int(p.Rate), C.int(0))
 	if err := ErrFromCode(ee); err != nil {
@@ -359,7 +378,7 @@ func (p *Parameters) SetVoiceParams() error {
 	if err := ErrFromCode(ee); err != nil {
 		return err
 	}
-	ee = C.espeak_SetParameter(C.espeakPUNCTUATION, C.int(2), C.int(0))
+	ee = C.espeak_SetParameter(C.espeakPUNCTUATION, p.AnnouncePunctuation.toC(), C.int(0))
 	if err := ErrFromCode(ee); err != nil {
 		return err
 	}
@@ -372,7 +391,9 @@ func (p *Parameters) SetVoiceParams() error {
 		return err
 	}
 	if p.punctList != "" {
-		ee = C.espeak_SetPunctuationList((*C.wchar_t)(unsafe.Pointer(C.CString(p.punctList))))
+		list := wcharString(p.punctList)
+		defer C.free(unsafe.Pointer(list))
+		ee = C.espeak_SetPunctuationList(list)
 		if err := ErrFromCode(ee); err != nil {
 			return err
 		}
@@ -380,6 +401,19 @@ func (p *Parameters) SetVoiceParams() error {
 	return nil
 }
 
+// wcharString returns s as a NUL terminated wchar_t string, allocated in C.
+func wcharString(s string) *C.wchar_t {
+	runes := []rune(s)
+	n := len(runes) + 1
+	buf := C.malloc(C.size_t(n) * C.size_t(unsafe.Sizeof(C.wchar_t(0))))
+	chars := (*[1 << 28]C.wchar_t)(buf)[:n:n]
+	for i, r := range runes {
+		chars[i] = C.wchar_t(r)
+	}
+	chars[n-1] = 0
+	return (*C.wchar_t)(buf)
+}
+
 // Option parameter creatien function.
 type Option func(*Parameters)
 
@@ -389,15 +423,16 @@ var DefaultParameters = &Parameters{
 	Volume:              100,
 	Pitch:               50,
 	Range:               50,
-	AnnouncePunctuation: PunctNone,
+	AnnouncePunctuation: PunctSome,
 	AnnounceCapitals:    CapitalNone,
 	WordGap:             10,
 	Dir:                 os.TempDir(),
 }
 
-// NewParameters returns *DefaultParameters modified by opts.
+// NewParameters returns a copy of *DefaultParameters modified by opts.
 func NewParameters(opts ...Option) *Parameters {
-	p := DefaultParameters
+	cp := *DefaultParameters
+	p := &cp
 	for _, opt := range opts {
 		opt(p)
 	}
@@ -460,6 +495,41 @@ func WithDir(path string) Option {
 	}
 }
 
+// WithSampleRate rate.
+func WithSampleRate(rate int32) Option {
+	return func(p *Parameters) {
+		p.SampleRate = rate
+	}
+}
+
+// WithResampleQuality q.
+func WithResampleQuality(q resample.Quality) Option {
+	return func(p *Parameters) {
+		p.ResampleQuality = q
+	}
+}
+
+// WithLexicon lex.
+func WithLexicon(lex *lexicon.Lexicon) Option {
+	return func(p *Parameters) {
+		p.Lexicon = lex
+	}
+}
+
+// WithNormalizer n.
+func WithNormalizer(n *normalize.Normalizer) Option {
+	return func(p *Parameters) {
+		p.Normalizer = n
+	}
+}
+
+// WithAutoVoice a.
+func WithAutoVoice(a *AutoVoice) Option {
+	return func(p *Parameters) {
+		p.AutoVoice = a
+	}
+}
+
 // WithRate rate.
 func (p *Parameters) WithRate(rate int) *Parameters {
 	p.Rate = rate
@@ -508,6 +578,36 @@ func (p *Parameters) WithDir(path string) *Parameters {
 	return p
 }
 
+// WithSampleRate rate.
+func (p *Parameters) WithSampleRate(rate int32) *Parameters {
+	p.SampleRate = rate
+	return p
+}
+
+// WithResampleQuality q.
+func (p *Parameters) WithResampleQuality(q resample.Quality) *Parameters {
+	p.ResampleQuality = q
+	return p
+}
+
+// WithLexicon lex.
+func (p *Parameters) WithLexicon(lex *lexicon.Lexicon) *Parameters {
+	p.Lexicon = lex
+	return p
+}
+
+// WithNormalizer n.
+func (p *Parameters) WithNormalizer(n *normalize.Normalizer) *Parameters {
+	p.Normalizer = n
+	return p
+}
+
+// WithAutoVoice a.
+func (p *Parameters) WithAutoVoice(a *AutoVoice) *Parameters {
+	p.AutoVoice = a
+	return p
+}
+
 // InitOption initialization options. Beware only PhonemeEvents and PhonemeIPA
 // are the only ones that belong to espeak.
 type InitOption uint8
@@ -549,12 +649,6 @@ func (a AudioOutput) toC() C.espeak_AUDIO_OUTPUT {
 	}
 }
 
-var (
-	initialized bool
-	useMbrola   bool
-	sampleRate  int32
-)
-
 // Init wrapper around espeak_Initialize. Returns a uintptr id which the
 // address of the data block (T: *[]int16) acted on, and the sample rate
 // used.
@@ -573,52 +667,53 @@ func Init(
 	if bufferLength == 0 {
 		bufferLength = 200
 	}
-
-	if options&UseMbrola == UseMbrola {
-		useMbrola = true
-	}
-	var cPath *C.char
+	c := config{output: output, bufferLength: bufferLength, options: options}
 	if path != nil {
-		cPath = C.CString(*path)
-		defer C.free(unsafe.Pointer(cPath))
-	}
-	sr := C.espeak_Initialize(
-		output.toC(),
-		C.int(bufferLength),
-		cPath,
-		C.int(options),
-	)
-	if int(sr) == -1 {
-		return 0, 0, EErrInternal
+		c.path = *path
+	}
+
+	var sr int32
+	err := eng.do(func() (err error) {
+		// Init always re-initializes, as the caller may have changed the
+		// callback or state behind the library's back.
+		lib.initialized = false
+		sr, err = lib.load(c)
+		return err
+	})
+	if err != nil {
+		return 0, 0, err
 	}
 	id, _, err := registry.newData()
 	if err != nil {
 		return 0, 0, err
 	}
-	sampleRate = int32(sr)
-	initialized = true
-	return id, sampleRate, nil
+	return id, sr, nil
 }
 
 // SetSynthCallback to the unsafe.Pointer passed. The underlying C object
 // has to be a a function of signature
 //    int (t_espeak_callback)(short*, int, espeak_EVENT*)
+// The callback runs on the engine's thread, and must not call back into
+// this package.
 func SetSynthCallback(ptr unsafe.Pointer) {
-	C.espeak_SetSynthCallback((*C.t_espeak_callback)(ptr))
+	eng.do(func() error {
+		C.espeak_SetSynthCallback((*C.t_espeak_callback)(ptr))
+		return nil
+	})
 }
 
 // Terminate closes the espeak connection. It's up to the caller to call this
 // and terminate the function.
 func Terminate() error {
-	ee := C.espeak_Terminate()
-	if err := ErrFromCode(ee); err != nil {
-		return err
-	}
-	return nil
+	return eng.do(lib.terminate)
 }
 
 // SetVoiceByName wrapper around espeak_SetVoiceByName.
 func SetVoiceByName(name string) error {
+	return eng.do(func() error { return setVoiceByName(name) })
+}
+
+func setVoiceByName(name string) error {
 	cName := C.CString(name)
 	defer C.free(unsafe.Pointer(cName))
 	ee := C.espeak_SetVoiceByName(cName)
@@ -631,6 +726,10 @@ func SetVoiceByName(name string) error {
 // SetVoiceByProps wrapper around espeak_SetVoiceByProperties.
 // An *Voice is used to pass criteria to select a voice.
 func SetVoiceByProps(v *Voice) error {
+	return eng.do(func() error { return setVoiceByProps(v) })
+}
+
+func setVoiceByProps(v *Voice) error {
 	ee := C.espeak_SetVoiceByProperties(v.cptr())
 	if err := ErrFromCode(ee); err != nil {
 		return err
@@ -682,14 +781,24 @@ func Synth(
 	posType PositionType,
 	uniqueIdent *uint64,
 	userData unsafe.Pointer,
+) error {
+	return eng.do(func() error {
+		return synth(text, flags, startPos, endPos, posType, uniqueIdent, userData)
+	})
+}
+
+func synth(
+	text string,
+	flags FlagType,
+	startPos, endPos uint32,
+	posType PositionType,
+	uniqueIdent *uint64,
+	userData unsafe.Pointer,
 ) error {
 	ctext := C.CString(text)
 	defer C.free(unsafe.Pointer(ctext))
 
-	var uid *C.uint
-	if uniqueIdent != nil {
-		*uid = C.uint(*uniqueIdent)
-	}
+	var uid C.uint
 
 	ee := C.espeak_Synth(
 		unsafe.Pointer(ctext),
@@ -698,16 +807,23 @@ func Synth(
 		posType.toC(),
 		C.uint(endPos),
 		C.uint(flags),
-		uid,
+		&uid,
 		userData)
 	if err := ErrFromCode(ee); err != nil {
 		return err
 	}
+	if uniqueIdent != nil {
+		*uniqueIdent = uint64(uid)
+	}
 	return nil
 }
 
 // Synchronize wrapper around espeak_Synchronize.
 func Synchronize() error {
+	return eng.do(synchronize)
+}
+
+func synchronize() error {
 	ee := C.espeak_Synchronize()
 	if err := ErrFromCode(ee); err != nil {
 		return err
@@ -718,6 +834,8 @@ func Synchronize() error {
 // Cancel wrapper around espeak_Cancel. Stop immediately synthesis and audio
 // output of the current text. When this function returns, the audio output is
 // fully stopped and the synthesizer is ready to synthesize a new message.
+// Unlike the rest of the API, Cancel does not wait for the engine, as it's
+// meant to interrupt it.
 func Cancel() error {
 	ee := C.espeak_Cancel()
 	if err := ErrFromCode(ee); err != nil {
@@ -738,141 +856,93 @@ func IsPlaying() bool {
 // params.Dir/outfile[.wav]. Returns the number of samples written to file,
 // if any.
 func TextToSpeech(text string, voice *Voice, outfile string, params *Parameters) (uint64, error) {
-	if text == "" {
-		return 0, ErrEmptyText
-	}
-	if params == nil {
-		params = NewParameters()
-	}
-	if voice == nil {
-		voice = DefaultVoice
-	}
-	// if outfile is "play" or empty, play the audio
-	// this path is simple, as pretty much only the voice is set
-	if outfile == "" || outfile == "play" {
-		_, _, err := Init(Playback, -1, nil, PhonemeEvents)
-		// if the error is of type ErrAllreadyInitialized, continue
-		if err != nil && !errors.Is(err, ErrAlreadyInitialized) {
-			return 0, err
-		}
-		if err := params.SetVoiceParams(); err != nil {
-			return 0, err
-		}
+	return TextToSpeechContext(context.Background(), text, voice, outfile, params)
+}
 
-		if err := SetVoiceByName(voice.Name); err != nil {
-			return 0, err
-		}
-		var uid *uint64
-		if err := Synth(
-			text,
-			CharsAuto|EndPause,
-			0,
-			0,
-			Character,
-			uid,
-			unsafe.Pointer(nil)); err != nil {
-			return 0, err
-		}
-		if err := Synchronize(); err != nil {
-			return 0, err
-		}
-		return 0, nil
+// TextToSpeechContext is like TextToSpeech, but stops synthesis (or
+// playback) and returns ctx.Err() if ctx is done before it finishes.
+func TextToSpeechContext(ctx context.Context, text string, voice *Voice, outfile string, params *Parameters) (uint64, error) {
+	var (
+		s   *Synthesizer
+		err error
+	)
+	if outfile == "" || outfile == "play" {
+		s, err = defaultPlayer()
+	} else {
+		s, err = defaultSynthesizer()
 	}
-	// outputting to wav
-
-	// Gensamples calls init
-	data, err := GenSamples(text, voice, params)
 	if err != nil {
 		return 0, err
 	}
-
-	outfile = ensureWavSuffix(outfile)
-	if err := os.MkdirAll(params.Dir, 0755); err != nil {
-		return 0, err
-	}
-	outfile = filepath.Join(params.Dir, outfile)
-	fh, _ := os.Create(outfile)
-	defer fh.Close()
-
-	w := wav.NewWriter(fh, sampleRate)
-	written, err := w.WriteSamples(data)
-	if err != nil {
-		return 0, nil
-	}
-	return written, nil
+	return s.TextToSpeechContext(ctx, text, voice, outfile, params)
 }
 
 // GenSamples generates a []int16 sample slice containing the data of text,
 // using voice, modified by params. If params is nil, default parameters are
 // used.
 func GenSamples(text string, voice *Voice, params *Parameters) ([]int16, error) {
-	if text == "" {
-		return nil, ErrEmptyText
-	}
-	if params == nil {
-		params = NewParameters()
-	}
-	if voice == nil {
-		voice = DefaultVoice
-	}
-
-	id, _, err := Init(Synchronous, 200, nil, PhonemeEvents)
-	// if the error is of type ErrAllreadyInitialized, continue
-	if err != nil && !errors.Is(err, ErrAlreadyInitialized) {
-		return nil, err
-	}
-
-	var (
-		uniqueIdentifier *uint64
-		userData         = unsafe.Pointer(&id)
-	)
-	if err := params.SetVoiceParams(); err != nil {
-		return nil, err
-	}
+	return GenSamplesContext(context.Background(), text, voice, params)
+}
 
-	//set call back
-	SetSynthCallback(C.processSamples)
-	if err := SetVoiceByName(voice.Name); err != nil {
+// GenSamplesContext is like GenSamples, but stops synthesis and returns
+// ctx.Err() if ctx is done before it finishes.
+func GenSamplesContext(ctx context.Context, text string, voice *Voice, params *Parameters) ([]int16, error) {
+	s, err := defaultSynthesizer()
+	if err != nil {
 		return nil, err
 	}
+	return s.GenSamplesContext(ctx, text, voice, params)
+}
 
-	if err := Synth(
-		text,
-		CharsAuto|EndPause,
-		0,
-		0,
-		Character,
-		uniqueIdentifier,
-		userData); err != nil {
-		return nil, err
-	}
-	if err := Synchronize(); err != nil {
+// Synthesize generates the samples of text, along with the events espeak
+// reported, using voice, modified by params. If params is nil, default
+// parameters are used.
+func Synthesize(ctx context.Context, text string, voice *Voice, params *Parameters) (*Result, error) {
+	s, err := defaultSynthesizer()
+	if err != nil {
 		return nil, err
 	}
-	data := registry.getData(id)
-	defer registry.removeData(id)
-
-	return *data, nil
+	return s.Synthesize(ctx, text, voice, params)
 }
 
-// SampleRate return the produced sample rate.
+// SampleRate return the sample rate espeak was last initialized with.
 func SampleRate() int32 {
-	return sampleRate
+	var sr int32
+	eng.do(func() error {
+		sr = lib.sampleRate
+		return nil
+	})
+	return sr
 }
 
 //export processSamples
 func processSamples(wav *C.short, numsamples C.int, events *C.espeak_EVENT) C.int {
+	// wav is NULL at the end of synthesis, returning non-zero tells espeak
+	// to stop.
+	var stop C.int
 	if wav == nil {
+		stop = 1
+	}
+	ptr := C.eventUserData(events)
+	if ptr == nil {
+		return stop
+	}
+	j := registry.getJob(*(*uintptr)(ptr))
+	if j == nil {
+		return stop
+	}
+	if j.abort() {
 		return 1
 	}
-	id := (*uintptr)(unsafe.Pointer(C.eventUserData(events)))
-	data := registry.getData(*id)
-	length := int(numsamples)
-	*data = append(
-		*data,
-		(*[1 << 28]int16)(unsafe.Pointer(wav))[:length:length]...,
-	)
-	return 0
+	var samples []int16
+	if wav != nil {
+		length := int(numsamples)
+		samples = (*[1 << 28]int16)(unsafe.Pointer(wav))[:length:length]
+	}
+	if !j.deliver(samples, eventsFromC(events)) {
+		return 1
+	}
+	return stop
 }
 
 func ensureWavSuffix(s string) string {
@@ -913,6 +983,13 @@ var (
 	ErrAlreadyInitialized = errors.New("espeak already initialized")
 	// ErrNotInitialized espeak not initialized (call Init).
 	ErrNotInitialized = errors.New("espeak not initialized (call Init)")
+	// ErrClosed the Synthesizer has been closed.
+	ErrClosed = errors.New("synthesizer is closed")
+	// ErrOutputMode the operation is not supported by the Synthesizer's
+	// AudioOutput, e.g. retrieving samples from a Playback synthesizer.
+	ErrOutputMode = errors.New("operation not supported by the audio output")
+	// ErrManifest a batch manifest, or item, is invalid.
+	ErrManifest = errors.New("invalid manifest")
 )
 
 // ErrFromCode get a Go error from an espeak_ERROR.
@@ -942,32 +1019,107 @@ wav [ ][ ][ ][ ]
 */
 
 var registry = &cache{
-	samples: make([]*[]int16, 0),
+	jobs: make([]*job, 0),
+}
+
+// job is the state of a single synthesis. Its id is the address of the job
+// (and of its samples), passed to processSamples in the events' user_data.
+type job struct {
+	// samples must be the first field, so the id is also the address of
+	// the data block.
+	samples []int16
+	events  []Event
+	mu      sync.Mutex
+	// done when closed, synthesis is abandoned.
+	done    <-chan struct{}
+	aborted bool
+	// chunks if set, samples are sent through it as they're produced,
+	// instead of being accumulated.
+	chunks chan<- Chunk
+	// remap if set, converts the text positions of events, for text that
+	// was rewritten before synthesis.
+	remap func([]Event)
+}
+
+// abort returns true if synthesis should stop, marking j as aborted.
+func (j *job) abort() bool {
+	select {
+	case <-j.done:
+		j.mu.Lock()
+		j.aborted = true
+		j.mu.Unlock()
+		return true
+	default:
+		return false
+	}
+}
+
+// wasAborted returns whether the synth callback stopped synthesis.
+func (j *job) wasAborted() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.aborted
+}
+
+// deliver hands samples, owned by espeak, and events over to j. Returns
+// false if j was abandoned while waiting for its chunks to be received.
+func (j *job) deliver(samples []int16, events []Event) bool {
+	if j.remap != nil {
+		j.remap(events)
+	}
+	if j.chunks == nil {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		j.samples = append(j.samples, samples...)
+		j.events = append(j.events, events...)
+		return true
+	}
+	if len(samples) == 0 && len(events) == 0 {
+		return true
+	}
+	cp := make([]int16, len(samples))
+	copy(cp, samples)
+	select {
+	case j.chunks <- Chunk{Samples: cp, Events: events}:
+		return true
+	case <-j.done:
+		j.mu.Lock()
+		j.aborted = true
+		j.mu.Unlock()
+		return false
+	}
 }
 
 type cache struct {
 	sync.Mutex
-	samples []*[]int16
+	jobs []*job
 }
 
-func (c *cache) newData() (uintptr, *[]int16, error) {
+// newJob registers a job, abandoned once done is closed. done may be nil.
+// If chunks is not nil, samples are sent through it.
+func (c *cache) newJob(done <-chan struct{}, chunks chan<- Chunk) (uintptr, *job) {
 	c.Lock()
 	defer c.Unlock()
 
-	d := make([]int16, 0)
-	c.samples = append(c.samples, &d)
-	return uintptr(unsafe.Pointer(&d)), &d, nil
+	j := &job{samples: make([]int16, 0), done: done, chunks: chunks}
+	c.jobs = append(c.jobs, j)
+	return uintptr(unsafe.Pointer(j)), j
+}
+
+func (c *cache) newData() (uintptr, *[]int16, error) {
+	id, j := c.newJob(nil, nil)
+	return id, &j.samples, nil
 }
 
 func (c *cache) removeData(id uintptr) error {
 	c.Lock()
 	defer c.Unlock()
 
-	for i, data := range c.samples {
-		if uintptr(unsafe.Pointer(data)) == id {
-			c.samples[i] = c.samples[len(c.samples)-1]
-			c.samples[len(c.samples)-1] = nil
-			c.samples = c.samples[:len(c.samples)-1]
+	for i, j := range c.jobs {
+		if uintptr(unsafe.Pointer(j)) == id {
+			c.jobs[i] = c.jobs[len(c.jobs)-1]
+			c.jobs[len(c.jobs)-1] = nil
+			c.jobs = c.jobs[:len(c.jobs)-1]
 			return nil
 		}
 	}
@@ -975,30 +1127,25 @@ func (c *cache) removeData(id uintptr) error {
 	return fmt.Errorf("id not found: %v", id)
 }
 
-// getData returns a copy of the data (as it may be deleted later)
-func (c *cache) exportData(id uintptr) []int16 {
-	c.Lock()
-	defer c.Unlock()
-
-	for _, data := range c.samples {
-		if uintptr(unsafe.Pointer(data)) == id {
-			cp := make([]int16, len(*data), cap(*data))
-			copy(cp, *data)
-			return cp
-		}
-	}
-
-	return nil
+// result returns a copy of the samples and events gathered by j.
+func (j *job) result() ([]int16, []Event) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	samples := make([]int16, len(j.samples))
+	copy(samples, j.samples)
+	events := make([]Event, len(j.events))
+	copy(events, j.events)
+	return samples, events
 }
 
-// getData returns the pointer to the data.
-func (c *cache) getData(id uintptr) *[]int16 {
+// getJob returns the job registered as id, nil if not found.
+func (c *cache) getJob(id uintptr) *job {
 	c.Lock()
 	defer c.Unlock()
 
-	for _, data := range c.samples {
-		if uintptr(unsafe.Pointer(data)) == id {
-			return data
+	for _, j := range c.jobs {
+		if uintptr(unsafe.Pointer(j)) == id {
+			return j
 		}
 	}
 
@@ -73,3 +73,15 @@ func TestEnsureWavSuffix(t *testing.T) {
 		})
 	}
 }
+
+func TestDefaultParameters_punctuation(t *testing.T) {
+	// espeak was always told to announce "some" punctuation, that of the
+	// list, none unless set.
+	p := NewParameters()
+	if p.AnnouncePunctuation != PunctSome {
+		t.Errorf("expected %v got %v", PunctSome, p.AnnouncePunctuation)
+	}
+	if p.PunctuationList() != "" {
+		t.Errorf("expected %q got %q", "", p.PunctuationList())
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+// Package espeakhttp serves speech synthesized with espeak over HTTP. A
+// Handler answers:
+//
+//	GET|POST /speak    the audio of a text
+//	GET      /voices   the installed voices, as JSON, of ?language= if given
+//	GET      /healthz  whether the synthesizer works
+//	POST     /v1/audio/speech
+//	                   the audio of a text, as the OpenAI speech API does
+//
+// /speak reads the fields of a JSON object, sent as application/json, or of
+// a form, or the query:
+//
+//	text              the text to speak, required
+//	voice             voice name, identifier or language, e.g. "es"
+//	ssml              the text is SSML, true or false
+//	format            wav, the default, wav-float, wav-alaw, wav-mulaw, or
+//	                  pcm, raw 16 bit little endian samples
+//	rate, volume, pitch, range, word_gap, punctuation, capitals,
+//	punctuation_list, sample_rate, quality
+//	                  override the espeak.Parameters of the Handler
+//	normalize         expand numbers, dates and units into words
+//	auto_voice        pick the voice by the language of the text, among
+//	                  languages, with min_confidence, or else fallback
+//
+// /v1/audio/speech reads a JSON object of model, which is ignored, input,
+// the text, voice, an alias of Options.VoiceAliases or an espeak voice,
+// response_format, wav by default, pcm, 16 bit samples at 24kHz, or another
+// format of /speak, and speed, 0.25 to 4, which scales the rate. Errors
+// have the shape of the OpenAI API's, so its clients can be pointed at a
+// Handler.
+//
+// Audio is streamed as espeak synthesizes it, chunked, .wav files having the
+// sizes of streamed files, 0xFFFFFFFF. That of SSML, spoken a segment at a
+// time, is written once done, with its length. No files are involved.
+// Errors are JSON objects, see Error.
+package espeakhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/djangulo/go-espeak"
+)
+
+// DefaultMaxText bytes of text a request may have, by default.
+const DefaultMaxText = 10000
+
+// Options of a Handler.
+type Options struct {
+	// Voice of requests that don't name one. Default the synthesizer's.
+	Voice *espeak.Voice
+	// Parameters requests override. Default the synthesizer's.
+	Parameters *espeak.Parameters
+	// MaxText bytes of text a request may have. Default DefaultMaxText.
+	MaxText int
+	// VoiceAliases the voice names of /v1/audio/speech requests, e.g.
+	// "alloy", by the espeak voice, identifier or language each is spoken
+	// with. Names not in it are taken as espeak's. Default
+	// DefaultVoiceAliases.
+	VoiceAliases map[string]string
+}
+
+// Handler an http.Handler serving speech, see the package documentation.
+// Mount it under a prefix with http.StripPrefix.
+type Handler struct {
+	s    *espeak.Synthesizer
+	opts Options
+	mux  *http.ServeMux
+}
+
+// New returns a *Handler synthesizing with s, which must have been created
+// with espeak.Synchronous. opts may be nil.
+func New(s *espeak.Synthesizer, opts *Options) *Handler {
+	h := &Handler{s: s, mux: http.NewServeMux()}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.MaxText <= 0 {
+		h.opts.MaxText = DefaultMaxText
+	}
+	aliases := h.opts.VoiceAliases
+	if aliases == nil {
+		aliases = DefaultVoiceAliases
+	}
+	h.opts.VoiceAliases = make(map[string]string, len(aliases))
+	for name, voice := range aliases {
+		h.opts.VoiceAliases[strings.ToLower(name)] = voice
+	}
+	h.mux.HandleFunc("/speak", h.speak)
+	h.mux.HandleFunc("/voices", h.voices)
+	h.mux.HandleFunc("/healthz", h.healthz)
+	h.mux.HandleFunc("/v1/audio/speech", h.audioSpeech)
+	h.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, &Error{Status: http.StatusNotFound, Type: invalidRequest, Message: "no such endpoint " + r.URL.Path})
+	})
+	return h
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// Error types.
+const (
+	invalidRequest = "invalid_request_error"
+	serverError    = "server_error"
+)
+
+// Error the body of an error response, as {"error": {...}}.
+type Error struct {
+	// Status HTTP status code of the response.
+	Status int `json:"-"`
+	// Message describing the error.
+	Message string `json:"message"`
+	// Type invalid_request_error, for the client's errors, or server_error.
+	Type string `json:"type"`
+	// Param the field of the request at fault, if any.
+	Param string `json:"param,omitempty"`
+	// Code of the error, if any, e.g. "voice_not_found".
+	Code string `json:"code,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// paramError returns a 400 *Error of param.
+func paramError(param, message string) *Error {
+	return &Error{Status: http.StatusBadRequest, Type: invalidRequest, Param: param, Message: message}
+}
+
+// writeError writes err as a JSON error response.
+func writeError(w http.ResponseWriter, err *Error) {
+	if err.Status == http.StatusMethodNotAllowed {
+		w.Header().Set("Allow", err.Param)
+		err.Param = ""
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(err.Status)
+	json.NewEncoder(w).Encode(struct {
+		Error *Error `json:"error"`
+	}{err})
+}
+
+// synthError returns the *Error of err, returned synthesizing.
+func synthError(err error) *Error {
+	var e *Error
+	switch {
+	case errors.As(err, &e):
+		return e
+	case errors.Is(err, espeak.ErrEmptyText):
+		return paramError("text", "no text to speak")
+	case errors.Is(err, espeak.EErrNotFound):
+		return &Error{Status: http.StatusNotFound, Type: invalidRequest, Param: "voice", Code: "voice_not_found", Message: err.Error()}
+	case errors.Is(err, espeak.ErrClosed):
+		return &Error{Status: http.StatusServiceUnavailable, Type: serverError, Message: err.Error()}
+	default:
+		return &Error{Status: http.StatusInternalServerError, Type: serverError, Message: err.Error()}
+	}
+}
+
+// allow returns a 405 *Error unless r's method is one of methods.
+func allow(r *http.Request, methods ...string) *Error {
+	for _, m := range methods {
+		if r.Method == m || (r.Method == http.MethodHead && m == http.MethodGet) {
+			return nil
+		}
+	}
+	return &Error{
+		Status:  http.StatusMethodNotAllowed,
+		Type:    invalidRequest,
+		Message: "method " + r.Method + " not allowed",
+		// written to the Allow header.
+		Param: strings.Join(methods, ", "),
+	}
+}
+
+// writeJSON writes v as a JSON response.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, synthError(err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(data, '\n'))
+}
+
+// voice a voice, as listed by /voices.
+type voice struct {
+	Name       string        `json:"name"`
+	Language   string        `json:"language"`
+	Identifier string        `json:"identifier"`
+	Gender     espeak.Gender `json:"gender"`
+	Age        espeak.Age    `json:"age,omitempty"`
+}
+
+// voices lists the installed voices, of the language query parameter, if
+// given.
+func (h *Handler) voices(w http.ResponseWriter, r *http.Request) {
+	if err := allow(r, http.MethodGet); err != nil {
+		writeError(w, err)
+		return
+	}
+	var spec *espeak.Voice
+	if lang := r.URL.Query().Get("language"); lang != "" {
+		spec = &espeak.Voice{Languages: strings.ToLower(lang)}
+	}
+	list, err := h.s.ListVoices(spec)
+	if err != nil {
+		writeError(w, synthError(err))
+		return
+	}
+	out := make([]voice, 0, len(list))
+	for _, v := range list {
+		out = append(out, voice{v.Name, v.Language(), v.Identifier, v.Gender, v.Age})
+	}
+	writeJSON(w, out)
+}
+
+// healthz reports whether the synthesizer can list its voices.
+func (h *Handler) healthz(w http.ResponseWriter, r *http.Request) {
+	if err := allow(r, http.MethodGet); err != nil {
+		writeError(w, err)
+		return
+	}
+	if _, err := h.s.ListVoices(nil); err != nil {
+		e := synthError(err)
+		e.Status = http.StatusServiceUnavailable
+		writeError(w, e)
+		return
+	}
+	writeJSON(w, map[string]string{"status": "ok"})
+}
@@ -0,0 +1,349 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package espeakhttp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/djangulo/go-espeak"
+	"github.com/djangulo/go-espeak/wav"
+)
+
+func newHandler(t *testing.T, opts *Options) *Handler {
+	t.Helper()
+	s, err := espeak.NewSynthesizer(espeak.Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return New(s, opts)
+}
+
+// errorOf returns the error of the JSON error response of rec.
+func errorOf(t *testing.T, rec *httptest.ResponseRecorder) *Error {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected %q got %q", "application/json", ct)
+	}
+	var body struct {
+		Error *Error `json:"error"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == nil {
+		t.Fatalf("expected an error, got %q: %v", rec.Body.String(), err)
+	}
+	return body.Error
+}
+
+func TestHandler_speak(t *testing.T) {
+	h := newHandler(t, &Options{MaxText: 100})
+	rate := strconv.Itoa(int(h.s.SampleRate() / 2))
+
+	for _, tt := range []struct {
+		name        string
+		req         *http.Request
+		contentType string
+		sampleRate  string
+		tag         wav.FormatTag
+		// sized SSML is written with its length, text streamed.
+		sized bool
+	}{
+		{
+			"get",
+			httptest.NewRequest(http.MethodGet, "/speak?text=Hello+world&voice=es", nil),
+			"audio/wav", "", wav.PCM, false,
+		},
+		{
+			"form",
+			formRequest(url.Values{"text": {"Hello world"}, "rate": {"200"}, "format": {"wav-mulaw"}, "sample_rate": {rate}}),
+			"audio/wav", rate, wav.MuLaw, false,
+		},
+		{
+			"json",
+			jsonRequest(`{"text": "<speak>Hello <break time=\"100ms\"/> world</speak>", "ssml": true, "punctuation": "some", "capitals": 2, "languages": ["en"], "auto_voice": true}`),
+			"audio/wav", "", wav.PCM, true,
+		},
+		{
+			"pcm",
+			jsonRequest(`{"text": "Hello world", "format": "pcm", "sample_rate": ` + rate + `}`),
+			"audio/pcm;rate=" + rate + ";channels=1", rate, 0, false,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, tt.req)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected %d got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("expected %q got %q", tt.contentType, ct)
+			}
+			want := ""
+			if tt.sized {
+				want = strconv.Itoa(rec.Body.Len())
+			}
+			if cl := rec.Header().Get("Content-Length"); cl != want {
+				t.Errorf("expected Content-Length %q got %q", want, cl)
+			}
+			if tt.tag == 0 {
+				if rec.Body.Len() == 0 || rec.Body.Len()%2 != 0 {
+					t.Errorf("expected 16 bit samples, got %d bytes", rec.Body.Len())
+				}
+				return
+			}
+			r, err := wav.NewReader(bytes.NewReader(rec.Body.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Format().Tag != tt.tag {
+				t.Errorf("expected %v got %v", tt.tag, r.Format().Tag)
+			}
+			if tt.sampleRate != "" && strconv.Itoa(int(r.SampleRate())) != tt.sampleRate {
+				t.Errorf("expected %s got %d", tt.sampleRate, r.SampleRate())
+			}
+			samples, err := r.ReadSamples()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(samples) == 0 {
+				t.Error("expected samples")
+			}
+		})
+	}
+}
+
+func TestHandler_stream(t *testing.T) {
+	h := newHandler(t, nil)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+	for _, format := range []string{"wav", "pcm"} {
+		resp, err := http.Get(srv.URL + "/speak?format=" + format + "&text=" + url.QueryEscape(strings.Repeat("Hello world. ", 20)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected %d got %d: %s", http.StatusOK, resp.StatusCode, body)
+		}
+		if len(resp.TransferEncoding) != 1 || resp.TransferEncoding[0] != "chunked" {
+			t.Errorf("expected a chunked %s response got %v", format, resp.TransferEncoding)
+		}
+		want, err := h.s.GenSamplesContext(context.Background(), strings.Repeat("Hello world. ", 20), nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format == "pcm" {
+			if len(body) != 2*len(want) {
+				t.Errorf("expected %d bytes got %d", 2*len(want), len(body))
+			}
+			continue
+		}
+		r, err := wav.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Frames() != -1 {
+			t.Errorf("expected the sizes of a streamed file, got %d frames", r.Frames())
+		}
+		if got, err := r.ReadSamples(); err != nil || len(got) != len(want) {
+			t.Errorf("expected %d samples got %d: %v", len(want), len(got), err)
+		}
+	}
+}
+
+func TestHandler_speakErrors(t *testing.T) {
+	h := newHandler(t, &Options{MaxText: 100})
+	for _, tt := range []struct {
+		name   string
+		req    *http.Request
+		status int
+		param  string
+	}{
+		{"no text", jsonRequest(`{"voice": "es"}`), http.StatusBadRequest, "text"},
+		{"too long", formRequest(url.Values{"text": {strings.Repeat("a", 101)}}), http.StatusRequestEntityTooLarge, "text"},
+		{"unknown voice", formRequest(url.Values{"text": {"Hi"}, "voice": {"klingon"}}), http.StatusNotFound, "voice"},
+		{"out of range", jsonRequest(`{"text": "Hi", "rate": 1000}`), http.StatusBadRequest, "rate"},
+		{"not an integer", formRequest(url.Values{"text": {"Hi"}, "pitch": {"high"}}), http.StatusBadRequest, "pitch"},
+		{"bad punctuation", jsonRequest(`{"text": "Hi", "punctuation": "most"}`), http.StatusBadRequest, "punctuation"},
+		{"bad sample rate", jsonRequest(`{"text": "Hi", "sample_rate": 12}`), http.StatusBadRequest, "sample_rate"},
+		{"bad format", jsonRequest(`{"text": "Hi", "format": "mp3"}`), http.StatusBadRequest, "format"},
+		{"bad boolean", jsonRequest(`{"text": "Hi", "ssml": "maybe"}`), http.StatusBadRequest, "ssml"},
+		{"bad ssml", jsonRequest(`{"text": "<speak>Hi", "ssml": true}`), http.StatusBadRequest, "text"},
+		{"object", jsonRequest(`{"text": {"en": "Hi"}}`), http.StatusBadRequest, "text"},
+		{"malformed", jsonRequest(`{"text": `), http.StatusBadRequest, ""},
+		{"content type", func() *http.Request {
+			r := httptest.NewRequest(http.MethodPost, "/speak", strings.NewReader("Hi"))
+			r.Header.Set("Content-Type", "text/plain")
+			return r
+		}(), http.StatusUnsupportedMediaType, ""},
+		{"method", httptest.NewRequest(http.MethodDelete, "/speak", nil), http.StatusMethodNotAllowed, ""},
+		{"endpoint", httptest.NewRequest(http.MethodGet, "/shout", nil), http.StatusNotFound, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, tt.req)
+			if rec.Code != tt.status {
+				t.Fatalf("expected %d got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			e := errorOf(t, rec)
+			if e.Param != tt.param {
+				t.Errorf("expected %q got %q", tt.param, e.Param)
+			}
+			if e.Message == "" || e.Type != invalidRequest {
+				t.Errorf("expected a message and %q got %+v", invalidRequest, e)
+			}
+		})
+	}
+}
+
+func TestHandler_voices(t *testing.T) {
+	h := newHandler(t, nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/voices?language=es", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected %d got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var voices []voice
+	if err := json.Unmarshal(rec.Body.Bytes(), &voices); err != nil {
+		t.Fatal(err)
+	}
+	if len(voices) == 0 {
+		t.Fatal("expected voices")
+	}
+	for _, v := range voices {
+		if !strings.HasPrefix(v.Language, "es") {
+			t.Errorf("expected a voice of %q got %+v", "es", v)
+		}
+	}
+}
+
+func TestHandler_healthz(t *testing.T) {
+	h := newHandler(t, nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected %d got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+
+	h.s.Close()
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected %d got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	if e := errorOf(t, rec); e.Type != serverError {
+		t.Errorf("expected %q got %q", serverError, e.Type)
+	}
+}
+
+func TestHandler_audioSpeech(t *testing.T) {
+	h := newHandler(t, &Options{VoiceAliases: map[string]string{"Alloy": "en-us", "carmen": "es"}})
+	speech := func(body string) *http.Request {
+		r := jsonRequest(body)
+		r.URL.Path = "/v1/audio/speech"
+		return r
+	}
+
+	for _, tt := range []struct {
+		name        string
+		body        string
+		contentType string
+		rate        int32
+	}{
+		{"wav", `{"model": "tts-1", "input": "Hello world", "voice": "alloy", "response_format": "wav"}`, "audio/wav", h.s.SampleRate()},
+		{"default format", `{"model": "tts-1", "input": "Hola mundo", "voice": "carmen", "speed": 1.5}`, "audio/wav", h.s.SampleRate()},
+		{"espeak voice", `{"model": "tts-1", "input": "Bonjour", "voice": "fr", "response_format": "wav-alaw"}`, "audio/wav", h.s.SampleRate()},
+		{"pcm", `{"model": "tts-1-hd", "input": "Hello world", "voice": "ALLOY", "response_format": "pcm", "speed": 0.25}`, "audio/pcm;rate=24000;channels=1", 24000},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, speech(tt.body))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected %d got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("expected %q got %q", tt.contentType, ct)
+			}
+			if cl := rec.Header().Get("Content-Length"); cl != "" {
+				t.Errorf("expected a streamed response, got Content-Length %s", cl)
+			}
+			if strings.HasPrefix(tt.contentType, "audio/wav") {
+				r, err := wav.NewReader(bytes.NewReader(rec.Body.Bytes()))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if r.SampleRate() != tt.rate {
+					t.Errorf("expected %d got %d", tt.rate, r.SampleRate())
+				}
+			}
+		})
+	}
+
+	// the rate follows speed.
+	lengths := make(map[string]int)
+	for _, speed := range []string{"0.5", "2"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, speech(`{"input": "Hello world, once more", "voice": "alloy", "response_format": "pcm", "speed": `+speed+`}`))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected %d got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+		}
+		lengths[speed] = rec.Body.Len()
+	}
+	if lengths["0.5"] <= lengths["2"] {
+		t.Errorf("expected slower speech to be longer, got %v", lengths)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		req    *http.Request
+		status int
+		param  string
+	}{
+		{"no input", speech(`{"model": "tts-1", "voice": "alloy"}`), http.StatusBadRequest, "input"},
+		{"mp3", speech(`{"input": "Hi", "voice": "alloy", "response_format": "mp3"}`), http.StatusBadRequest, "response_format"},
+		{"speed", speech(`{"input": "Hi", "voice": "alloy", "speed": 5}`), http.StatusBadRequest, "speed"},
+		{"unknown voice", speech(`{"input": "Hi", "voice": "klingon"}`), http.StatusNotFound, "voice"},
+		{"malformed", speech(`{"input": 1}`), http.StatusBadRequest, ""},
+		{"form", func() *http.Request {
+			r := formRequest(url.Values{"input": {"Hi"}})
+			r.URL.Path = "/v1/audio/speech"
+			return r
+		}(), http.StatusUnsupportedMediaType, ""},
+		{"method", httptest.NewRequest(http.MethodGet, "/v1/audio/speech", nil), http.StatusMethodNotAllowed, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, tt.req)
+			if rec.Code != tt.status {
+				t.Fatalf("expected %d got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if e := errorOf(t, rec); e.Param != tt.param {
+				t.Errorf("expected %q got %q", tt.param, e.Param)
+			}
+		})
+	}
+}
+
+func formRequest(form url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/speak", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func jsonRequest(body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/speak", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json; charset=utf-8")
+	return r
+}
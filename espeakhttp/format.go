@@ -0,0 +1,138 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package espeakhttp
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/djangulo/go-espeak"
+	"github.com/djangulo/go-espeak/wav"
+)
+
+// format an encoding of the audio of a response.
+type format struct {
+	// contentType of the response.
+	contentType string
+	// raw 16 bit little endian samples, rather than a .wav file of tag and
+	// bits per sample.
+	raw  bool
+	tag  wav.FormatTag
+	bits int
+}
+
+// formats the formats of responses, by name.
+var formats = map[string]*format{
+	"wav":       {contentType: "audio/wav", tag: wav.PCM, bits: 16},
+	"wav-float": {contentType: "audio/wav", tag: wav.IEEEFloat, bits: 32},
+	"wav-alaw":  {contentType: "audio/wav", tag: wav.ALaw, bits: 8},
+	"wav-mulaw": {contentType: "audio/wav", tag: wav.MuLaw, bits: 8},
+	"pcm":       {contentType: "audio/pcm", raw: true},
+}
+
+// formatNames returns the names of formats, for errors.
+func formatNames() string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// mediaType returns the Content-Type of audio in f, at rate.
+func (f *format) mediaType(rate int32) string {
+	if f.raw {
+		// there's no header to tell.
+		return f.contentType + ";rate=" + strconv.Itoa(int(rate)) + ";channels=1"
+	}
+	return f.contentType
+}
+
+// encoder writes samples in a format, as they come.
+type encoder interface {
+	WriteSamples(samples []int16) (uint64, error)
+	Close() error
+}
+
+// encoder returns an encoder of samples at rate to w, in f. A .wav file
+// written to an io.WriteSeeker has its sizes fixed on Close, to other
+// io.Writers it has those of streamed files.
+func (f *format) encoder(w io.Writer, rate int32) (encoder, error) {
+	if f.raw {
+		return rawWriter{w}, nil
+	}
+	ww, err := wav.NewFormatWriter(w, wav.Format{Tag: f.tag, Channels: 1, SampleRate: rate, BitsPerSample: f.bits})
+	if err != nil {
+		return nil, err
+	}
+	return ww, nil
+}
+
+// encode returns the audio of r in f.
+func (f *format) encode(r *espeak.Result) ([]byte, error) {
+	var b buffer
+	enc, err := f.encoder(&b, r.SampleRate)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := enc.WriteSamples(r.Samples); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return b.data, nil
+}
+
+// rawWriter an encoder of raw 16 bit little endian samples.
+type rawWriter struct {
+	w io.Writer
+}
+
+func (rw rawWriter) WriteSamples(samples []int16) (uint64, error) {
+	data := make([]byte, 2*len(samples))
+	for i, s := range samples {
+		binary.LittleEndian.PutUint16(data[2*i:], uint16(s))
+	}
+	n, err := rw.w.Write(data)
+	return uint64(n / 2), err
+}
+
+func (rw rawWriter) Close() error {
+	return nil
+}
+
+// buffer an in memory io.WriteSeeker, for the .wav writer to fix the sizes
+// in its header.
+type buffer struct {
+	data []byte
+	pos  int
+}
+
+func (b *buffer) Write(p []byte) (int, error) {
+	if end := b.pos + len(p); end > len(b.data) {
+		b.data = append(b.data, make([]byte, end-len(b.data))...)
+	}
+	b.pos += copy(b.data[b.pos:], p)
+	return len(p), nil
+}
+
+func (b *buffer) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += int64(b.pos)
+	case io.SeekEnd:
+		offset += int64(len(b.data))
+	}
+	if offset < 0 {
+		return 0, errors.New("espeakhttp: negative position")
+	}
+	b.pos = int(offset)
+	return offset, nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package espeakhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// DefaultVoiceAliases the voices of the OpenAI speech API, by the espeak
+// voice, identifier or language each is spoken with.
+var DefaultVoiceAliases = map[string]string{
+	"alloy":   "en-us",
+	"ash":     "en-us",
+	"ballad":  "en",
+	"coral":   "en-us",
+	"echo":    "en-us",
+	"fable":   "en",
+	"nova":    "en-us",
+	"onyx":    "en-us",
+	"sage":    "en",
+	"shimmer": "en-us",
+	"verse":   "en",
+}
+
+// pcmRate the sample rate of the OpenAI speech API's pcm format.
+const pcmRate = 24000
+
+// speechRequest the body of a /v1/audio/speech request.
+type speechRequest struct {
+	// Model is accepted, and ignored.
+	Model          string   `json:"model"`
+	Input          string   `json:"input"`
+	Voice          string   `json:"voice"`
+	ResponseFormat string   `json:"response_format"`
+	Speed          *float64 `json:"speed"`
+}
+
+// audioSpeech answers /v1/audio/speech, as the OpenAI speech API's
+// https://platform.openai.com/docs/api-reference/audio/createSpeech does.
+// speed scales the Rate of h's parameters, within espeak's range.
+func (h *Handler) audioSpeech(w http.ResponseWriter, r *http.Request) {
+	if err := allow(r, http.MethodPost); err != nil {
+		writeError(w, err)
+		return
+	}
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		if media, _, err := mime.ParseMediaType(ct); err != nil || media != "application/json" {
+			writeError(w, &Error{
+				Status:  http.StatusUnsupportedMediaType,
+				Type:    invalidRequest,
+				Message: "unsupported content type " + ct + ", send application/json",
+			})
+			return
+		}
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, int64(h.opts.MaxText)*4+maxBody)
+	var sr speechRequest
+	if err := json.NewDecoder(r.Body).Decode(&sr); err != nil {
+		writeError(w, bodyError(err))
+		return
+	}
+
+	fields := map[string]string{"text": sr.Input}
+	if name := sr.Voice; name != "" {
+		if alias, ok := h.opts.VoiceAliases[strings.ToLower(name)]; ok {
+			name = alias
+		}
+		fields["voice"] = name
+	}
+	switch f := strings.ToLower(sr.ResponseFormat); f {
+	case "":
+	case "pcm":
+		fields["format"] = f
+		fields["sample_rate"] = fmt.Sprint(pcmRate)
+	default:
+		if _, ok := formats[f]; !ok {
+			writeError(w, paramError("response_format", fmt.Sprintf("unsupported response_format %q, one of %s", sr.ResponseFormat, formatNames())))
+			return
+		}
+		fields["format"] = f
+	}
+	req, e := h.parse(fields)
+	if e != nil {
+		if e.Param == "text" {
+			e.Param = "input"
+		}
+		writeError(w, e)
+		return
+	}
+	if sr.Speed != nil {
+		speed := *sr.Speed
+		if speed < 0.25 || speed > 4 {
+			writeError(w, paramError("speed", fmt.Sprintf("speed %g is out of range, 0.25 to 4", speed)))
+			return
+		}
+		rate := int(math.Round(float64(req.params.Rate) * speed))
+		if rate < 80 {
+			rate = 80
+		}
+		if rate > 450 {
+			rate = 450
+		}
+		req.params.Rate = rate
+	}
+	h.respond(w, r, req)
+}
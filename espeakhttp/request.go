@@ -0,0 +1,377 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package espeakhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/djangulo/go-espeak"
+	"github.com/djangulo/go-espeak/normalize"
+	"github.com/djangulo/go-espeak/resample"
+	"github.com/djangulo/go-espeak/ssml"
+)
+
+// maxBody bytes of a request body, on top of the text it may have.
+const maxBody = 64 << 10
+
+// request a speech request, parsed.
+type request struct {
+	text   string
+	voice  *espeak.Voice
+	params *espeak.Parameters
+	ssml   bool
+	format *format
+}
+
+// fields returns the fields of r: the members of its JSON body, or the
+// values of its form, and query, first one of each.
+func (h *Handler) fields(w http.ResponseWriter, r *http.Request) (map[string]string, *Error) {
+	media := ""
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		var err error
+		if media, _, err = mime.ParseMediaType(ct); err != nil {
+			return nil, &Error{Status: http.StatusUnsupportedMediaType, Type: invalidRequest, Message: err.Error()}
+		}
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, int64(h.opts.MaxText)*4+maxBody)
+	fields := make(map[string]string)
+	switch {
+	case r.Method == http.MethodPost && media == "application/json":
+		d := json.NewDecoder(r.Body)
+		d.UseNumber()
+		var obj map[string]interface{}
+		if err := d.Decode(&obj); err != nil {
+			return nil, bodyError(err)
+		}
+		for k, v := range obj {
+			switch v := v.(type) {
+			case nil:
+			case string:
+				fields[k] = v
+			case json.Number:
+				fields[k] = v.String()
+			case bool:
+				fields[k] = strconv.FormatBool(v)
+			case []interface{}:
+				// a list of languages.
+				var list []string
+				for _, e := range v {
+					s, ok := e.(string)
+					if !ok {
+						return nil, paramError(k, k+" must be a list of strings")
+					}
+					list = append(list, s)
+				}
+				fields[k] = strings.Join(list, ",")
+			default:
+				return nil, paramError(k, k+" must be a string, number or boolean")
+			}
+		}
+		return fields, nil
+	case r.Method == http.MethodPost && media == "multipart/form-data":
+		if err := r.ParseMultipartForm(maxBody); err != nil {
+			return nil, bodyError(err)
+		}
+	case r.Method != http.MethodPost || media == "" || media == "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err != nil {
+			return nil, bodyError(err)
+		}
+	default:
+		return nil, &Error{
+			Status:  http.StatusUnsupportedMediaType,
+			Type:    invalidRequest,
+			Message: "unsupported content type " + media + ", send application/json or a form",
+		}
+	}
+	for k, v := range r.Form {
+		if len(v) > 0 {
+			fields[k] = v[0]
+		}
+	}
+	return fields, nil
+}
+
+// bodyError returns the *Error of err, returned reading a request's body.
+func bodyError(err error) *Error {
+	if strings.Contains(err.Error(), "request body too large") {
+		return &Error{Status: http.StatusRequestEntityTooLarge, Type: invalidRequest, Code: "body_too_large", Message: err.Error()}
+	}
+	return &Error{Status: http.StatusBadRequest, Type: invalidRequest, Message: "malformed request: " + err.Error()}
+}
+
+// parse returns the request of fields: text, voice, ssml, format, and the
+// overrides of h's parameters.
+func (h *Handler) parse(fields map[string]string) (*request, *Error) {
+	req := &request{text: fields["text"], voice: h.opts.Voice, format: formats["wav"]}
+	if strings.TrimSpace(req.text) == "" {
+		return nil, paramError("text", "no text to speak")
+	}
+	if len(req.text) > h.opts.MaxText {
+		return nil, &Error{
+			Status:  http.StatusRequestEntityTooLarge,
+			Type:    invalidRequest,
+			Param:   "text",
+			Code:    "text_too_long",
+			Message: fmt.Sprintf("text is longer than %d bytes", h.opts.MaxText),
+		}
+	}
+	if name := fields["voice"]; name != "" {
+		v, err := h.s.FindVoice(name)
+		if err != nil {
+			return nil, synthError(err)
+		}
+		req.voice = v
+	}
+	if name := fields["format"]; name != "" {
+		f, ok := formats[strings.ToLower(name)]
+		if !ok {
+			return nil, paramError("format", fmt.Sprintf("unknown format %q, one of %s", name, formatNames()))
+		}
+		req.format = f
+	}
+	var e *Error
+	if req.ssml, e = boolParam(fields, "ssml"); e != nil {
+		return nil, e
+	}
+	if req.params, e = h.parameters(fields); e != nil {
+		return nil, e
+	}
+	return req, nil
+}
+
+// parameters returns h's parameters, with the overrides of fields.
+func (h *Handler) parameters(fields map[string]string) (*espeak.Parameters, *Error) {
+	base := h.opts.Parameters
+	if base == nil {
+		base = h.s.Parameters()
+	}
+	p := *base
+	for _, ip := range []struct {
+		name     string
+		dst      *int
+		min, max int
+	}{
+		{"rate", &p.Rate, 80, 450},
+		{"volume", &p.Volume, 0, 200},
+		{"pitch", &p.Pitch, 0, 100},
+		{"range", &p.Range, 0, 100},
+		{"word_gap", &p.WordGap, 0, 1000},
+	} {
+		v, ok := fields[ip.name]
+		if !ok || v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, paramError(ip.name, fmt.Sprintf("%s %q is not an integer", ip.name, v))
+		}
+		if n < ip.min || n > ip.max {
+			return nil, paramError(ip.name, fmt.Sprintf("%s %d is out of range, %d to %d", ip.name, n, ip.min, ip.max))
+		}
+		*ip.dst = n
+	}
+	if v := fields["punctuation"]; v != "" {
+		if err := p.AnnouncePunctuation.UnmarshalText([]byte(v)); err != nil {
+			return nil, paramError("punctuation", err.Error())
+		}
+	}
+	if v, ok := fields["punctuation_list"]; ok {
+		p.SetPunctuationList(v)
+		if fields["punctuation"] == "" && v != "" {
+			p.AnnouncePunctuation = espeak.PunctSome
+		}
+	}
+	if v := fields["capitals"]; v != "" {
+		if err := p.AnnounceCapitals.UnmarshalText([]byte(v)); err != nil {
+			return nil, paramError("capitals", err.Error())
+		}
+	}
+	if v := fields["sample_rate"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || (n != 0 && (n < 1000 || n > 192000)) {
+			return nil, paramError("sample_rate", fmt.Sprintf("sample_rate %q is not 0, or 1000 to 192000", v))
+		}
+		p.SampleRate = int32(n)
+	}
+	if v := fields["quality"]; v != "" {
+		q, ok := map[string]resample.Quality{
+			"default": resample.Default,
+			"low":     resample.Low,
+			"medium":  resample.Medium,
+			"high":    resample.High,
+		}[strings.ToLower(v)]
+		if !ok {
+			return nil, paramError("quality", fmt.Sprintf("unknown quality %q, one of default, low, medium or high", v))
+		}
+		p.ResampleQuality = q
+	}
+	norm, e := boolParam(fields, "normalize")
+	if e != nil {
+		return nil, e
+	}
+	if norm && p.Normalizer == nil {
+		p.Normalizer = normalize.New()
+	}
+	auto, e := boolParam(fields, "auto_voice")
+	if e != nil {
+		return nil, e
+	}
+	if auto {
+		a := &espeak.AutoVoice{}
+		if p.AutoVoice != nil {
+			*a = *p.AutoVoice
+		}
+		if v := fields["languages"]; v != "" {
+			a.Languages = nil
+			for _, l := range strings.Split(v, ",") {
+				if l = strings.TrimSpace(l); l != "" {
+					a.Languages = append(a.Languages, l)
+				}
+			}
+		}
+		if v := fields["min_confidence"]; v != "" {
+			c, err := strconv.ParseFloat(v, 64)
+			if err != nil || c < 0 || c > 1 {
+				return nil, paramError("min_confidence", fmt.Sprintf("min_confidence %q is not 0 to 1", v))
+			}
+			a.MinConfidence = c
+		}
+		if v := fields["fallback"]; v != "" {
+			fallback, err := h.s.FindVoice(v)
+			if err != nil {
+				e := synthError(err)
+				e.Param = "fallback"
+				return nil, e
+			}
+			a.Fallback = fallback
+		}
+		p.AutoVoice = a
+	}
+	return &p, nil
+}
+
+// boolParam returns the boolean field name, false if it's not set.
+func boolParam(fields map[string]string, name string) (bool, *Error) {
+	v := fields[name]
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, paramError(name, fmt.Sprintf("%s %q is not a boolean", name, v))
+	}
+	return b, nil
+}
+
+// synthesizeSSML returns the audio of req's SSML.
+func (h *Handler) synthesizeSSML(ctx context.Context, req *request) (*espeak.Result, error) {
+	segments, err := espeak.SSMLSegments(req.text)
+	if errors.Is(err, ssml.ErrSyntax) {
+		return nil, paramError("text", err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return h.s.SynthSegments(ctx, segments, req.voice, req.params)
+}
+
+// speak responds with the audio of the request's text.
+func (h *Handler) speak(w http.ResponseWriter, r *http.Request) {
+	if err := allow(r, http.MethodGet, http.MethodPost); err != nil {
+		writeError(w, err)
+		return
+	}
+	fields, e := h.fields(w, r)
+	if e != nil {
+		writeError(w, e)
+		return
+	}
+	req, e := h.parse(fields)
+	if e != nil {
+		writeError(w, e)
+		return
+	}
+	h.respond(w, r, req)
+}
+
+// respond writes the audio of req, in its format. Text is streamed, see
+// stream, SSML is written once synthesized, with its length.
+func (h *Handler) respond(w http.ResponseWriter, r *http.Request, req *request) {
+	if !req.ssml {
+		h.stream(w, r, req)
+		return
+	}
+	res, err := h.synthesizeSSML(r.Context(), req)
+	if r.Context().Err() != nil {
+		// the client is gone.
+		return
+	}
+	if err != nil {
+		writeError(w, synthError(err))
+		return
+	}
+	data, err := req.format.encode(res)
+	if err != nil {
+		writeError(w, synthError(err))
+		return
+	}
+	w.Header().Set("Content-Type", req.format.mediaType(res.SampleRate))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Write(data)
+}
+
+// stream writes the audio of req's text as espeak synthesizes it, with no
+// Content-Length, so it's sent chunked. Errors before the first chunk get
+// an error response, later ones abort it, the status having been sent.
+func (h *Handler) stream(w http.ResponseWriter, r *http.Request, req *request) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	chunks, err := h.s.SynthChunks(ctx, req.text, req.voice, req.params)
+	if err != nil {
+		writeError(w, synthError(err))
+		return
+	}
+	chunk, ok := <-chunks
+	if r.Context().Err() != nil {
+		// the client is gone.
+		return
+	}
+	if chunk.Err != nil {
+		writeError(w, synthError(chunk.Err))
+		return
+	}
+	rate := req.params.SampleRate
+	if rate == 0 {
+		rate = h.s.SampleRate()
+	}
+	enc, err := req.format.encoder(w, rate)
+	if err != nil {
+		writeError(w, synthError(err))
+		return
+	}
+	w.Header().Set("Content-Type", req.format.mediaType(rate))
+	flusher, _ := w.(http.Flusher)
+	for ; ok; chunk, ok = <-chunks {
+		if r.Context().Err() != nil {
+			return
+		}
+		if chunk.Err != nil {
+			panic(http.ErrAbortHandler)
+		}
+		if _, err := enc.WriteSamples(chunk.Samples); err != nil {
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	enc.Close()
+}
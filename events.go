@@ -0,0 +1,132 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package espeak
+
+/*
+#cgo CFLAGS: -I/usr/include/espeak
+#cgo LDFLAGS: -lportaudio -lespeak
+#include <speak_lib.h>
+
+// cgo can't access unions, these read espeak_EVENT.id.
+static inline const char *eventName(espeak_EVENT *event) {
+	return event->id.name;
+}
+
+static inline const char *eventString(espeak_EVENT *event) {
+	return event->id.string;
+}
+*/
+import "C"
+import (
+	"strings"
+	"unsafe"
+)
+
+// EventType analogous to espeak_EVENT_TYPE.
+type EventType int
+
+const (
+	// WordEvent start of word.
+	WordEvent EventType = iota + 1
+	// SentenceEvent start of sentence.
+	SentenceEvent
+	// MarkEvent SSML <mark> element.
+	MarkEvent
+	// PlayEvent SSML <audio> element.
+	PlayEvent
+	// EndEvent end of sentence or clause.
+	EndEvent
+	// MsgTerminatedEvent end of message.
+	MsgTerminatedEvent
+	// PhonemeEvent phoneme, emitted if espeak was initialized with
+	// PhonemeEvents.
+	PhonemeEvent
+	// SampleRateEvent sample rate changed (mbrola voices).
+	SampleRateEvent
+)
+
+func (t EventType) String() string {
+	switch t {
+	case WordEvent:
+		return "word"
+	case SentenceEvent:
+		return "sentence"
+	case MarkEvent:
+		return "mark"
+	case PlayEvent:
+		return "play"
+	case EndEvent:
+		return "end"
+	case MsgTerminatedEvent:
+		return "msg-terminated"
+	case PhonemeEvent:
+		return "phoneme"
+	case SampleRateEvent:
+		return "sample-rate"
+	default:
+		return "unknown"
+	}
+}
+
+// Event analogous to espeak_EVENT. Events are reported by espeak during
+// synthesis, see Synthesizer.Synthesize and Chunk.
+type Event struct {
+	Type EventType `json:"type"`
+	// TextPosition position in the text the event refers to, in characters
+	// (not bytes), starting at 1.
+	TextPosition int `json:"text_position"`
+	// Length of the word, in characters. Only set for WordEvent.
+	Length int `json:"length,omitempty"`
+	// AudioPosition time within the generated speech, in mS.
+	AudioPosition int `json:"audio_position"`
+	// Sample offset within the generated speech.
+	Sample int `json:"sample"`
+	// Name of the SSML <mark> (MarkEvent) or <audio> (PlayEvent).
+	Name string `json:"name,omitempty"`
+	// Phoneme mnemonic, or IPA if espeak was initialized with PhonemeIPA.
+	// Only set for PhonemeEvent.
+	Phoneme string `json:"phoneme,omitempty"`
+	// SampleRate only set for SampleRateEvent.
+	SampleRate int `json:"sample_rate,omitempty"`
+}
+
+// eventsFromC converts an espeak_EVENT list, terminated by an
+// espeakEVENT_LIST_TERMINATED event, into a []Event.
+func eventsFromC(events *C.espeak_EVENT) []Event {
+	if events == nil {
+		return nil
+	}
+	list := (*[1 << 20]C.espeak_EVENT)(unsafe.Pointer(events))
+	var out []Event
+	for i := range list {
+		ce := &list[i]
+		if ce._type == C.espeakEVENT_LIST_TERMINATED {
+			break
+		}
+		e := Event{
+			Type:          EventType(ce._type),
+			TextPosition:  int(ce.text_position),
+			AudioPosition: int(ce.audio_position),
+			Sample:        int(ce.sample),
+		}
+		switch e.Type {
+		case WordEvent:
+			e.Length = int(ce.length)
+		case MarkEvent, PlayEvent:
+			if name := C.eventName(ce); name != nil {
+				e.Name = C.GoString(name)
+			}
+		case PhonemeEvent:
+			// id.string is 8 bytes, not necessarily NUL terminated.
+			e.Phoneme = C.GoStringN(C.eventString(ce), 8)
+			if i := strings.IndexByte(e.Phoneme, 0); i >= 0 {
+				e.Phoneme = e.Phoneme[:i]
+			}
+		case SampleRateEvent:
+			e.SampleRate = int(*(*C.int)(unsafe.Pointer(&ce.id)))
+		}
+		out = append(out, e)
+	}
+	return out
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package espeak
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSynthesize_events(t *testing.T) {
+	r, err := Synthesize(context.Background(), "Hello world. Hello again.", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Samples) == 0 {
+		t.Fatal("0 samples generated")
+	}
+	if r.SampleRate <= 0 {
+		t.Errorf("expected a positive sample rate, got %d", r.SampleRate)
+	}
+	count := make(map[EventType]int)
+	last := 0
+	for _, e := range r.Events {
+		count[e.Type]++
+		if e.Sample < last {
+			t.Errorf("event %+v out of order, previous sample %d", e, last)
+		}
+		if e.Sample > len(r.Samples) {
+			t.Errorf("event %+v past the end of the samples (%d)", e, len(r.Samples))
+		}
+		last = e.Sample
+		switch e.Type {
+		case WordEvent:
+			if e.Length == 0 {
+				t.Errorf("word event with no length: %+v", e)
+			}
+		case PhonemeEvent:
+			if e.Phoneme == "" {
+				t.Errorf("phoneme event with no phoneme: %+v", e)
+			}
+		}
+	}
+	if count[WordEvent] != 4 {
+		t.Errorf("expected 4 word events, got %d", count[WordEvent])
+	}
+	if count[SentenceEvent] == 0 {
+		t.Error("expected sentence events")
+	}
+	if count[PhonemeEvent] == 0 {
+		t.Error("expected phoneme events")
+	}
+	if n := len(r.Events); n == 0 || r.Events[n-1].Type != MsgTerminatedEvent {
+		t.Errorf("expected the last event to be %v", MsgTerminatedEvent)
+	}
+
+	t.Run("chunks", func(t *testing.T) {
+		chunks, err := SynthChunks(context.Background(), "Hello world. Hello again.", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var events []Event
+		for c := range chunks {
+			events = append(events, c.Events...)
+		}
+		if len(events) != len(r.Events) {
+			t.Fatalf("expected %d events, got %d", len(r.Events), len(events))
+		}
+		for i := range events {
+			if events[i] != r.Events[i] {
+				t.Errorf("event %d: expected %+v got %+v", i, r.Events[i], events[i])
+			}
+		}
+	})
+}
+
+func TestEventType_String(t *testing.T) {
+	for _, tt := range []struct {
+		in   EventType
+		want string
+	}{
+		{WordEvent, "word"},
+		{SentenceEvent, "sentence"},
+		{MarkEvent, "mark"},
+		{PhonemeEvent, "phoneme"},
+		{EventType(100), "unknown"},
+	} {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("expected %q got %q", tt.want, got)
+		}
+	}
+}
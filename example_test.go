@@ -25,3 +25,18 @@ func ExampleTextToSpeech_customVoice() {
 	}
 	espeak.TextToSpeech("Γειά σου Κόσμε!", &greek, "play", nil)
 }
+
+// ExampleNewSynthesizer shows usage of a session-scoped Synthesizer.
+func ExampleNewSynthesizer() {
+	s, err := espeak.NewSynthesizer(espeak.Synchronous, 200, nil, espeak.PhonemeEvents)
+	if err != nil {
+		panic(err)
+	}
+	defer s.Close()
+	s.SetVoice(espeak.ESSpainMale)
+	s.SetParameters(espeak.NewParameters(espeak.WithRate(150)))
+	// uses the synthesizer's voice and parameters
+	s.GenSamples("¡Hola mundo!", nil, nil)
+	// per-call overrides
+	s.GenSamples("Hello world!", espeak.ENUSMale, nil)
+}
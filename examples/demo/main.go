@@ -143,7 +143,7 @@ func serve(w http.ResponseWriter, r *http.Request) {
 	if d.Say != "" {
 		src := randString(64) + ".wav"
 		d.FileSource = "/audio/" + src
-		_, err = espeak.TextToSpeech(d.Say, voice, src, params)
+		_, err = espeak.TextToSpeechContext(r.Context(), d.Say, voice, src, params)
 		if err != nil {
 			http.Error(w, err.Error(), 500)
 			return
@@ -175,7 +175,7 @@ func download(w http.ResponseWriter, r *http.Request) {
 	if d.Say != "" {
 		src := randString(64) + ".wav"
 		d.FileSource = "/downloads/" + src
-		_, err = espeak.TextToSpeech(d.Say, voice, src, params)
+		_, err = espeak.TextToSpeechContext(r.Context(), d.Say, voice, src, params)
 		if err != nil {
 			http.Error(w, err.Error(), 500)
 			return
@@ -0,0 +1,23 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+// Package lang holds the helpers for language tags and words shared by the
+// lexicon and normalize packages.
+package lang
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Normalize returns tag trimmed and lower cased, with underscores turned
+// into hyphens, e.g. "en-us" for "en_US".
+func Normalize(tag string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(tag), "_", "-", -1))
+}
+
+// IsWordRune returns whether r is part of a word: a letter, a digit, a
+// combining mark or an underscore.
+func IsWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
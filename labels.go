@@ -0,0 +1,197 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package espeak
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/djangulo/go-espeak/wav"
+)
+
+// LabelKind kinds of labels built from a synthesis, see Labels.
+type LabelKind int
+
+const (
+	// WordLabels a region per word.
+	WordLabels LabelKind = 1 << iota
+	// SentenceLabels a region per sentence.
+	SentenceLabels
+	// MarkLabels a point per SSML <mark>, labeled with its name.
+	MarkLabels
+)
+
+// Label a region of audio, or a point if Start and End are equal, as shown
+// by audio editors.
+type Label struct {
+	Start, End time.Duration
+	Text       string
+}
+
+// Labels builds the labels of kinds of text, from r, its synthesis. Labels
+// are sorted by start, those enclosing others first.
+func Labels(text string, r *Result, kinds LabelKind) []Label {
+	labels := make([]Label, 0)
+	if kinds&WordLabels != 0 {
+		for _, w := range r.Words {
+			labels = append(labels, Label{Start: w.StartTime, End: w.EndTime, Text: w.Text})
+		}
+	}
+	idx := newTextIndex(text)
+	for i, e := range r.Events {
+		switch {
+		case e.Type == MarkEvent && kinds&MarkLabels != 0:
+			at := samplesToDuration(e.Sample, r.SampleRate)
+			labels = append(labels, Label{Start: at, End: at, Text: e.Name})
+		case e.Type == SentenceEvent && kinds&SentenceLabels != 0:
+			// a sentence ends where the next one starts.
+			start, end := idx.byteOffset(e.TextPosition), len(text)
+			endSample := len(r.Samples)
+			for _, next := range r.Events[i+1:] {
+				if next.Type == SentenceEvent {
+					end, endSample = idx.byteOffset(next.TextPosition), next.Sample
+					break
+				}
+			}
+			if end < start {
+				end = start
+			}
+			labels = append(labels, Label{
+				Start: samplesToDuration(e.Sample, r.SampleRate),
+				End:   samplesToDuration(endSample, r.SampleRate),
+				Text:  strings.Join(strings.Fields(text[start:end]), " "),
+			})
+		}
+	}
+	sort.SliceStable(labels, func(i, j int) bool {
+		if labels[i].Start != labels[j].Start {
+			return labels[i].Start < labels[j].Start
+		}
+		return labels[i].End > labels[j].End
+	})
+	return labels
+}
+
+// WriteAudacityLabels writes labels to w as an Audacity label track, which
+// File > Import > Labels... reads.
+func WriteAudacityLabels(w io.Writer, labels []Label) error {
+	bw := bufio.NewWriter(w)
+	for _, l := range labels {
+		text := strings.Join(strings.Fields(l.Text), " ")
+		fmt.Fprintf(bw, "%.6f\t%.6f\t%s\n", l.Start.Seconds(), l.End.Seconds(), text)
+	}
+	return bw.Flush()
+}
+
+// Markers converts labels into .wav markers of audio at sampleRate, see
+// wav.Writer.AddMarkers.
+func Markers(labels []Label, sampleRate int32) []wav.Marker {
+	markers := make([]wav.Marker, len(labels))
+	for i, l := range labels {
+		start := durationToSamples(l.Start, sampleRate)
+		markers[i] = wav.Marker{
+			Position: uint32(start),
+			Length:   uint32(durationToSamples(l.End, sampleRate) - start),
+			Label:    l.Text,
+		}
+	}
+	return markers
+}
+
+// LabelOptions the labels written by TextToSpeechLabels.
+type LabelOptions struct {
+	// Kinds of labels. Default WordLabels.
+	Kinds LabelKind
+	// Cues the labels are also embedded in the .wav file, as cue points
+	// and regions.
+	Cues bool
+	// SSML the text is an SSML document.
+	SSML bool
+}
+
+// TextToSpeechLabels is like TextToSpeechContext, saving the audio to
+// params.Dir/outfile[.wav], and the labels of text to a label track next to
+// it, params.Dir/outfile.txt, which Audacity imports. opts may be nil.
+// Returns the labels.
+func (s *Synthesizer) TextToSpeechLabels(ctx context.Context, text string, voice *Voice, outfile string, params *Parameters, opts *LabelOptions) ([]Label, error) {
+	if opts == nil {
+		opts = &LabelOptions{}
+	}
+	kinds := opts.Kinds
+	if kinds == 0 {
+		kinds = WordLabels
+	}
+	flags := CharsAuto | EndPause
+	if opts.SSML {
+		flags |= SSML
+	}
+	r, err := s.synthesizeResult(ctx, text, flags, voice, params)
+	if err != nil {
+		return nil, err
+	}
+	_, params, err = s.resolve(voice, params)
+	if err != nil {
+		return nil, err
+	}
+	labels := Labels(text, r, kinds)
+
+	if err := os.MkdirAll(params.Dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(params.Dir, ensureWavSuffix(outfile))
+	txt := strings.TrimSuffix(path, ".wav") + ".txt"
+	err = writeFile(path, func(f *os.File) error {
+		w := wav.NewWriter(f, r.SampleRate)
+		if opts.Cues {
+			w.AddMarkers(Markers(labels, r.SampleRate)...)
+		}
+		if _, err := w.WriteSamples(r.Samples); err != nil {
+			return err
+		}
+		return w.Close()
+	})
+	if err == nil {
+		err = writeFile(txt, func(f *os.File) error {
+			return WriteAudacityLabels(f, labels)
+		})
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return labels, nil
+}
+
+// writeFile creates the file at path and fills it with write.
+func writeFile(path string, write func(f *os.File) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
+
+// TextToSpeechLabels saves text's audio and labels through the default
+// synthesizer. See Synthesizer.TextToSpeechLabels.
+func TextToSpeechLabels(ctx context.Context, text string, voice *Voice, outfile string, params *Parameters, opts *LabelOptions) ([]Label, error) {
+	s, err := defaultSynthesizer()
+	if err != nil {
+		return nil, err
+	}
+	return s.TextToSpeechLabels(ctx, text, voice, outfile, params, opts)
+}
@@ -0,0 +1,125 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package espeak
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/djangulo/go-espeak/wav"
+)
+
+func TestLabels(t *testing.T) {
+	text := "Hi there. Bye."
+	r := &Result{
+		SampleRate: 1000,
+		Samples:    make([]int16, 3000),
+		Events: []Event{
+			{Type: SentenceEvent, TextPosition: 1, Sample: 0},
+			{Type: WordEvent, TextPosition: 1, Length: 2, Sample: 0},
+			{Type: WordEvent, TextPosition: 4, Length: 6, Sample: 500},
+			{Type: MarkEvent, Name: "here", Sample: 1200},
+			{Type: SentenceEvent, TextPosition: 11, Sample: 1500},
+			{Type: WordEvent, TextPosition: 11, Length: 4, Sample: 1500},
+		},
+	}
+	r.Words = AlignWords(text, r.Events, r.SampleRate, len(r.Samples))
+	ms := time.Millisecond
+	want := []Label{
+		{0, 1500 * ms, "Hi there."},
+		{0, 500 * ms, "Hi"},
+		{500 * ms, 1500 * ms, "there."},
+		{1200 * ms, 1200 * ms, "here"},
+		{1500 * ms, 3000 * ms, "Bye."},
+		{1500 * ms, 3000 * ms, "Bye."},
+	}
+	got := Labels(text, r, WordLabels|SentenceLabels|MarkLabels)
+	if len(got) != len(want) {
+		t.Fatalf("expected %d labels got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("label %d: expected %+v got %+v", i, want[i], got[i])
+		}
+	}
+	if got := Labels(text, r, MarkLabels); len(got) != 1 || got[0].Text != "here" {
+		t.Errorf("expected the mark alone got %+v", got)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteAudacityLabels(&buf, want[3:5]); err != nil {
+		t.Fatal(err)
+	}
+	wantTxt := "1.200000\t1.200000\there\n1.500000\t3.000000\tBye.\n"
+	if buf.String() != wantTxt {
+		t.Errorf("expected %q got %q", wantTxt, buf.String())
+	}
+
+	markers := Markers(want[2:4], 8000)
+	wantMarkers := []wav.Marker{{Position: 4000, Length: 8000, Label: "there."}, {Position: 9600, Label: "here"}}
+	for i := range wantMarkers {
+		if markers[i] != wantMarkers[i] {
+			t.Errorf("marker %d: expected %+v got %+v", i, wantMarkers[i], markers[i])
+		}
+	}
+}
+
+func TestSynthesizer_TextToSpeechLabels(t *testing.T) {
+	s, err := NewSynthesizer(Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	dir, err := ioutil.TempDir("", "go-espeak-labels-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	params := NewParameters(WithDir(dir))
+
+	text := `<speak>Hello <mark name="m1"/>world. Good bye.</speak>`
+	opts := &LabelOptions{Kinds: WordLabels | MarkLabels, Cues: true, SSML: true}
+	labels, err := s.TextToSpeechLabels(context.Background(), text, nil, "labeled", params, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(labels) != 5 {
+		t.Fatalf("expected 4 words and a mark got %+v", labels)
+	}
+
+	txt, err := ioutil.ReadFile(filepath.Join(dir, "labeled.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(txt)), "\n"); len(lines) != len(labels) {
+		t.Errorf("expected %d labels got %q", len(labels), txt)
+	}
+	fh, err := os.Open(filepath.Join(dir, "labeled.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+	r, err := wav.NewReader(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReadSamples(); err != nil {
+		t.Fatal(err)
+	}
+	want := Markers(labels, r.SampleRate())
+	got := r.Markers()
+	if len(got) != len(want) {
+		t.Fatalf("expected %d markers got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("marker %d: expected %+v got %+v", i, want[i], got[i])
+		}
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package langid
+
+// corpus sample text of each language written in an alphabet shared with
+// others, the profiles are trained on.
+var corpus = map[string]string{
+	"en": `The weather was cold this morning, so we stayed at home and read the
+newspaper. There is a small shop at the end of the street where they sell
+fresh bread every day. My brother works in a hospital and he often comes back
+late at night. What do you think about the new plan? I would like to know
+which train leaves first, because we have to be there before noon. They said
+that the children were playing in the garden while their parents talked about
+the holidays. Although it was raining, everyone enjoyed the walk through the
+old town. Could you please tell me how much this costs? We should have
+thought about it earlier, but nobody knew what would happen. The company has
+announced that the results of the year were better than expected. She always
+writes her letters by hand and sends them with beautiful stamps.
+
+I like my dog, and my dog likes me. Do you have a cat? Yes, I do, but she is
+very old now. Hello! Good morning, how are you? I am fine, thank you, and you?
+Not bad. Okay, see you later. Where is the station? It is next to the bank, on
+the left. I think it is going to rain tomorrow. Can I have a cup of tea,
+please? Of course, it will be ready in a minute. My friends and I went to the
+cinema last night and the film was great. What time is it? It is half past
+seven. I don't know why he left so early. We need milk, eggs and some apples
+from the market. Have a nice day! Thanks, you too.`,
+
+	"es": `El tiempo estaba frío esta mañana, así que nos quedamos en casa leyendo
+el periódico. Hay una pequeña tienda al final de la calle donde venden pan
+fresco todos los días. Mi hermano trabaja en un hospital y a menudo vuelve
+tarde por la noche. ¿Qué piensas del nuevo plan? Me gustaría saber qué tren
+sale primero, porque tenemos que estar allí antes del mediodía. Dijeron que
+los niños jugaban en el jardín mientras sus padres hablaban de las
+vacaciones. Aunque llovía, todos disfrutaron del paseo por el casco antiguo.
+¿Podrías decirme cuánto cuesta esto? Deberíamos haberlo pensado antes, pero
+nadie sabía lo que iba a pasar. La empresa ha anunciado que los resultados
+del año fueron mejores de lo esperado. Ella siempre escribe sus cartas a mano
+y las envía con sellos muy bonitos. ¡Hola! ¿Cómo estás? Muy bien, gracias,
+¿y tú? Estoy cansado, pero contento de verte.
+
+Me gusta mi perro, y a mi perro le gusto yo. ¿Tienes un gato? Sí, pero ya es
+muy viejo. ¡Buenos días! ¿Qué tal? Bien, gracias. Vale, hasta luego. ¿Dónde
+está la estación? Está al lado del banco, a la izquierda. Creo que mañana va a
+llover. ¿Me pone un café, por favor? Claro, enseguida. Anoche fui al cine con
+mis amigos y la película fue genial. ¿Qué hora es? Son las siete y media. No
+sé por qué se fue tan temprano. Necesitamos leche, huevos y unas manzanas del
+mercado. ¡Que tengas un buen día! Gracias, igualmente.`,
+
+	"fr": `Il faisait froid ce matin, alors nous sommes restés à la maison pour
+lire le journal. Il y a une petite boutique au bout de la rue où l'on vend du
+pain frais tous les jours. Mon frère travaille dans un hôpital et il rentre
+souvent tard le soir. Que penses-tu du nouveau projet ? J'aimerais savoir
+quel train part en premier, parce que nous devons être là-bas avant midi. Ils
+ont dit que les enfants jouaient dans le jardin pendant que leurs parents
+parlaient des vacances. Bien qu'il pleuvait, tout le monde a apprécié la
+promenade dans la vieille ville. Pourriez-vous me dire combien cela coûte ?
+Nous aurions dû y penser plus tôt, mais personne ne savait ce qui allait se
+passer. L'entreprise a annoncé que les résultats de l'année étaient meilleurs
+que prévu. Elle écrit toujours ses lettres à la main et les envoie avec de
+beaux timbres.
+
+J'aime mon chien, et mon chien m'aime aussi. Tu as un chat ? Oui, mais il est
+très vieux maintenant. Salut ! Ça va ? Ça va bien, merci, et toi ? D'accord, à
+plus tard. Où est la gare ? Elle est à côté de la banque, sur la gauche. Je
+pense qu'il va pleuvoir demain. Je voudrais un café, s'il vous plaît. Bien
+sûr, tout de suite. Hier soir, je suis allé au cinéma avec mes amis et le film
+était génial. Quelle heure est-il ? Il est sept heures et demie. Je ne sais
+pas pourquoi il est parti si tôt. Il nous faut du lait, des œufs et quelques
+pommes du marché. Bonne journée ! Merci, vous aussi.`,
+
+	"de": `Heute Morgen war es kalt, deshalb sind wir zu Hause geblieben und haben
+die Zeitung gelesen. Am Ende der Straße gibt es einen kleinen Laden, in dem
+jeden Tag frisches Brot verkauft wird. Mein Bruder arbeitet in einem
+Krankenhaus und kommt oft spät in der Nacht nach Hause. Was hältst du von dem
+neuen Plan? Ich möchte wissen, welcher Zug zuerst fährt, weil wir vor Mittag
+dort sein müssen. Sie sagten, dass die Kinder im Garten spielten, während
+ihre Eltern über die Ferien sprachen. Obwohl es regnete, genossen alle den
+Spaziergang durch die Altstadt. Könnten Sie mir bitte sagen, wie viel das
+kostet? Wir hätten früher darüber nachdenken sollen, aber niemand wusste, was
+passieren würde. Das Unternehmen hat bekannt gegeben, dass die Ergebnisse des
+Jahres besser als erwartet waren. Sie schreibt ihre Briefe immer mit der Hand
+und schickt sie mit schönen Briefmarken.
+
+Ich mag meinen Hund, und mein Hund mag mich. Hast du eine Katze? Ja, aber sie
+ist jetzt sehr alt. Hallo! Guten Morgen, wie geht's? Mir geht es gut, danke,
+und dir? Okay, bis später. Wo ist der Bahnhof? Er ist neben der Bank, auf der
+linken Seite. Ich glaube, morgen wird es regnen. Kann ich bitte einen Kaffee
+haben? Natürlich, sofort. Gestern Abend war ich mit meinen Freunden im Kino,
+und der Film war toll. Wie spät ist es? Es ist halb acht. Ich weiß nicht,
+warum er so früh gegangen ist. Wir brauchen Milch, Eier und ein paar Äpfel vom
+Markt. Schönen Tag noch! Danke, gleichfalls.`,
+
+	"it": `Stamattina faceva freddo, così siamo rimasti a casa a leggere il
+giornale. C'è un piccolo negozio in fondo alla strada dove vendono pane
+fresco ogni giorno. Mio fratello lavora in un ospedale e spesso torna tardi
+la sera. Che cosa pensi del nuovo progetto? Vorrei sapere quale treno parte
+per primo, perché dobbiamo essere lì prima di mezzogiorno. Hanno detto che i
+bambini giocavano nel giardino mentre i loro genitori parlavano delle
+vacanze. Anche se pioveva, tutti si sono goduti la passeggiata nel centro
+storico. Potrebbe dirmi quanto costa questo? Avremmo dovuto pensarci prima,
+ma nessuno sapeva che cosa sarebbe successo. L'azienda ha annunciato che i
+risultati dell'anno sono stati migliori del previsto. Lei scrive sempre le
+sue lettere a mano e le spedisce con dei francobolli bellissimi. Ciao! Come stai? Sto bene,
+grazie, e tu? Sono stanco, ma sono contento di vederti.
+
+Mi piace il mio cane, e al mio cane piaccio io. Hai un gatto? Sì, ma adesso è
+molto vecchio. Buongiorno! Come va? Bene, grazie. Va bene, a dopo. Dov'è la
+stazione? È accanto alla banca, sulla sinistra. Penso che domani pioverà.
+Posso avere un caffè, per favore? Certo, subito. Ieri sera sono andato al
+cinema con i miei amici e il film era bellissimo. Che ore sono? Sono le sette
+e mezza. Non so perché sia andato via così presto. Ci servono latte, uova e
+qualche mela dal mercato. Buona giornata! Grazie, altrettanto.`,
+
+	"pt": `Estava frio esta manhã, por isso ficamos em casa a ler o jornal. Há uma
+pequena loja no fim da rua onde vendem pão fresco todos os dias. O meu irmão
+trabalha num hospital e muitas vezes volta tarde à noite. O que você acha do
+novo plano? Gostaria de saber qual comboio sai primeiro, porque temos de
+estar lá antes do meio-dia. Disseram que as crianças brincavam no jardim
+enquanto os pais falavam sobre as férias. Embora estivesse a chover, todos
+gostaram do passeio pela cidade velha. Pode dizer-me quanto custa isto?
+Devíamos ter pensado nisso mais cedo, mas ninguém sabia o que ia acontecer. A
+empresa anunciou que os resultados do ano foram melhores do que o esperado.
+Ela escreve sempre as suas cartas à mão e envia-as com selos muito bonitos.
+Não há nada melhor do que um café com os amigos depois do trabalho. Olá!
+Como vai? Estou bem, obrigado, e você? Estou cansado, mas feliz em te ver.
+
+Eu gosto do meu cão, e o meu cão gosta de mim. Tens um gato? Sim, mas agora já
+está muito velho. Bom dia! Tudo bem? Tudo ótimo, obrigada. Está bem, até logo.
+Onde fica a estação? Fica ao lado do banco, à esquerda. Acho que amanhã vai
+chover. Posso tomar um café, por favor? Claro, é para já. Ontem à noite fui ao
+cinema com os meus amigos e o filme foi ótimo. Que horas são? São sete e meia.
+Não sei porque é que ele saiu tão cedo. Precisamos de leite, ovos e algumas
+maçãs do mercado. Tenha um bom dia! Obrigado, igualmente.`,
+
+	"nl": `Het was koud vanochtend, dus we zijn thuisgebleven en hebben de krant
+gelezen. Aan het einde van de straat is een kleine winkel waar ze elke dag
+vers brood verkopen. Mijn broer werkt in een ziekenhuis en komt vaak laat in
+de avond thuis. Wat vind jij van het nieuwe plan? Ik zou graag willen weten
+welke trein het eerst vertrekt, omdat we er voor de middag moeten zijn. Ze
+zeiden dat de kinderen in de tuin speelden terwijl hun ouders over de
+vakantie praatten. Hoewel het regende, genoot iedereen van de wandeling door
+de oude stad. Kunt u mij vertellen hoeveel dit kost? We hadden er eerder aan
+moeten denken, maar niemand wist wat er zou gebeuren. Het bedrijf heeft
+bekendgemaakt dat de resultaten van het jaar beter waren dan verwacht. Zij
+schrijft haar brieven altijd met de hand en stuurt ze met mooie postzegels.
+
+Ik hou van mijn hond, en mijn hond houdt van mij. Heb jij een kat? Ja, maar ze
+is nu heel oud. Hallo! Goedemorgen, hoe gaat het? Goed, dank je, en met jou?
+Oké, tot straks. Waar is het station? Het is naast de bank, aan de linkerkant.
+Ik denk dat het morgen gaat regenen. Mag ik een kopje koffie, alstublieft?
+Natuurlijk, het komt eraan. Gisteravond ben ik met mijn vrienden naar de
+bioscoop geweest en de film was geweldig. Hoe laat is het? Het is half acht.
+Ik weet niet waarom hij zo vroeg is vertrokken. We hebben melk, eieren en een
+paar appels van de markt nodig. Fijne dag! Dank je, jij ook.`,
+
+	"sv": `Det var kallt i morse, så vi stannade hemma och läste tidningen. Det
+finns en liten affär i slutet av gatan där de säljer färskt bröd varje dag.
+Min bror arbetar på ett sjukhus och kommer ofta hem sent på kvällen. Vad
+tycker du om den nya planen? Jag skulle vilja veta vilket tåg som går först,
+eftersom vi måste vara där före lunch. De sa att barnen lekte i trädgården
+medan deras föräldrar pratade om semestern. Även om det regnade njöt alla av
+promenaden genom den gamla staden. Kan du säga mig hur mycket det här kostar?
+Vi borde ha tänkt på det tidigare, men ingen visste vad som skulle hända.
+Företaget har meddelat att årets resultat blev bättre än väntat. Hon skriver
+alltid sina brev för hand och skickar dem med vackra frimärken. Hej! Hur
+mår du? Jag mår bra, tack, och du?
+
+Jag tycker om min hund, och min hund tycker om mig. Har du en katt? Ja, men
+hon är väldigt gammal nu. God morgon! Hur står det till? Bra, tack. Okej, vi
+ses senare. Var ligger stationen? Den ligger bredvid banken, till vänster. Jag
+tror att det kommer att regna i morgon. Kan jag få en kopp kaffe, tack?
+Självklart, på en gång. I går kväll gick jag på bio med mina vänner och filmen
+var jättebra. Vad är klockan? Hon är halv åtta. Jag vet inte varför han gick
+så tidigt. Vi behöver mjölk, ägg och några äpplen från torget. Ha en trevlig
+dag! Tack, detsamma.`,
+
+	"pl": `Dziś rano było zimno, więc zostaliśmy w domu i czytaliśmy gazetę. Na
+końcu ulicy jest mały sklep, w którym codziennie sprzedają świeży chleb. Mój
+brat pracuje w szpitalu i często wraca późno w nocy. Co myślisz o nowym
+planie? Chciałbym wiedzieć, który pociąg odjeżdża pierwszy, ponieważ musimy
+tam być przed południem. Powiedzieli, że dzieci bawiły się w ogrodzie,
+podczas gdy ich rodzice rozmawiali o wakacjach. Chociaż padał deszcz,
+wszystkim podobał się spacer po starym mieście. Czy może mi pan powiedzieć,
+ile to kosztuje? Powinniśmy byli pomyśleć o tym wcześniej, ale nikt nie
+wiedział, co się stanie. Firma ogłosiła, że wyniki roku były lepsze niż
+oczekiwano. Ona zawsze pisze listy ręcznie i wysyła je z pięknymi znaczkami.
+
+Lubię mojego psa, a mój pies lubi mnie. Masz kota? Tak, ale jest już bardzo
+stary. Cześć! Dzień dobry, co słychać? Wszystko dobrze, dziękuję, a u ciebie?
+Dobrze, do zobaczenia później. Gdzie jest dworzec? Jest obok banku, po lewej
+stronie. Myślę, że jutro będzie padać. Czy mogę prosić o kawę? Oczywiście, już
+podaję. Wczoraj wieczorem poszedłem z przyjaciółmi do kina i film był świetny.
+Która jest godzina? Jest wpół do ósmej. Nie wiem, dlaczego wyszedł tak
+wcześnie. Potrzebujemy mleka, jajek i kilku jabłek z targu. Miłego dnia!
+Dziękuję, nawzajem.`,
+
+	"ru": `Сегодня утром было холодно, поэтому мы остались дома и читали газету.
+В конце улицы есть маленький магазин, где каждый день продают свежий хлеб.
+Мой брат работает в больнице и часто возвращается поздно вечером. Что ты
+думаешь о новом плане? Я хотел бы знать, какой поезд отправляется первым,
+потому что мы должны быть там до полудня. Они сказали, что дети играли в
+саду, пока их родители говорили об отпуске. Хотя шёл дождь, всем
+понравилась прогулка по старому городу. Не могли бы вы сказать, сколько это
+стоит? Нам следовало подумать об этом раньше, но никто не знал, что
+случится. Компания объявила, что результаты года оказались лучше, чем
+ожидалось. Она всегда пишет письма от руки и отправляет их с красивыми
+марками.
+
+Я люблю свою собаку, а моя собака любит меня. У тебя есть кошка? Да, но она
+уже очень старая. Привет! Доброе утро, как дела? Хорошо, спасибо, а у тебя?
+Ладно, до встречи. Где вокзал? Он рядом с банком, слева. Думаю, завтра будет
+дождь. Можно мне чашку кофе, пожалуйста? Конечно, сейчас принесу. Вчера
+вечером я ходил в кино с друзьями, и фильм был отличный. Который час? Половина
+восьмого. Не знаю, почему он ушёл так рано. Нам нужны молоко, яйца и несколько
+яблок с рынка. Хорошего дня! Спасибо, и вам того же.`,
+
+	"uk": `Сьогодні вранці було холодно, тому ми залишилися вдома і читали газету.
+Наприкінці вулиці є маленька крамниця, де щодня продають свіжий хліб. Мій
+брат працює в лікарні і часто повертається пізно ввечері. Що ти думаєш про
+новий план? Я хотів би знати, який потяг вирушає першим, бо ми маємо бути
+там до полудня. Вони сказали, що діти гралися в саду, поки їхні батьки
+говорили про відпустку. Хоча йшов дощ, усім сподобалася прогулянка старим
+містом. Чи не могли б ви сказати, скільки це коштує? Нам слід було подумати
+про це раніше, але ніхто не знав, що станеться. Компанія оголосила, що
+результати року виявилися кращими, ніж очікувалося. Вона завжди пише листи
+від руки і надсилає їх з гарними марками.
+
+Я люблю свого пса, а мій пес любить мене. У тебе є кішка? Так, але вона вже
+дуже стара. Привіт! Доброго ранку, як справи? Добре, дякую, а в тебе? Гаразд,
+до зустрічі. Де вокзал? Він біля банку, ліворуч. Думаю, завтра буде дощ. Можна
+мені чашку кави, будь ласка? Звичайно, зараз принесу. Учора ввечері я ходив у
+кіно з друзями, і фільм був чудовий. Котра година? Пів на восьму. Не знаю,
+чому він пішов так рано. Нам потрібні молоко, яйця і кілька яблук з ринку.
+Гарного дня! Дякую, і вам також.`,
+}
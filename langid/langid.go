@@ -0,0 +1,330 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+// Package langid identifies the language of a text, offline. Languages with
+// a script of their own, like Greek or Korean, are told by their script;
+// those sharing an alphabet, like English and Spanish, by how well the
+// text's letter n-grams fit a profile of each, trained on sample text.
+//
+// Profiles for en, es, fr, de, it, pt, nl, sv, pl, ru and uk are included,
+// others can be added with Train.
+package langid
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Guess a language the text may be in.
+type Guess struct {
+	// Language code, e.g. "en".
+	Language string `json:"language"`
+	// Confidence that the text is in Language, from 0 to 1. A few words are
+	// seldom told with much confidence, a sentence or two usually are.
+	Confidence float64 `json:"confidence"`
+}
+
+// scripts written in a single language, as far as telling them apart
+// goes. Han is only taken for Chinese where there's no kana.
+var scripts = []struct {
+	table    *unicode.RangeTable
+	language string
+}{
+	{unicode.Hiragana, "ja"},
+	{unicode.Katakana, "ja"},
+	{unicode.Han, "zh"},
+	{unicode.Hangul, "ko"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Arabic, "ar"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Thai, "th"},
+	{unicode.Armenian, "hy"},
+	{unicode.Georgian, "ka"},
+}
+
+// alphabets shared by several languages, told apart by their profiles.
+var alphabets = []*unicode.RangeTable{unicode.Latin, unicode.Cyrillic}
+
+// maxNgram the longest n-gram of a profile.
+const maxNgram = 3
+
+// profile the n-gram counts of a language.
+type profile struct {
+	language string
+	alphabet *unicode.RangeTable
+	counts   map[string]int
+	total    int
+}
+
+var (
+	profilesMu sync.RWMutex
+	profiles   = make(map[string]*profile)
+	// vocabulary the n-grams of every profile.
+	vocabulary = make(map[string]bool)
+)
+
+func init() {
+	for lang, text := range corpus {
+		Train(lang, text)
+	}
+}
+
+// Train adds a profile of language, e.g. "ca", built from sample, replacing
+// any other of the language. The sample should be a few paragraphs of
+// everyday text in a Latin or Cyrillic alphabet.
+func Train(language, sample string) {
+	p := &profile{language: strings.ToLower(language), counts: make(map[string]int)}
+	letters := make(map[*unicode.RangeTable]int)
+	for _, r := range sample {
+		for _, a := range alphabets {
+			if unicode.Is(a, r) {
+				letters[a]++
+			}
+		}
+	}
+	for _, a := range alphabets {
+		if p.alphabet == nil || letters[a] > letters[p.alphabet] {
+			p.alphabet = a
+		}
+	}
+	ngrams(sample, func(g string) {
+		p.counts[g]++
+		p.total++
+	})
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	_, replaced := profiles[p.language]
+	profiles[p.language] = p
+	if replaced {
+		// the n-grams of the replaced profile go, unless others have them.
+		buildVocabulary()
+		return
+	}
+	for g := range p.counts {
+		vocabulary[g] = true
+	}
+}
+
+// buildVocabulary rebuilds the vocabulary out of the profiles. profilesMu
+// must be held.
+func buildVocabulary() {
+	vocabulary = make(map[string]bool)
+	for _, p := range profiles {
+		for g := range p.counts {
+			vocabulary[g] = true
+		}
+	}
+}
+
+// Languages returns the languages that can be identified, sorted.
+func Languages() []string {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	seen := make(map[string]bool)
+	var langs []string
+	for l := range profiles {
+		seen[l] = true
+		langs = append(langs, l)
+	}
+	for _, s := range scripts {
+		if !seen[s.language] {
+			seen[s.language] = true
+			langs = append(langs, s.language)
+		}
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// ngrams calls f with the 1 to maxNgram letter n-grams of the words of
+// text, lower cased, padded with a space on each side.
+func ngrams(text string, f func(string)) {
+	word := make([]rune, 0, 32)
+	flush := func() {
+		if len(word) == 0 {
+			return
+		}
+		padded := append(append([]rune{' '}, word...), ' ')
+		for n := 1; n <= maxNgram; n++ {
+			for i := 0; i+n <= len(padded); i++ {
+				if n == 1 && padded[i] == ' ' {
+					continue
+				}
+				f(string(padded[i : i+n]))
+			}
+		}
+		word = word[:0]
+	}
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.Is(unicode.Mn, r) {
+			word = append(word, unicode.ToLower(r))
+			continue
+		}
+		flush()
+	}
+	flush()
+}
+
+// Identifier identifies the language of texts, among a set of languages.
+// It is safe for concurrent use.
+type Identifier struct {
+	languages map[string]bool
+}
+
+// New returns an *Identifier of languages, e.g. "en", "es", or any
+// language if none are given. Regional variants, e.g. "en-us", are
+// identified as their language.
+func New(languages ...string) *Identifier {
+	id := &Identifier{}
+	if len(languages) > 0 {
+		id.languages = make(map[string]bool)
+		for _, l := range languages {
+			id.languages[primary(l)] = true
+		}
+	}
+	return id
+}
+
+// primary returns the primary language of code, e.g. "en" for "en-US".
+func primary(code string) string {
+	code = strings.ToLower(code)
+	if i := strings.IndexAny(code, "-_"); i > 0 {
+		code = code[:i]
+	}
+	return code
+}
+
+func (id *Identifier) allowed(language string) bool {
+	return id.languages == nil || id.languages[language]
+}
+
+// maxLetters read from a text, enough to tell its language.
+const maxLetters = 2000
+
+// Detect returns the languages text may be in, most likely first, along
+// with their confidence, which add up to 1. Returns none if text has no
+// letters, or none in the languages of id.
+func (id *Identifier) Detect(text string) []Guess {
+	counts := make(map[string]int)
+	alphabetCounts := make(map[*unicode.RangeTable]int)
+	letters := 0
+	var sample strings.Builder
+	for _, r := range text {
+		if letters >= maxLetters {
+			break
+		}
+		if !unicode.IsLetter(r) {
+			sample.WriteRune(r)
+			continue
+		}
+		letters++
+		sample.WriteRune(r)
+		for _, a := range alphabets {
+			if unicode.Is(a, r) {
+				alphabetCounts[a]++
+			}
+		}
+		for _, s := range scripts {
+			if unicode.Is(s.table, r) {
+				counts[s.language]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return nil
+	}
+	// Chinese characters are part of Japanese writing.
+	if counts["ja"] > 0 {
+		counts["ja"] += counts["zh"]
+		delete(counts, "zh")
+	}
+
+	var guesses []Guess
+	for lang, n := range counts {
+		if id.allowed(lang) {
+			guesses = append(guesses, Guess{lang, float64(n) / float64(letters)})
+		}
+	}
+	for _, a := range alphabets {
+		if n := alphabetCounts[a]; n > 0 {
+			guesses = append(guesses, id.score(sample.String(), a, float64(n)/float64(letters))...)
+		}
+	}
+	if len(guesses) == 0 {
+		return nil
+	}
+	// normalized over the allowed languages.
+	total := 0.0
+	for _, g := range guesses {
+		total += g.Confidence
+	}
+	for i := range guesses {
+		guesses[i].Confidence /= total
+	}
+	sort.SliceStable(guesses, func(i, j int) bool {
+		if guesses[i].Confidence != guesses[j].Confidence {
+			return guesses[i].Confidence > guesses[j].Confidence
+		}
+		return guesses[i].Language < guesses[j].Language
+	})
+	return guesses
+}
+
+// score returns the guesses of the languages of alphabet a, sharing weight,
+// the share of the text's letters in a, by how likely the n-grams of text
+// are in each.
+func (id *Identifier) score(text string, a *unicode.RangeTable, weight float64) []Guess {
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	var candidates []*profile
+	for _, p := range profiles {
+		if p.alphabet == a && id.allowed(p.language) {
+			candidates = append(candidates, p)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	// naive Bayes, with additive smoothing.
+	const alpha = 0.5
+	v := float64(len(vocabulary))
+	logp := make([]float64, len(candidates))
+	n := 0
+	ngrams(text, func(g string) {
+		n++
+		for i, p := range candidates {
+			logp[i] += math.Log((float64(p.counts[g]) + alpha) / (float64(p.total) + alpha*v))
+		}
+	})
+	// the n-grams of a word aren't independent, taken as such the
+	// posteriors are all but certain of a few words. Their margins are
+	// scaled down to grow with the square root of the n-grams instead.
+	scale := 1 / math.Sqrt(float64(n))
+	max := math.Inf(-1)
+	for _, l := range logp {
+		max = math.Max(max, l)
+	}
+	sum := 0.0
+	for i := range logp {
+		logp[i] = math.Exp((logp[i] - max) * scale)
+		sum += logp[i]
+	}
+	guesses := make([]Guess, len(candidates))
+	for i, p := range candidates {
+		guesses[i] = Guess{p.language, weight * logp[i] / sum}
+	}
+	return guesses
+}
+
+// Detect returns the languages text may be in, most likely first. See
+// Identifier.Detect.
+func Detect(text string) []Guess {
+	return defaultIdentifier.Detect(text)
+}
+
+var defaultIdentifier = New()
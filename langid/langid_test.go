@@ -0,0 +1,186 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package langid
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	for _, tt := range []struct {
+		text string
+		want string
+	}{
+		{"Hello, how are you today?", "en"},
+		{"Hola, ¿cómo estás?", "es"},
+		{"Bonjour tout le monde", "fr"},
+		{"Guten Tag, wie geht es dir?", "de"},
+		{"Ciao, come stai?", "it"},
+		{"Olá, tudo bem com você?", "pt"},
+		{"Goedemorgen, hoe gaat het?", "nl"},
+		{"Hej, hur mår du?", "sv"},
+		{"Dzień dobry, jak się masz?", "pl"},
+		{"Привет, как дела?", "ru"},
+		{"Привіт, як справи?", "uk"},
+		{"Γεια σου κόσμε", "el"},
+		{"こんにちは世界", "ja"},
+		{"你好世界", "zh"},
+		{"안녕하세요", "ko"},
+	} {
+		t.Run(tt.want, func(t *testing.T) {
+			got := Detect(tt.text)
+			if len(got) == 0 {
+				t.Fatalf("expected %q got none", tt.want)
+			}
+			if got[0].Language != tt.want {
+				t.Errorf("expected %q got %+v", tt.want, got)
+			}
+			sum := 0.0
+			for _, g := range got {
+				sum += g.Confidence
+			}
+			if math.Abs(sum-1) > 1e-9 {
+				t.Errorf("expected confidences adding up to 1 got %v", sum)
+			}
+		})
+	}
+
+	t.Run("no letters", func(t *testing.T) {
+		if got := Detect("1, 2, 3!"); got != nil {
+			t.Errorf("expected none got %+v", got)
+		}
+	})
+}
+
+func TestDetect_short(t *testing.T) {
+	for _, text := range []string{"I like my dog", "I am fine", "good morning", "yes", "see you later"} {
+		t.Run(text, func(t *testing.T) {
+			got := Detect(text)
+			if len(got) == 0 || got[0].Language != "en" {
+				t.Errorf("expected %q got %+v", "en", got)
+			}
+			for _, g := range got {
+				if g.Language == "pl" && g.Confidence > 0.2 {
+					t.Errorf("expected pl under 0.2 got %v", g.Confidence)
+				}
+			}
+		})
+	}
+
+	// confidence grows with the text.
+	for _, tt := range []struct {
+		text     string
+		min, max float64
+	}{
+		{"ok", 0, 0.6},
+		{"no", 0, 0.6},
+		{"I like my dog", 0.6, 0.95},
+		{"Hello, how are you today? I hope you had a good weekend.", 0.99, 1},
+	} {
+		t.Run(tt.text, func(t *testing.T) {
+			got := Detect(tt.text)
+			if len(got) == 0 || got[0].Confidence < tt.min || got[0].Confidence > tt.max {
+				t.Errorf("expected confidence %v to %v got %+v", tt.min, tt.max, got)
+			}
+		})
+	}
+}
+
+func TestIdentifier_Detect(t *testing.T) {
+	id := New("en-US", "fr")
+	got := id.Detect("Hola, ¿cómo estás?")
+	if len(got) != 2 {
+		t.Fatalf("expected en and fr got %+v", got)
+	}
+	for _, g := range got {
+		if g.Language != "en" && g.Language != "fr" {
+			t.Errorf("unexpected %q", g.Language)
+		}
+	}
+	if got := id.Detect("Привет, как дела?"); got != nil {
+		t.Errorf("expected none got %+v", got)
+	}
+	// half Greek, half English.
+	got = New("el", "en").Detect("καλημέρα good morning")
+	if len(got) != 2 || got[0].Confidence < 0.4 || got[1].Confidence < 0.4 {
+		t.Errorf("expected el and en about even got %+v", got)
+	}
+}
+
+func TestTrain(t *testing.T) {
+	defer func() {
+		profilesMu.Lock()
+		delete(profiles, "eo")
+		buildVocabulary()
+		profilesMu.Unlock()
+	}()
+	Train("eo", `Mi ŝatas legi librojn en la ĝardeno. La vetero estas bela hodiaŭ,
+kaj ĉiuj infanoj ludas ekstere. Ĉu vi volas trinki kafon kun mi? Ni iros al la
+urbo morgaŭ por aĉeti novajn ŝuojn.`)
+	if got := New("eo", "es", "en").Detect("Ĉu vi ŝatas la ĝardenon?"); len(got) == 0 || got[0].Language != "eo" {
+		t.Errorf("expected eo got %+v", got)
+	}
+	found := false
+	for _, l := range Languages() {
+		found = found || l == "eo"
+	}
+	if !found {
+		t.Errorf("expected eo in %v", Languages())
+	}
+}
+
+func TestTrain_replace(t *testing.T) {
+	defer func() {
+		profilesMu.Lock()
+		delete(profiles, "xx")
+		buildVocabulary()
+		profilesMu.Unlock()
+	}()
+	before := len(vocabulary)
+	Train("xx", "qqqxj zzzvk")
+	Train("xx", "las casas")
+	profilesMu.RLock()
+	defer profilesMu.RUnlock()
+	// the n-grams of the first profile are gone, the second's were known.
+	if vocabulary["qqq"] || len(vocabulary) != before {
+		t.Errorf("expected %d n-grams got %d", before, len(vocabulary))
+	}
+}
+
+func TestIdentifier_Spans(t *testing.T) {
+	for _, tt := range []struct {
+		text string
+		want []string
+	}{
+		{
+			"I love this new city. Es muy bonita, y la comida es deliciosa. But it's really expensive!",
+			[]string{"en:I love this new city. ", "es:Es muy bonita, y la comida es deliciosa. ", "en:But it's really expensive!"},
+		},
+		{
+			"Hola, good morning everyone!",
+			[]string{"es:Hola, ", "en:good morning everyone!"},
+		},
+		{
+			"1, 2, 3. Hello there, my friend.",
+			[]string{"en:1, 2, 3. Hello there, my friend."},
+		},
+		{"Hola", []string{"es:Hola"}},
+		{"1, 2, 3", nil},
+	} {
+		t.Run(tt.text, func(t *testing.T) {
+			var got []string
+			for _, sp := range New("en", "es").Spans(tt.text) {
+				got = append(got, sp.Language+":"+tt.text[sp.Start:sp.End])
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %q got %q", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected %q got %q", tt.want[i], got[i])
+				}
+			}
+		})
+	}
+}
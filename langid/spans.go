@@ -0,0 +1,152 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package langid
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Span of a text in a single language.
+type Span struct {
+	// Start and End byte offsets of the span in the text.
+	Start, End int
+	Guess
+}
+
+// minSpanLetters a clause needs for its language to be told for sure.
+const minSpanLetters = 8
+
+// Spans splits text into spans in a single language each. Languages are
+// told clause by clause, a clause running up to a comma, semicolon, colon,
+// sentence end or bracket, so a language switch within a clause goes
+// unnoticed. Clauses too short to tell for sure, like a single word, join
+// the one after if their best guess is its language, the one before
+// otherwise, and are told on their own at either end of the text. Spans
+// cover the whole text, in order. Returns none if no language of id is
+// found in it.
+func (id *Identifier) Spans(text string) []Span {
+	var (
+		spans []Span
+		// pending clauses too short to tell, with their best guess.
+		pending []Span
+	)
+	add := func(sp Span) {
+		n := len(spans)
+		if n > 0 {
+			sp.Start = spans[n-1].End
+		}
+		if n > 0 && spans[n-1].Language == sp.Language {
+			last := &spans[n-1]
+			// weighed by length.
+			a, b := float64(last.End-last.Start), float64(sp.End-sp.Start)
+			last.Confidence = (last.Confidence*a + sp.Confidence*b) / (a + b)
+			last.End = sp.End
+			return
+		}
+		spans = append(spans, sp)
+	}
+	for _, c := range clauses(text) {
+		unit := text[c[0]:c[1]]
+		sp := Span{Start: c[0], End: c[1]}
+		if guesses := id.Detect(unit); len(guesses) > 0 {
+			sp.Guess = guesses[0]
+		}
+		if sp.Language == "" || letters(unit) < minSpanLetters {
+			pending = append(pending, sp)
+			continue
+		}
+		// the trailing pending clauses guessed in this one's language
+		// start it, spans being contiguous.
+		i := len(pending)
+		for i > 0 && pending[i-1].Language == sp.Language {
+			i--
+		}
+		if n := len(spans); n > 0 && spans[n-1].Language == sp.Language {
+			i = 0
+		}
+		if i > 0 {
+			if len(spans) == 0 {
+				// nothing before to join, they're told on their own.
+				for _, p := range pending[:i] {
+					if p.Language != "" {
+						add(p)
+					}
+				}
+			} else {
+				spans[len(spans)-1].End = pending[i-1].End
+			}
+		}
+		pending = pending[:0]
+		add(sp)
+	}
+	if len(spans) == 0 {
+		// too short to split, told as a whole.
+		if guesses := id.Detect(text); len(guesses) > 0 {
+			return []Span{{Start: 0, End: len(text), Guess: guesses[0]}}
+		}
+		return nil
+	}
+	spans[0].Start = 0
+	// the ones after the last told are told on their own.
+	for _, p := range pending {
+		if p.Language == "" {
+			spans[len(spans)-1].End = p.End
+			continue
+		}
+		add(p)
+	}
+	return spans
+}
+
+// Spans splits text into spans in a single language each. See
+// Identifier.Spans.
+func Spans(text string) []Span {
+	return defaultIdentifier.Spans(text)
+}
+
+// clauses returns the byte offsets of the clauses of text, each holding
+// the punctuation and spaces that follow it.
+func clauses(text string) [][2]int {
+	var out [][2]int
+	start := 0
+	closing := false
+	for i, r := range text {
+		switch {
+		case strings.ContainsRune("¿¡([{«“", r):
+			if i > start && letters(text[start:i]) > 0 {
+				out = append(out, [2]int{start, i})
+				start = i
+			}
+			closing = false
+		case strings.ContainsRune(",;:.!?…)]}»”", r):
+			closing = true
+		case unicode.IsSpace(r):
+		default:
+			if closing {
+				out = append(out, [2]int{start, i})
+				start = i
+			}
+			closing = false
+		}
+	}
+	if start < len(text) {
+		out = append(out, [2]int{start, len(text)})
+	}
+	return out
+}
+
+// letters returns the number of letters in s.
+func letters(s string) int {
+	n := 0
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		if unicode.IsLetter(r) {
+			n++
+		}
+		s = s[size:]
+	}
+	return n
+}
@@ -0,0 +1,202 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package lexicon
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Load reads the lexicon at path, its format told by the extension: .json,
+// .csv, or .pls (also .xml).
+func Load(path string) (*Lexicon, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return ReadJSON(fh)
+	case ".csv":
+		return ReadCSV(fh)
+	case ".pls", ".xml":
+		return ReadPLS(fh)
+	default:
+		return nil, fmt.Errorf("%w: unknown extension %q", ErrFormat, filepath.Ext(path))
+	}
+}
+
+// jsonLexicon the object form of a JSON lexicon, whose language and
+// alphabet are the defaults of its entries.
+type jsonLexicon struct {
+	Language string  `json:"language"`
+	Alphabet string  `json:"alphabet"`
+	Entries  []Entry `json:"entries"`
+}
+
+// ReadJSON reads a lexicon from JSON, either an array of entries, e.g.
+//
+//	[{"word": "GNU", "phonemes": "gnu:"}]
+//
+// or an object, whose language and alphabet apply to the entries lacking
+// one, e.g.
+//
+//	{"language": "en", "alphabet": "ipa", "entries": [{"word": "GNU", "phonemes": "ɡnuː"}]}
+func ReadJSON(r io.Reader) (*Lexicon, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var lex jsonLexicon
+	if b = bytes.TrimSpace(b); len(b) > 0 && b[0] == '[' {
+		err = json.Unmarshal(b, &lex.Entries)
+	} else {
+		err = json.Unmarshal(b, &lex)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	for i := range lex.Entries {
+		e := &lex.Entries[i]
+		if e.Language == "" {
+			e.Language = lex.Language
+		}
+		if e.Alphabet == "" {
+			e.Alphabet = lex.Alphabet
+		}
+	}
+	return New(lex.Entries...)
+}
+
+// ReadCSV reads a lexicon from CSV, whose first record names the columns,
+// after the JSON fields of Entry, e.g.
+//
+//	word,phonemes,alphabet,language
+//	GNU,ɡnuː,ipa,en
+//
+// Lines starting with # are ignored.
+func ReadCSV(r io.Reader) (*Lexicon, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	if len(records) == 0 {
+		return New()
+	}
+	header := records[0]
+	for _, col := range header {
+		switch col {
+		case "word", "regexp", "replacement", "phonemes", "alphabet", "language", "case_sensitive":
+		default:
+			return nil, fmt.Errorf("%w: unknown column %q", ErrFormat, col)
+		}
+	}
+	entries := make([]Entry, 0, len(records)-1)
+	for n, rec := range records[1:] {
+		var e Entry
+		for i, v := range rec {
+			switch header[i] {
+			case "word":
+				e.Word = v
+			case "regexp":
+				e.Regexp = v
+			case "replacement":
+				e.Replacement = v
+			case "phonemes":
+				e.Phonemes = v
+			case "alphabet":
+				e.Alphabet = v
+			case "language":
+				e.Language = v
+			case "case_sensitive":
+				if v == "" {
+					continue
+				}
+				if e.CaseSensitive, err = strconv.ParseBool(v); err != nil {
+					return nil, fmt.Errorf("%w: record %d: case_sensitive %q", ErrFormat, n+2, v)
+				}
+			}
+		}
+		entries = append(entries, e)
+	}
+	return New(entries...)
+}
+
+// plsLexicon the <lexicon> element of a PLS document.
+type plsLexicon struct {
+	Alphabet string      `xml:"alphabet,attr"`
+	Language string      `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Lexemes  []plsLexeme `xml:"lexeme"`
+}
+
+type plsLexeme struct {
+	Graphemes []string `xml:"grapheme"`
+	// Pronunciations <phoneme> and <alias> elements, in document order.
+	Pronunciations []plsPronunciation `xml:",any"`
+}
+
+type plsPronunciation struct {
+	XMLName  xml.Name
+	Alphabet string `xml:"alphabet,attr"`
+	Prefer   string `xml:"prefer,attr"`
+	Text     string `xml:",chardata"`
+}
+
+// ReadPLS reads a lexicon from a W3C Pronunciation Lexicon Specification
+// document. Every grapheme of a lexeme gets the lexeme's preferred
+// pronunciation, the first <phoneme> or <alias> unless one has
+// prefer="true". The lexicon's xml:lang is the language of the entries.
+func ReadPLS(r io.Reader) (*Lexicon, error) {
+	var lex plsLexicon
+	if err := xml.NewDecoder(r).Decode(&lex); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	entries := make([]Entry, 0, len(lex.Lexemes))
+	for _, lx := range lex.Lexemes {
+		var pron *plsPronunciation
+		for i := range lx.Pronunciations {
+			p := &lx.Pronunciations[i]
+			if p.XMLName.Local != "phoneme" && p.XMLName.Local != "alias" {
+				continue
+			}
+			if pron == nil || p.Prefer == "true" {
+				pron = p
+			}
+			if p.Prefer == "true" {
+				break
+			}
+		}
+		if pron == nil {
+			continue
+		}
+		e := Entry{Language: lex.Language}
+		if pron.XMLName.Local == "alias" {
+			e.Replacement = strings.TrimSpace(pron.Text)
+		} else {
+			e.Phonemes = strings.TrimSpace(pron.Text)
+			e.Alphabet = lex.Alphabet
+			if pron.Alphabet != "" {
+				e.Alphabet = pron.Alphabet
+			}
+		}
+		for _, g := range lx.Graphemes {
+			e.Word = strings.TrimSpace(g)
+			entries = append(entries, e)
+		}
+	}
+	return New(entries...)
+}
@@ -0,0 +1,241 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+// Package lexicon rewrites text before it's synthesized, so that words
+// espeak gets wrong, e.g. names, acronyms or jargon, are read as a
+// replacement spelling or pronounced as a given phoneme string.
+//
+// Lexicons can be built from entries, or loaded from JSON, CSV or W3C
+// Pronunciation Lexicon Specification (PLS) files. Set
+// espeak.Parameters.Lexicon to apply one when synthesizing.
+package lexicon
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/djangulo/go-espeak/internal/lang"
+	"github.com/djangulo/go-espeak/phoneme"
+	"github.com/djangulo/go-espeak/textmap"
+)
+
+// Errors
+var (
+	// ErrEntry an entry lacks a word or regexp to match, or a replacement
+	// or phonemes to substitute.
+	ErrEntry = errors.New("lexicon: invalid entry")
+	// ErrFormat the file format is unknown, or the file is malformed.
+	ErrFormat = errors.New("lexicon: invalid format")
+)
+
+// Entry a word, or pattern, and how to read it.
+type Entry struct {
+	// Word matched as a whole word, ignoring case unless CaseSensitive.
+	Word string `json:"word,omitempty"`
+	// Regexp matched instead of Word, in RE2 syntax. Replacement may refer
+	// to its submatches, e.g. $1.
+	Regexp string `json:"regexp,omitempty"`
+	// Replacement spelling read instead of the match.
+	Replacement string `json:"replacement,omitempty"`
+	// Phonemes the match is pronounced as, in Alphabet. Takes precedence
+	// over Replacement.
+	Phonemes string `json:"phonemes,omitempty"`
+	// Alphabet of Phonemes: "espeak" (the default), "ipa" or "x-sampa".
+	// Other than espeak's, they're converted through the phoneme tables of
+	// the language.
+	Alphabet string `json:"alphabet,omitempty"`
+	// Language the entry applies to, e.g. "en" (which covers "en-us") or
+	// "en-us". Empty applies to every language.
+	Language string `json:"language,omitempty"`
+	// CaseSensitive match Word as is.
+	CaseSensitive bool `json:"case_sensitive,omitempty"`
+}
+
+// rule a compiled Entry.
+type rule struct {
+	Entry
+	re       *regexp.Regexp
+	alphabet phoneme.Alphabet
+	// phonemes in espeak's mnemonics, if known before Apply.
+	phonemes string
+}
+
+// Lexicon a list of entries, in order of precedence. It is safe for
+// concurrent use.
+type Lexicon struct {
+	rules []rule
+}
+
+// New returns a *Lexicon of entries. Where matches overlap, the leftmost
+// wins, then the one of the earliest entry.
+func New(entries ...Entry) (*Lexicon, error) {
+	l := &Lexicon{rules: make([]rule, 0, len(entries))}
+	for i, e := range entries {
+		r, err := compile(e)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %w", i+1, err)
+		}
+		l.rules = append(l.rules, r)
+	}
+	return l, nil
+}
+
+func compile(e Entry) (rule, error) {
+	e.Language = lang.Normalize(e.Language)
+	r := rule{Entry: e}
+	var err error
+	switch {
+	case e.Regexp != "":
+		r.re, err = regexp.Compile(e.Regexp)
+		if err != nil {
+			return r, fmt.Errorf("%w: %v", ErrEntry, err)
+		}
+	case e.Word != "":
+		expr := regexp.QuoteMeta(e.Word)
+		if !e.CaseSensitive {
+			expr = "(?i)" + expr
+		}
+		r.re = regexp.MustCompile(expr)
+	default:
+		return r, fmt.Errorf("%w: no word or regexp", ErrEntry)
+	}
+	if e.Phonemes == "" {
+		if e.Replacement == "" {
+			return r, fmt.Errorf("%w: no replacement or phonemes for %q", ErrEntry, e.Word+e.Regexp)
+		}
+		return r, nil
+	}
+	r.alphabet = phoneme.Espeak
+	if e.Alphabet != "" {
+		if r.alphabet, err = phoneme.ParseAlphabet(e.Alphabet); err != nil {
+			return r, err
+		}
+	}
+	if r.alphabet == phoneme.Espeak {
+		r.phonemes = e.Phonemes
+		return r, nil
+	}
+	if e.Language == "" {
+		return r, nil
+	}
+	// a language without a table of its own, e.g. "en", is converted
+	// through that of the text's, e.g. "en-us", on Apply.
+	r.phonemes, err = phoneme.Convert(e.Phonemes, r.alphabet, phoneme.Espeak, e.Language)
+	if errors.Is(err, phoneme.ErrUnknownLanguage) {
+		return r, nil
+	}
+	return r, err
+}
+
+// Entries returns the entries of l.
+func (l *Lexicon) Entries() []Entry {
+	entries := make([]Entry, len(l.rules))
+	for i := range l.rules {
+		entries[i] = l.rules[i].Entry
+	}
+	return entries
+}
+
+// Len returns the number of entries of l.
+func (l *Lexicon) Len() int {
+	return len(l.rules)
+}
+
+// match an occurrence of a rule in the text.
+type match struct {
+	start, end int
+	rule       int
+	submatches []int
+}
+
+// Apply rewrites text for language, replacing the matches of the entries
+// that apply to it. Phonemes are written within [[ ]], in which case
+// phonemes is true, and the text must be synthesized with espeak's Phonemes
+// flag. The returned *textmap.Map maps offsets of out to those of text.
+func (l *Lexicon) Apply(text, language string) (out string, m *textmap.Map, phonemes bool, err error) {
+	language = lang.Normalize(language)
+	var matches []match
+	for i := range l.rules {
+		r := &l.rules[i]
+		if !appliesTo(r.Language, language) {
+			continue
+		}
+		for _, loc := range r.re.FindAllStringSubmatchIndex(text, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			if r.Regexp == "" && !wholeWord(text, loc[0], loc[1]) {
+				continue
+			}
+			matches = append(matches, match{start: loc[0], end: loc[1], rule: i, submatches: loc})
+		}
+	}
+	if len(matches) == 0 {
+		return text, nil, false, nil
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].start != matches[j].start {
+			return matches[i].start < matches[j].start
+		}
+		return matches[i].rule < matches[j].rule
+	})
+
+	var b textmap.Builder
+	pos := 0
+	for _, mt := range matches {
+		if mt.start < pos {
+			continue
+		}
+		r := &l.rules[mt.rule]
+		var repl string
+		if r.Phonemes != "" {
+			ph, err := r.espeakPhonemes(language)
+			if err != nil {
+				return "", nil, false, err
+			}
+			repl = "[[" + ph + "]]"
+			phonemes = true
+		} else {
+			repl = string(r.re.ExpandString(nil, r.Replacement, text, mt.submatches))
+		}
+		b.Keep(text[pos:mt.start])
+		b.Replace(text[mt.start:mt.end], repl)
+		pos = mt.end
+	}
+	b.Keep(text[pos:])
+	return b.String(), b.Map(), phonemes, nil
+}
+
+// espeakPhonemes returns the phonemes of r in espeak's mnemonics,
+// converting them through the table of language if needed.
+func (r *rule) espeakPhonemes(language string) (string, error) {
+	if r.phonemes != "" {
+		return r.phonemes, nil
+	}
+	return phoneme.Convert(r.Phonemes, r.alphabet, phoneme.Espeak, language)
+}
+
+// wholeWord returns whether text[start:end] isn't part of a longer word.
+func wholeWord(text string, start, end int) bool {
+	first, _ := utf8.DecodeRuneInString(text[start:])
+	if before, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && lang.IsWordRune(first) && lang.IsWordRune(before) {
+		return false
+	}
+	last, _ := utf8.DecodeLastRuneInString(text[:end])
+	if after, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && lang.IsWordRune(last) && lang.IsWordRune(after) {
+		return false
+	}
+	return true
+}
+
+// appliesTo returns whether an entry of language entryLang applies to text
+// in language, either being the same, or entryLang its primary language.
+func appliesTo(entryLang, language string) bool {
+	return entryLang == "" ||
+		entryLang == language ||
+		strings.HasPrefix(language, entryLang+"-")
+}
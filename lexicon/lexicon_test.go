@@ -0,0 +1,198 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package lexicon
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/djangulo/go-espeak/phoneme"
+)
+
+func TestLexicon_Apply(t *testing.T) {
+	lex, err := New(
+		Entry{Word: "GNU", Phonemes: "gn'u:"},
+		Entry{Word: "SQL", Replacement: "sequel", CaseSensitive: true},
+		Entry{Word: "niño", Replacement: "ninio", Language: "es"},
+		Entry{Regexp: `(\d+)km`, Replacement: "$1 kilometers", Language: "en"},
+		Entry{Word: "tomato", Phonemes: "təmˈɑːtoʊ", Alphabet: "ipa", Language: "en-us"},
+		Entry{Word: "Linux", Phonemes: "lˈɪnʊks", Alphabet: "ipa"},
+		// never matches, GNU comes first.
+		Entry{Word: "gnu", Replacement: "new"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name, text, language, want string
+		phonemes                   bool
+	}{
+		{"phonemes", "I use GNU.", "en-us", "I use [[gn'u:]].", true},
+		{"ignores case", "gnu and Gnu", "en-us", "[[gn'u:]] and [[gn'u:]]", true},
+		{"whole words", "GNUs and gnus", "en-us", "GNUs and gnus", false},
+		{"case sensitive", "SQL, not sql", "en-us", "sequel, not sql", false},
+		{"language", "el niño", "es", "el ninio", false},
+		{"other language", "el niño", "en-us", "el niño", false},
+		{"primary language", "10km", "en-gb", "10 kilometers", false},
+		{"regexp", "run 10km, then 5km", "en-us", "run 10 kilometers, then 5 kilometers", false},
+		{"ipa", "a tomato", "en-us", "a [[t@m'A:toU]]", true},
+		{"ipa for the text's language", "Linux", "en_US", "[[l'InUks]]", true},
+		{"unicode boundaries", "señor niño!", "es", "señor ninio!", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, phonemes, err := lex.Apply(tt.text, tt.language)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q got %q", tt.want, got)
+			}
+			if phonemes != tt.phonemes {
+				t.Errorf("expected phonemes %v got %v", tt.phonemes, phonemes)
+			}
+		})
+	}
+
+	t.Run("offsets", func(t *testing.T) {
+		text := "GNU is 10km away"
+		got, m, _, err := lex.Apply(text, "en")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tt := range []struct{ word, orig string }{
+			{"[[gn'u:]]", "GNU"},
+			{"is", "is"},
+			{"kilometers", "10km"},
+			{"away", "away"},
+		} {
+			i := strings.Index(got, tt.word)
+			s, e := m.Range(i, i+len(tt.word))
+			if text[s:e] != tt.orig {
+				t.Errorf("%q: expected %q got %q", tt.word, tt.orig, text[s:e])
+			}
+		}
+	})
+
+	t.Run("unknown language", func(t *testing.T) {
+		// Linux's IPA can't be converted without a table.
+		if _, _, _, err := lex.Apply("Linux", "xx"); !errors.Is(err, phoneme.ErrUnknownLanguage) {
+			t.Errorf("expected %v got %v", phoneme.ErrUnknownLanguage, err)
+		}
+	})
+}
+
+func TestNew_errors(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		entry Entry
+		want  error
+	}{
+		{"no word", Entry{Replacement: "x"}, ErrEntry},
+		{"no replacement", Entry{Word: "x"}, ErrEntry},
+		{"bad regexp", Entry{Regexp: "(", Replacement: "x"}, ErrEntry},
+		{"bad alphabet", Entry{Word: "x", Phonemes: "x", Alphabet: "arpabet"}, phoneme.ErrAlphabet},
+		{"bad phonemes", Entry{Word: "x", Phonemes: "θ", Alphabet: "ipa", Language: "es-la"}, phoneme.ErrUnknownSymbol},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.entry); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	want := []Entry{
+		{Word: "GNU", Phonemes: "ɡnuː", Alphabet: "ipa", Language: "en"},
+		{Word: "SQL", Replacement: "sequel", Language: "en", CaseSensitive: true},
+	}
+	for _, tt := range []struct {
+		file, content string
+	}{
+		{"lexicon.json", `[
+			{"word": "GNU", "phonemes": "ɡnuː", "alphabet": "ipa", "language": "en"},
+			{"word": "SQL", "replacement": "sequel", "language": "en", "case_sensitive": true}
+		]`},
+		{"object.json", `{"language": "en", "entries": [
+			{"word": "GNU", "phonemes": "ɡnuː", "alphabet": "ipa"},
+			{"word": "SQL", "replacement": "sequel", "case_sensitive": true}
+		]}`},
+		{"lexicon.csv", "# comment\n" +
+			"word,replacement,phonemes,alphabet,language,case_sensitive\n" +
+			"GNU,,ɡnuː,ipa,en,\n" +
+			"SQL,sequel,,,en,true\n"},
+		{"lexicon.pls", `<?xml version="1.0" encoding="UTF-8"?>
+<lexicon version="1.0" xmlns="http://www.w3.org/2005/01/pronunciation-lexicon"
+      alphabet="ipa" xml:lang="en">
+  <lexeme>
+    <grapheme>GNU</grapheme>
+    <phoneme>ɡnuː</phoneme>
+    <alias>new</alias>
+  </lexeme>
+  <lexeme>
+    <grapheme>SQL</grapheme>
+    <phoneme>ɛskjuːɛl</phoneme>
+    <alias prefer="true">sequel</alias>
+  </lexeme>
+</lexicon>`},
+	} {
+		t.Run(tt.file, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "lexicon")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, tt.file)
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			lex, err := Load(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := lex.Entries()
+			if len(got) != len(want) {
+				t.Fatalf("expected %d entries got %d: %+v", len(want), len(got), got)
+			}
+			for i := range want {
+				w := want[i]
+				if tt.file == "lexicon.pls" {
+					// PLS has no case sensitivity.
+					w.CaseSensitive = false
+				}
+				if got[i] != w {
+					t.Errorf("entry %d: expected %+v got %+v", i, w, got[i])
+				}
+			}
+			out, _, _, err := lex.Apply("GNU SQL", "en-us")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "[[gnu:]] sequel"; out != want {
+				t.Errorf("expected %q got %q", want, out)
+			}
+		})
+	}
+
+	t.Run("unknown extension", func(t *testing.T) {
+		f, err := ioutil.TempFile("", "lexicon*.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		defer os.Remove(f.Name())
+		if _, err := Load(f.Name()); !errors.Is(err, ErrFormat) {
+			t.Errorf("expected %v got %v", ErrFormat, err)
+		}
+	})
+
+	t.Run("unknown column", func(t *testing.T) {
+		if _, err := ReadCSV(strings.NewReader("word,spelling\nx,y\n")); !errors.Is(err, ErrFormat) {
+			t.Errorf("expected %v got %v", ErrFormat, err)
+		}
+	})
+}
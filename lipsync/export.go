@@ -0,0 +1,107 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package lipsync
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// jsonTrack a track in Rhubarb Lip Sync's JSON format.
+type jsonTrack struct {
+	Metadata struct {
+		SoundFile string  `json:"soundFile"`
+		Duration  float64 `json:"duration"`
+		Set       string  `json:"visemeSet"`
+	} `json:"metadata"`
+	MouthCues []jsonCue `json:"mouthCues"`
+}
+
+type jsonCue struct {
+	Start    float64  `json:"start"`
+	End      float64  `json:"end"`
+	Value    string   `json:"value"`
+	Phonemes []string `json:"phonemes,omitempty"`
+}
+
+// WriteJSON writes t to w as JSON, in the format of Rhubarb Lip Sync, along
+// with the name of the set and the phonemes of each cue. Times are in
+// seconds. soundFile is the path of the audio, as reported in the metadata.
+func WriteJSON(w io.Writer, t *Track, soundFile string) error {
+	var jt jsonTrack
+	jt.Metadata.SoundFile = soundFile
+	jt.Metadata.Duration = seconds(t.Duration)
+	jt.Metadata.Set = t.Set.Name
+	jt.MouthCues = make([]jsonCue, len(t.Cues))
+	for i, c := range t.Cues {
+		jt.MouthCues[i] = jsonCue{Start: seconds(c.Start), End: seconds(c.End), Value: c.Viseme, Phonemes: c.Phonemes}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(jt)
+}
+
+// seconds returns d in seconds, rounded to the millisecond.
+func seconds(d time.Duration) float64 {
+	return float64(d.Round(time.Millisecond)) / float64(time.Second)
+}
+
+// WriteRhubarb writes t to w in Rhubarb Lip Sync's TSV format, the start
+// time of each cue and its viseme, followed by the end of the last one, as
+// silence.
+func WriteRhubarb(w io.Writer, t *Track) error {
+	bw := bufio.NewWriter(w)
+	for _, c := range t.Cues {
+		fmt.Fprintf(bw, "%.2f\t%s\n", seconds(c.Start), c.Viseme)
+	}
+	if n := len(t.Cues); n > 0 && t.Cues[n-1].Viseme != t.Set.Viseme(Silence) {
+		fmt.Fprintf(bw, "%.2f\t%s\n", seconds(t.Cues[n-1].End), t.Set.Viseme(Silence))
+	}
+	return bw.Flush()
+}
+
+// WritePapagayo writes t to w as a Papagayo .pgo file, of a single voice
+// speaking a single phrase, at fps frames per second. Papagayo expects the
+// visemes of PrestonBlair. soundFile is the path of the audio.
+func WritePapagayo(w io.Writer, t *Track, soundFile string, fps int) error {
+	frame := func(d time.Duration) int {
+		return int((d*time.Duration(fps) + time.Second/2) / time.Second)
+	}
+	words := make([]string, len(t.Words))
+	for i, word := range t.Words {
+		words[i] = strings.Join(strings.Fields(word.Text), "")
+	}
+	text := strings.Join(words, " ")
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "lipsync version 1\n%s\n%d\n%d\n1\n", soundFile, fps, frame(t.Duration))
+	fmt.Fprintf(bw, "\tVoice 1\n\t%s\n\t1\n", text)
+	start, end := 0, 0
+	if n := len(t.Words); n > 0 {
+		start, end = frame(t.Words[0].StartTime), frame(t.Words[n-1].EndTime)
+	}
+	fmt.Fprintf(bw, "\t\t%s\n\t\t%d\n\t\t%d\n\t\t%d\n", text, start, end, len(t.Words))
+	silence := t.Set.Viseme(Silence)
+	for i, word := range t.Words {
+		var cues []Cue
+		for _, c := range t.Cues {
+			if c.End > word.StartTime && c.Start < word.EndTime && c.Viseme != silence {
+				cues = append(cues, c)
+			}
+		}
+		fmt.Fprintf(bw, "\t\t\t%s %d %d %d\n", words[i], frame(word.StartTime), frame(word.EndTime), len(cues))
+		for _, c := range cues {
+			at := c.Start
+			if at < word.StartTime {
+				at = word.StartTime
+			}
+			fmt.Fprintf(bw, "\t\t\t\t%d %s\n", frame(at), c.Viseme)
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,419 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+// Package lipsync builds lip-sync tracks, the mouth shapes (visemes) of
+// speech over time, from the phoneme events espeak reports while
+// synthesizing, for animating characters and avatars.
+//
+// Phonemes are grouped into classes by where and how they're articulated,
+// and each viseme set maps classes to its visemes. The Preston Blair,
+// Oculus and Rhubarb sets are included, others can be made with NewSet.
+// Tracks can be saved as JSON, Rhubarb TSV and Papagayo .pgo files.
+package lipsync
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	espeak "github.com/djangulo/go-espeak"
+	"github.com/djangulo/go-espeak/phoneme"
+)
+
+// ErrNoPhonemes the synthesizer wasn't created with espeak.PhonemeEvents,
+// and reports no phonemes.
+var ErrNoPhonemes = errors.New("lipsync: synthesizer doesn't report phoneme events")
+
+// Class phonemes that look alike on the lips.
+type Class int
+
+const (
+	// Silence pauses, and no speech.
+	Silence Class = iota
+	// Bilabial p, b, m.
+	Bilabial
+	// Labiodental f, v.
+	Labiodental
+	// Dental θ, ð.
+	Dental
+	// Alveolar t, d.
+	Alveolar
+	// Velar k, g, ŋ, h.
+	Velar
+	// Postalveolar ʃ, ʒ, tʃ, dʒ.
+	Postalveolar
+	// Sibilant s, z.
+	Sibilant
+	// Nasal n.
+	Nasal
+	// Lateral l.
+	Lateral
+	// Rhotic r, ɹ, ʁ.
+	Rhotic
+	// Labiovelar w.
+	Labiovelar
+	// OpenVowel a, ɑ, æ, ʌ.
+	OpenVowel
+	// MidVowel e, ɛ, ə, ɜ.
+	MidVowel
+	// CloseVowel i, ɪ, y, and j.
+	CloseVowel
+	// RoundedVowel o, ɔ, ɒ.
+	RoundedVowel
+	// CloseRoundedVowel u, ʊ.
+	CloseRoundedVowel
+	// Other phonemes not in any other class.
+	Other
+)
+
+// classes of IPA symbols, by their first rune.
+var classes = map[rune]Class{}
+
+func init() {
+	for class, runes := range map[Class]string{
+		Bilabial:          "pbmɱɸβ",
+		Labiodental:       "fvʋ",
+		Dental:            "θð",
+		Alveolar:          "tdɾɗ",
+		Velar:             "kgɡŋxɣhɦʔqχ",
+		Postalveolar:      "ʃʒɕʑçʝ",
+		Sibilant:          "sz",
+		Nasal:             "nɲɳ",
+		Lateral:           "lɫʎɭɬ",
+		Rhotic:            "rɹʁʀɻ",
+		Labiovelar:        "wɥʍ",
+		OpenVowel:         "aɑæʌɐɶä",
+		MidVowel:          "eɛəɜɞøœɘɵ",
+		CloseVowel:        "iɪyʏjɨɯ",
+		RoundedVowel:      "oɔɒ",
+		CloseRoundedVowel: "uʊʉ",
+	} {
+		for _, r := range runes {
+			classes[r] = class
+		}
+	}
+}
+
+// Classify returns the class of phoneme ipa, ignoring stress and length
+// marks. Affricates take the class of their fricative, "tʃ" is
+// Postalveolar, "ts" Sibilant.
+func Classify(ipa string) Class {
+	ipa = strings.TrimLeft(ipa, "ˈˌ'")
+	if ipa == "" || strings.HasPrefix(ipa, "_") {
+		return Silence
+	}
+	r, n := utf8.DecodeRuneInString(ipa)
+	if r == 't' || r == 'd' {
+		if next, _ := utf8.DecodeRuneInString(ipa[n:]); next != utf8.RuneError {
+			if c, ok := classes[next]; ok && (c == Postalveolar || c == Sibilant) {
+				return c
+			}
+		}
+	}
+	if c, ok := classes[r]; ok {
+		return c
+	}
+	return Other
+}
+
+// mnemonics espeak mnemonics whose IPA differs, used where there's no
+// phoneme table for the language.
+var mnemonics = map[string]string{
+	"tS": "tʃ", "dZ": "dʒ", "T": "θ", "D": "ð", "S": "ʃ", "Z": "ʒ",
+	"N": "ŋ", "n^": "ɲ", "l^": "ʎ", "C": "ç", "Q": "ʔ", "R": "ʁ", "r": "ɹ",
+	"@": "ə", "3": "ɜ", "V": "ʌ", "O": "ɔ", "U": "ʊ", "I": "ɪ", "E": "ɛ",
+	"0": "ɒ", "A": "ɑ", "Y": "ø", "W": "œ",
+}
+
+// toIPA returns phoneme ph, in alphabet a, in IPA, through table t if not
+// nil.
+func toIPA(ph string, a phoneme.Alphabet, t *phoneme.Table) string {
+	ph = strings.TrimLeft(ph, "'\",%")
+	if a == phoneme.IPA || ph == "" {
+		return ph
+	}
+	if t != nil {
+		if ipa, err := t.Convert(ph, a, phoneme.IPA); err == nil && ipa != "" {
+			return ipa
+		}
+	}
+	for n := 2; n > 0; n-- {
+		if len(ph) >= n {
+			if ipa, ok := mnemonics[ph[:n]]; ok {
+				return ipa
+			}
+		}
+	}
+	return ph
+}
+
+// Set a viseme set, the viseme of each class of phonemes.
+type Set struct {
+	Name string
+	// Visemes of the set, in order.
+	Visemes []string
+	classes map[Class]string
+}
+
+// NewSet returns a *Set called name, of the visemes of each class. Classes
+// left out get the viseme of Silence.
+func NewSet(name string, visemes map[Class]string) *Set {
+	s := &Set{Name: name, classes: make(map[Class]string)}
+	seen := make(map[string]bool)
+	for c := Silence; c <= Other; c++ {
+		v, ok := visemes[c]
+		if !ok {
+			v = visemes[Silence]
+		}
+		s.classes[c] = v
+		if !seen[v] {
+			seen[v] = true
+			s.Visemes = append(s.Visemes, v)
+		}
+	}
+	return s
+}
+
+// Viseme returns the viseme of class c.
+func (s *Set) Viseme(c Class) string {
+	return s.classes[c]
+}
+
+// Viseme sets.
+var (
+	// PrestonBlair the classic animation set, used by Papagayo.
+	PrestonBlair = NewSet("preston-blair", map[Class]string{
+		Silence:           "rest",
+		Bilabial:          "MBP",
+		Labiodental:       "FV",
+		Dental:            "etc",
+		Alveolar:          "etc",
+		Velar:             "etc",
+		Postalveolar:      "etc",
+		Sibilant:          "etc",
+		Nasal:             "etc",
+		Lateral:           "L",
+		Rhotic:            "etc",
+		Labiovelar:        "WQ",
+		OpenVowel:         "AI",
+		MidVowel:          "E",
+		CloseVowel:        "E",
+		RoundedVowel:      "O",
+		CloseRoundedVowel: "U",
+		Other:             "etc",
+	})
+	// Oculus the 15 visemes of the Oculus (Meta) lip-sync SDK.
+	Oculus = NewSet("oculus", map[Class]string{
+		Silence:           "sil",
+		Bilabial:          "PP",
+		Labiodental:       "FF",
+		Dental:            "TH",
+		Alveolar:          "DD",
+		Velar:             "kk",
+		Postalveolar:      "CH",
+		Sibilant:          "SS",
+		Nasal:             "nn",
+		Lateral:           "nn",
+		Rhotic:            "RR",
+		Labiovelar:        "ou",
+		OpenVowel:         "aa",
+		MidVowel:          "E",
+		CloseVowel:        "ih",
+		RoundedVowel:      "oh",
+		CloseRoundedVowel: "ou",
+		Other:             "DD",
+	})
+	// Rhubarb the mouth shapes A to H, and X, of Rhubarb Lip Sync.
+	Rhubarb = NewSet("rhubarb", map[Class]string{
+		Silence:           "X",
+		Bilabial:          "A",
+		Labiodental:       "G",
+		Dental:            "B",
+		Alveolar:          "B",
+		Velar:             "B",
+		Postalveolar:      "B",
+		Sibilant:          "B",
+		Nasal:             "B",
+		Lateral:           "H",
+		Rhotic:            "E",
+		Labiovelar:        "F",
+		OpenVowel:         "D",
+		MidVowel:          "C",
+		CloseVowel:        "B",
+		RoundedVowel:      "E",
+		CloseRoundedVowel: "F",
+		Other:             "B",
+	})
+)
+
+// Cue a viseme, shown from Start to End.
+type Cue struct {
+	Start, End time.Duration
+	Viseme     string
+	// Phonemes spoken, in IPA, none for silence.
+	Phonemes []string
+}
+
+// Track the visemes of a synthesis.
+type Track struct {
+	// Set of the visemes.
+	Set *Set
+	// Duration of the audio.
+	Duration time.Duration
+	// Cues in order, covering the whole audio.
+	Cues []Cue
+	// Words timings of the words spoken.
+	Words []espeak.WordTiming
+}
+
+// Options how a track is built.
+type Options struct {
+	// Set of visemes. Default Oculus.
+	Set *Set
+	// Alphabet of the phonemes of the events, phoneme.IPA if espeak was
+	// initialized with espeak.PhonemeIPA. Default phoneme.Espeak.
+	Alphabet phoneme.Alphabet
+	// Language of the voice, whose phoneme table converts mnemonics to
+	// IPA. Mnemonics are read as the usual ones without it. Default that of
+	// the Result's Voice, if any.
+	Language string
+	// MinDuration of a cue. Shorter ones are merged into the one before,
+	// or, after a rest, into the one after, so mouths don't flicker. A short
+	// sound between rests is kept. Default 0.
+	MinDuration time.Duration
+}
+
+// New builds the track of r, synthesized by a synthesizer reporting phoneme
+// events. A phoneme lasts until the next one, or the end of the clause.
+// opts may be nil.
+func New(r *espeak.Result, opts *Options) *Track {
+	if opts == nil {
+		opts = &Options{}
+	}
+	set := opts.Set
+	if set == nil {
+		set = Oculus
+	}
+	language := opts.Language
+	if language == "" && r.Voice != nil {
+		language = r.Voice.Language()
+	}
+	table, _ := phoneme.Lookup(language)
+	rate := r.SampleRate
+	at := func(sample int) time.Duration {
+		if rate <= 0 {
+			return 0
+		}
+		return time.Duration(sample) * time.Second / time.Duration(rate)
+	}
+	t := &Track{Set: set, Duration: at(len(r.Samples)), Words: r.Words}
+
+	// add appends a cue, after silence since the last one, if any.
+	add := func(start, end time.Duration, viseme, ipa string) {
+		if end <= start {
+			return
+		}
+		if last := t.lastEnd(); last < start {
+			t.Cues = append(t.Cues, Cue{Start: last, End: start, Viseme: set.Viseme(Silence)})
+		}
+		if n := len(t.Cues); n > 0 && t.Cues[n-1].Viseme == viseme {
+			last := &t.Cues[n-1]
+			last.End = end
+			if ipa != "" {
+				last.Phonemes = append(last.Phonemes, ipa)
+			}
+			return
+		}
+		c := Cue{Start: start, End: end, Viseme: viseme}
+		if ipa != "" {
+			c.Phonemes = []string{ipa}
+		}
+		t.Cues = append(t.Cues, c)
+	}
+	for i, e := range r.Events {
+		if e.Type != espeak.PhonemeEvent {
+			continue
+		}
+		end := len(r.Samples)
+		for _, next := range r.Events[i+1:] {
+			if next.Sample < e.Sample {
+				continue
+			}
+			if next.Type == espeak.PhonemeEvent || next.Type == espeak.EndEvent || next.Type == espeak.MsgTerminatedEvent {
+				end = next.Sample
+				break
+			}
+		}
+		ipa := toIPA(e.Phoneme, opts.Alphabet, table)
+		class := Classify(ipa)
+		if class == Silence {
+			ipa = ""
+		}
+		add(at(e.Sample), at(end), set.Viseme(class), ipa)
+	}
+	add(t.lastEnd(), t.Duration, set.Viseme(Silence), "")
+	t.merge(opts.MinDuration, set.Viseme(Silence))
+	return t
+}
+
+// merge merges the cues of t shorter than min into the one before, unless
+// it's a rest, or else into the one after, unless that's a rest too.
+func (t *Track) merge(min time.Duration, rest string) {
+	if min <= 0 {
+		return
+	}
+	cues := t.Cues
+	out := cues[:0]
+	for i := 0; i < len(cues); i++ {
+		c := cues[i]
+		n := len(out)
+		if c.End-c.Start < min {
+			switch {
+			case n > 0 && (out[n-1].Viseme != rest || c.Viseme == rest):
+				out[n-1].End = c.End
+				out[n-1].Phonemes = append(out[n-1].Phonemes, c.Phonemes...)
+				continue
+			case c.Viseme != rest && i+1 < len(cues) && cues[i+1].Viseme != rest:
+				cues[i+1].Start = c.Start
+				cues[i+1].Phonemes = append(append([]string(nil), c.Phonemes...), cues[i+1].Phonemes...)
+				continue
+			}
+		}
+		if n > 0 && out[n-1].Viseme == c.Viseme {
+			out[n-1].End = c.End
+			out[n-1].Phonemes = append(out[n-1].Phonemes, c.Phonemes...)
+			continue
+		}
+		out = append(out, c)
+	}
+	t.Cues = out
+}
+
+// lastEnd returns the end of the last cue of t, 0 if there's none.
+func (t *Track) lastEnd() time.Duration {
+	if n := len(t.Cues); n > 0 {
+		return t.Cues[n-1].End
+	}
+	return 0
+}
+
+// GenSamples synthesizes text through s, created with espeak.PhonemeEvents,
+// using voice, modified by params, returning its samples along with their
+// track of visemes of set, Oculus if nil.
+func GenSamples(ctx context.Context, s *espeak.Synthesizer, text string, voice *espeak.Voice, params *espeak.Parameters, set *Set) ([]int16, *Track, error) {
+	if s.Options()&espeak.PhonemeEvents == 0 {
+		return nil, nil, ErrNoPhonemes
+	}
+	r, err := s.Synthesize(ctx, text, voice, params)
+	if err != nil {
+		return nil, nil, err
+	}
+	// the voice AutoVoice picked, if set.
+	opts := &Options{Set: set, Language: r.Voice.Language()}
+	if s.Options()&espeak.PhonemeIPA != 0 {
+		opts.Alphabet = phoneme.IPA
+	}
+	return r.Samples, New(r, opts), nil
+}
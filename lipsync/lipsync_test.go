@@ -0,0 +1,219 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package lipsync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	espeak "github.com/djangulo/go-espeak"
+	"github.com/djangulo/go-espeak/phoneme"
+)
+
+func TestClassify(t *testing.T) {
+	for _, tt := range []struct {
+		ipa  string
+		want Class
+	}{
+		{"", Silence},
+		{"_:", Silence},
+		{"p", Bilabial},
+		{"ˈæ", OpenVowel},
+		{"tʃ", Postalveolar},
+		{"ts", Sibilant},
+		{"t", Alveolar},
+		{"ɹ", Rhotic},
+		{"oʊ", RoundedVowel},
+		{"uː", CloseRoundedVowel},
+		{"w", Labiovelar},
+		{"ʘ", Other},
+	} {
+		t.Run(tt.ipa, func(t *testing.T) {
+			if got := Classify(tt.ipa); got != tt.want {
+				t.Errorf("expected %d got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewSet(t *testing.T) {
+	if len(Oculus.Visemes) != 15 {
+		t.Errorf("expected 15 Oculus visemes got %v", Oculus.Visemes)
+	}
+	set := NewSet("open", map[Class]string{Silence: "closed", OpenVowel: "open"})
+	if set.Viseme(Bilabial) != "closed" || set.Viseme(OpenVowel) != "open" {
+		t.Errorf("unexpected visemes %v", set.Visemes)
+	}
+}
+
+// hello the synthesis of "hello", at 1kHz.
+func hello(ph ...string) *espeak.Result {
+	r := &espeak.Result{SampleRate: 1000, Samples: make([]int16, 1000)}
+	for i, p := range ph {
+		r.Events = append(r.Events, espeak.Event{Type: espeak.PhonemeEvent, Phoneme: p, Sample: 100 * (i + 1)})
+	}
+	r.Events = append(r.Events,
+		espeak.Event{Type: espeak.EndEvent, Sample: 600},
+		espeak.Event{Type: espeak.PhonemeEvent, Phoneme: "_", Sample: 700},
+		espeak.Event{Type: espeak.MsgTerminatedEvent, Sample: 900},
+	)
+	r.Words = []espeak.WordTiming{{Text: "hello", StartTime: 100 * time.Millisecond, EndTime: 600 * time.Millisecond}}
+	return r
+}
+
+func TestNew(t *testing.T) {
+	ms := time.Millisecond
+	want := []Cue{
+		{0, 100 * ms, "sil", nil},
+		{100 * ms, 200 * ms, "kk", []string{"h"}},
+		{200 * ms, 300 * ms, "E", []string{"ə"}},
+		{300 * ms, 400 * ms, "nn", []string{"l"}},
+		{400 * ms, 600 * ms, "oh", []string{"oʊ"}},
+		{600 * ms, time.Second, "sil", nil},
+	}
+	for _, tt := range []struct {
+		name string
+		r    *espeak.Result
+		opts *Options
+	}{
+		{"mnemonics", hello("h", "@", "l", "oU"), &Options{Language: "en-us"}},
+		{"mnemonics without a table", hello("h", "@", "l", "oU"), nil},
+		{"IPA", hello("h", "ə", "l", "oʊ"), &Options{Alphabet: phoneme.IPA}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			track := New(tt.r, tt.opts)
+			if track.Duration != time.Second {
+				t.Errorf("expected 1s got %v", track.Duration)
+			}
+			if len(track.Cues) != len(want) {
+				t.Fatalf("expected %d cues got %d: %+v", len(want), len(track.Cues), track.Cues)
+			}
+			for i := range want {
+				got := track.Cues[i]
+				if got.Start != want[i].Start || got.End != want[i].End || got.Viseme != want[i].Viseme {
+					t.Errorf("cue %d: expected %+v got %+v", i, want[i], got)
+				}
+				// without a table, "oU" is kept as is.
+				if tt.opts != nil && strings.Join(got.Phonemes, " ") != strings.Join(want[i].Phonemes, " ") {
+					t.Errorf("cue %d: expected phonemes %q got %q", i, want[i].Phonemes, got.Phonemes)
+				}
+			}
+		})
+	}
+
+	t.Run("min duration", func(t *testing.T) {
+		track := New(hello("h", "@", "l", "oU"), &Options{Set: Rhubarb, Language: "en-us", MinDuration: 150 * ms})
+		var got []string
+		for _, c := range track.Cues {
+			got = append(got, fmt.Sprintf("%s %v-%v %s", c.Viseme, c.Start, c.End, strings.Join(c.Phonemes, "")))
+		}
+		// h, after the silence, is merged into @, and l into them.
+		want := []string{"X 0s-100ms ", "C 100ms-400ms həl", "E 400ms-600ms oʊ", "X 600ms-1s "}
+		if strings.Join(got, ", ") != strings.Join(want, ", ") {
+			t.Errorf("expected %q got %q", want, got)
+		}
+	})
+
+	t.Run("min duration between rests", func(t *testing.T) {
+		r := &espeak.Result{SampleRate: 1000, Samples: make([]int16, 1000), Events: []espeak.Event{
+			{Type: espeak.PhonemeEvent, Phoneme: "_", Sample: 0},
+			{Type: espeak.PhonemeEvent, Phoneme: "m", Sample: 300},
+			{Type: espeak.PhonemeEvent, Phoneme: "_", Sample: 350},
+			{Type: espeak.MsgTerminatedEvent, Sample: 1000},
+		}}
+		track := New(r, &Options{Set: Rhubarb, MinDuration: 150 * ms})
+		var got []string
+		for _, c := range track.Cues {
+			got = append(got, c.Viseme)
+		}
+		// the short m isn't lost to the silences around it.
+		if strings.Join(got, " ") != "X A X" {
+			t.Errorf("expected X A X got %v", got)
+		}
+	})
+
+	t.Run("voice of the result", func(t *testing.T) {
+		r := hello("h", "@", "l", "oU")
+		r.Voice = espeak.ENUSMale
+		track := New(r, nil)
+		if got := track.Cues[4].Phonemes; len(got) != 1 || got[0] != "oʊ" {
+			t.Errorf("expected %q got %q", "oʊ", got)
+		}
+	})
+}
+
+func TestWrite(t *testing.T) {
+	track := New(hello("h", "@", "l", "oU"), &Options{Set: PrestonBlair, Language: "en-us"})
+	t.Run("rhubarb", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteRhubarb(&buf, track); err != nil {
+			t.Fatal(err)
+		}
+		want := "0.00\trest\n0.10\tetc\n0.20\tE\n0.30\tL\n0.40\tO\n0.60\trest\n"
+		if buf.String() != want {
+			t.Errorf("expected %q got %q", want, buf.String())
+		}
+	})
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteJSON(&buf, track, "hello.wav"); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{`"soundFile": "hello.wav"`, `"duration": 1`, `"visemeSet": "preston-blair"`, `"start": 0.4`, `"value": "O"`, `"oʊ"`} {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("expected %s in %s", want, buf.String())
+			}
+		}
+	})
+	t.Run("papagayo", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WritePapagayo(&buf, track, "hello.wav", 24); err != nil {
+			t.Fatal(err)
+		}
+		want := "lipsync version 1\nhello.wav\n24\n24\n1\n" +
+			"\tVoice 1\n\thello\n\t1\n" +
+			"\t\thello\n\t\t2\n\t\t14\n\t\t1\n" +
+			"\t\t\thello 2 14 4\n" +
+			"\t\t\t\t2 etc\n\t\t\t\t5 E\n\t\t\t\t7 L\n\t\t\t\t10 O\n"
+		if buf.String() != want {
+			t.Errorf("expected %q got %q", want, buf.String())
+		}
+	})
+}
+
+func TestGenSamples(t *testing.T) {
+	s, err := espeak.NewSynthesizer(espeak.Synchronous, 200, nil, espeak.PhonemeEvents)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	samples, track, err := GenSamples(context.Background(), s, "Hello world", nil, nil, Rhubarb)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(samples) == 0 || len(track.Cues) < 2 {
+		t.Fatalf("expected samples and cues, got %d samples and %+v", len(samples), track.Cues)
+	}
+	if last := track.Cues[len(track.Cues)-1]; last.End != track.Duration {
+		t.Errorf("expected the cues to end at %v got %v", track.Duration, last.End)
+	}
+	for i := 1; i < len(track.Cues); i++ {
+		if track.Cues[i].Start != track.Cues[i-1].End {
+			t.Errorf("cue %d: starts at %v, the one before ends at %v", i, track.Cues[i].Start, track.Cues[i-1].End)
+		}
+	}
+
+	plain, err := espeak.NewSynthesizer(espeak.Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+	if _, _, err := GenSamples(context.Background(), plain, "Hello", nil, nil, nil); !errors.Is(err, ErrNoPhonemes) {
+		t.Errorf("expected %v got %v", ErrNoPhonemes, err)
+	}
+}
@@ -0,0 +1,328 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package espeak
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/djangulo/go-espeak/langid"
+	"github.com/djangulo/go-espeak/ssml"
+)
+
+// Segment a part of a text in a single language, see SynthSegments.
+type Segment struct {
+	// Text of the segment, an SSML document if SSML is set.
+	Text string
+	// Language of Text, e.g. "es", spoken with the installed voice espeak
+	// prefers for it. Ignored if Voice is set.
+	Language string
+	// Voice Text is spoken with. Default the voice of Language, or the
+	// call's if neither is set.
+	Voice *Voice
+	// SSML Text is an SSML document.
+	SSML bool
+}
+
+// segment a Segment to synthesize, offset bytes into the text of the call.
+type segment struct {
+	text   string
+	offset int
+	flags  FlagType
+	voice  *Voice
+}
+
+// SynthSegments synthesizes segments one after the other, each with its own
+// voice, all modified by params, into a single *Result, as if they were a
+// single text: their texts joined by a space where neither has one at the
+// joint. Events and word timings refer to that text, and carry on from one
+// segment to the next; the end of each but the last is reported as an
+// EndEvent. Only the last is followed by a pause. params.AutoVoice picks the
+// voice of segments with neither Voice nor Language.
+func (s *Synthesizer) SynthSegments(ctx context.Context, segments []Segment, voice *Voice, params *Parameters) (*Result, error) {
+	voice, params, err := s.resolve(voice, params)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		text strings.Builder
+		segs []segment
+	)
+	for _, seg := range segments {
+		if strings.TrimSpace(seg.Text) == "" {
+			continue
+		}
+		if text.Len() > 0 {
+			last, _ := utf8.DecodeLastRuneInString(text.String())
+			first, _ := utf8.DecodeRuneInString(seg.Text)
+			if !unicode.IsSpace(last) && !unicode.IsSpace(first) {
+				text.WriteByte(' ')
+			}
+		}
+		sg := segment{text: seg.Text, offset: text.Len(), flags: CharsAuto, voice: seg.Voice}
+		if seg.SSML {
+			sg.flags |= SSML
+		}
+		switch {
+		case sg.voice != nil:
+		case seg.Language != "":
+			found, err := s.languageVoice(seg.Language)
+			if err != nil {
+				return nil, err
+			}
+			if found == nil {
+				// left to espeak to pick.
+				found = &Voice{Languages: seg.Language}
+			}
+			sg.voice = found
+		default:
+			if sg.voice, _, err = s.resolveText(seg.Text, sg.flags, voice, params); err != nil {
+				return nil, err
+			}
+		}
+		text.WriteString(seg.Text)
+		segs = append(segs, sg)
+	}
+	if len(segs) == 0 {
+		return nil, ErrEmptyText
+	}
+	return s.synthSegments(ctx, text.String(), segs, params)
+}
+
+// SynthSegments synthesizes segments through the default synthesizer. See
+// Synthesizer.SynthSegments.
+func SynthSegments(ctx context.Context, segments []Segment, voice *Voice, params *Parameters) (*Result, error) {
+	s, err := defaultSynthesizer()
+	if err != nil {
+		return nil, err
+	}
+	return s.SynthSegments(ctx, segments, voice, params)
+}
+
+// SynthMixed synthesizes text, written in more than one language, switching
+// voices as it goes. text is split into spans in a single language with
+// langid.Spans, each spoken with the voice params.AutoVoice would pick for
+// it, see AutoVoice; params.AutoVoice may be nil, to pick among any
+// language. Events and word timings refer to text. See SynthSegments.
+func (s *Synthesizer) SynthMixed(ctx context.Context, text string, voice *Voice, params *Parameters) (*Result, error) {
+	if text == "" {
+		return nil, ErrEmptyText
+	}
+	voice, params, err := s.resolve(voice, params)
+	if err != nil {
+		return nil, err
+	}
+	a := params.AutoVoice
+	if a == nil {
+		a = &AutoVoice{}
+	}
+	var segs []segment
+	for _, span := range langid.New(a.Languages...).Spans(text) {
+		v, err := s.guessVoice(span.Guess, voice, a)
+		if err != nil {
+			return nil, err
+		}
+		// voices given as specs, e.g. a fallback of {Languages: "fr"}, have
+		// no name, so the whole voice is compared.
+		if n := len(segs); n > 0 && *segs[n-1].voice == *v {
+			segs[n-1].text = text[segs[n-1].offset:span.End]
+			continue
+		}
+		segs = append(segs, segment{text: text[span.Start:span.End], offset: span.Start, flags: CharsAuto, voice: v})
+	}
+	if len(segs) == 0 {
+		// nothing to tell the language of.
+		segs = append(segs, segment{text: text, flags: CharsAuto, voice: voice})
+	}
+	return s.synthSegments(ctx, text, segs, params)
+}
+
+// SynthMixed synthesizes text, written in more than one language, through
+// the default synthesizer. See Synthesizer.SynthMixed.
+func SynthMixed(ctx context.Context, text string, voice *Voice, params *Parameters) (*Result, error) {
+	s, err := defaultSynthesizer()
+	if err != nil {
+		return nil, err
+	}
+	return s.SynthMixed(ctx, text, voice, params)
+}
+
+// synthSegments synthesizes segs, parts of text, one after the other, at
+// s's sample rate, converting the whole to params' at the end so the joints
+// don't show.
+func (s *Synthesizer) synthSegments(ctx context.Context, text string, segs []segment, params *Parameters) (*Result, error) {
+	if s.plays() {
+		return nil, ErrOutputMode
+	}
+	rs, err := s.resampler(params)
+	if err != nil {
+		return nil, err
+	}
+	// the same parameters for every segment, the voice is already picked.
+	native := *params
+	native.SampleRate = 0
+	native.AutoVoice = nil
+
+	r := &Result{SampleRate: s.sampleRate}
+	for i, seg := range segs {
+		flags := seg.flags
+		last := i == len(segs)-1
+		if last {
+			flags |= EndPause
+		}
+		sr, err := s.synthesizeResult(ctx, seg.text, flags, seg.voice, &native)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			r.Voice = sr.Voice
+		}
+		chars := utf8.RuneCountInString(text[:seg.offset])
+		at := len(r.Samples)
+		ms := int(samplesToDuration(at, s.sampleRate).Milliseconds())
+		for _, e := range sr.Events {
+			if e.Type == MsgTerminatedEvent && !last {
+				e.Type = EndEvent
+			}
+			if e.TextPosition > 0 {
+				e.TextPosition += chars
+			}
+			e.Sample += at
+			e.AudioPosition += ms
+			r.Events = append(r.Events, e)
+		}
+		r.Samples = append(r.Samples, sr.Samples...)
+	}
+	if rs != nil {
+		r.Samples = append(rs.ProcessInt16(r.Samples), rs.FlushInt16()...)
+		resampleEvents(r.Events, s.sampleRate, rs.OutRate())
+		r.SampleRate = rs.OutRate()
+	}
+	r.Words = AlignWords(text, r.Events, r.SampleRate, len(r.Samples))
+	return r, nil
+}
+
+// SSMLSegments splits doc, an SSML document, into segments at the elements
+// with an xml:lang other than their parent's, each a document of its own,
+// holding the elements around it, for SynthSegments. The segments before the
+// first xml:lang have no Language, and so are spoken with the call's voice.
+// The error wraps ssml.ErrSyntax if doc is not well-formed.
+func SSMLSegments(doc string) ([]Segment, error) {
+	type open struct {
+		start xml.StartElement
+		lang  string
+	}
+	var (
+		segments []Segment
+		stack    []open
+		b        strings.Builder
+		lang     string
+		// content the segment has text, a break, mark or audio.
+		content bool
+	)
+	start := func(t xml.StartElement) {
+		b.WriteString("<" + qualifiedName(t.Name))
+		for _, a := range t.Attr {
+			if qualifiedName(a.Name) == "xml:lang" {
+				continue
+			}
+			b.WriteString(" " + qualifiedName(a.Name) + `="`)
+			xml.EscapeText(&b, []byte(a.Value))
+			b.WriteByte('"')
+		}
+		b.WriteByte('>')
+	}
+	// split ends the segment so far, and starts one in language l.
+	split := func(l string) {
+		if content {
+			for i := len(stack) - 1; i >= 0; i-- {
+				b.WriteString("</" + qualifiedName(stack[i].start.Name) + ">")
+			}
+			segments = append(segments, Segment{Text: b.String(), Language: lang, SSML: true})
+		}
+		b.Reset()
+		content = false
+		lang = l
+		for _, o := range stack {
+			start(o.start)
+		}
+	}
+
+	d := xml.NewDecoder(strings.NewReader(doc))
+	d.Strict = true
+	data := []byte(doc)
+	for {
+		off := d.InputOffset()
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ssml.ErrSyntax, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			l := lang
+			for _, a := range t.Attr {
+				if qualifiedName(a.Name) == "xml:lang" {
+					l = a.Value
+				}
+			}
+			if l != lang {
+				split(l)
+			}
+			start(t)
+			switch qualifiedName(t.Name) {
+			case "break", "mark", "audio":
+				content = true
+			}
+			stack = append(stack, open{t.Copy(), l})
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("%w: unexpected </%s>", ssml.ErrSyntax, qualifiedName(t.Name))
+			}
+			stack = stack[:len(stack)-1]
+			// a self-closing element is read as a start and an end.
+			if raw := data[off:d.InputOffset()]; len(raw) > 0 {
+				b.Write(raw)
+			} else {
+				b.WriteString("</" + qualifiedName(t.Name) + ">")
+			}
+			parent := ""
+			if n := len(stack); n > 0 {
+				parent = stack[n-1].lang
+			}
+			if parent != lang && len(stack) > 0 {
+				split(parent)
+			}
+		case xml.CharData:
+			if len(stack) == 0 {
+				continue
+			}
+			b.Write(data[off:d.InputOffset()])
+			content = content || len(bytes.TrimSpace(t)) > 0
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("%w: <%s> is not closed", ssml.ErrSyntax, qualifiedName(stack[len(stack)-1].start.Name))
+	}
+	if content {
+		segments = append(segments, Segment{Text: b.String(), Language: lang, SSML: true})
+	}
+	return segments, nil
+}
+
+// qualifiedName returns n as written, prefix included.
+func qualifiedName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
@@ -0,0 +1,161 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package espeak
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/djangulo/go-espeak/ssml"
+)
+
+func TestSynthesizer_SynthSegments(t *testing.T) {
+	s, err := NewSynthesizer(Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+
+	en, err := s.Synthesize(ctx, "The word is", ENUSMale, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	segments := []Segment{
+		{Text: "The word is", Voice: ENUSMale},
+		{Text: "casa.", Language: "es"},
+	}
+	params := NewParameters(WithSampleRate(s.SampleRate() / 2))
+	r, err := s.SynthSegments(ctx, segments, nil, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.SampleRate != s.SampleRate()/2 {
+		t.Errorf("expected %d got %d", s.SampleRate()/2, r.SampleRate)
+	}
+	var words []string
+	for _, w := range r.Words {
+		words = append(words, w.Text)
+	}
+	if len(words) != 4 || !strings.HasPrefix(words[3], "casa") {
+		t.Fatalf("expected the words of %q got %q", "The word is casa.", words)
+	}
+	// casa starts after the English segment, which has no end pause.
+	if got := r.Words[3].Start; got < len(en.Samples)/2-len(en.Samples)/10 || got > len(en.Samples)/2 {
+		t.Errorf("expected casa to start around %d got %d", len(en.Samples)/2, got)
+	}
+	for i := 1; i < len(r.Events); i++ {
+		if r.Events[i].Sample < r.Events[i-1].Sample {
+			t.Errorf("event %d: at %d, before the one before, at %d", i, r.Events[i].Sample, r.Events[i-1].Sample)
+		}
+	}
+	terminated := 0
+	for _, e := range r.Events {
+		if e.Type == MsgTerminatedEvent {
+			terminated++
+		}
+	}
+	if terminated != 1 {
+		t.Errorf("expected 1 %v got %d", MsgTerminatedEvent, terminated)
+	}
+
+	t.Run("empty", func(t *testing.T) {
+		if _, err := s.SynthSegments(ctx, []Segment{{Text: " "}}, nil, nil); !errors.Is(err, ErrEmptyText) {
+			t.Errorf("expected %v got %v", ErrEmptyText, err)
+		}
+	})
+}
+
+func TestSynthesizer_SynthMixed(t *testing.T) {
+	s, err := NewSynthesizer(Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+	text := "I love this new city. Es muy bonita, y la comida es deliciosa."
+	params := NewParameters(WithAutoVoice(&AutoVoice{Languages: []string{"en", "es"}}))
+	r, err := s.SynthMixed(ctx, text, ENUSMale, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	es, err := s.Synthesize(ctx, "Es muy bonita, y la comida es deliciosa.", ESSpainMale, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var start int
+	for _, w := range r.Words {
+		if w.Text == "Es" {
+			start = w.Start
+			if w.Offset != 22 {
+				t.Errorf("expected offset 22 got %d", w.Offset)
+			}
+		}
+	}
+	if start == 0 {
+		t.Fatalf("expected the word Es in %+v", r.Words)
+	}
+	if len(r.Samples)-start != len(es.Samples) || r.Samples[start] != es.Samples[0] {
+		t.Errorf("expected the Spanish sentence spoken by %q", ESSpainMale.Name)
+	}
+}
+
+func TestSynthesizer_SynthMixed_specs(t *testing.T) {
+	s, err := NewSynthesizer(Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	ctx := context.Background()
+	// there's no it voice, so the Italian is spoken by the fallback, and
+	// neither voice has a name.
+	voice, fallback := &Voice{Languages: "en-us"}, &Voice{Languages: "fr"}
+	text := "I love this new city. Ciao, come stai? Sto bene, grazie."
+	params := NewParameters(WithAutoVoice(&AutoVoice{Languages: []string{"en", "it"}, Fallback: fallback}))
+	r, err := s.SynthMixed(ctx, text, voice, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	it, err := s.Synthesize(ctx, "Ciao, come stai? Sto bene, grazie.", fallback, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var start int
+	for _, w := range r.Words {
+		if w.Offset == strings.Index(text, "Ciao") {
+			start = w.Start
+		}
+	}
+	if start == 0 {
+		t.Fatalf("expected the word Ciao in %+v", r.Words)
+	}
+	if len(r.Samples)-start != len(it.Samples) || r.Samples[start] != it.Samples[0] {
+		t.Errorf("expected the Italian spoken by %v", fallback)
+	}
+}
+
+func TestSSMLSegments(t *testing.T) {
+	doc := `<speak xml:lang="en-US"><p>The word is <s xml:lang="es">casa<break/></s>, <mark name="m"/>it means house.</p></speak>`
+	got, err := SSMLSegments(doc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Segment{
+		{Text: `<speak><p>The word is </p></speak>`, Language: "en-US", SSML: true},
+		{Text: `<speak><p><s>casa<break></break></s></p></speak>`, Language: "es", SSML: true},
+		{Text: `<speak><p>, <mark name="m"></mark>it means house.</p></speak>`, Language: "en-US", SSML: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d segments got %+v", len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %+v got %+v", want[i], got[i])
+		}
+	}
+	if _, err := SSMLSegments("<speak><p>unclosed</speak>"); !errors.Is(err, ssml.ErrSyntax) {
+		t.Errorf("expected %v got %v", ssml.ErrSyntax, err)
+	}
+}
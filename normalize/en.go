@@ -0,0 +1,295 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package normalize
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	enOnes = [...]string{
+		"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+		"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen",
+		"seventeen", "eighteen", "nineteen",
+	}
+	enTens = [...]string{
+		"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety",
+	}
+	enScales = [...]string{"", "thousand", "million", "billion", "trillion"}
+	// enOrdinals irregular ordinals, others add "th".
+	enOrdinals = map[string]string{
+		"one":    "first",
+		"two":    "second",
+		"three":  "third",
+		"five":   "fifth",
+		"eight":  "eighth",
+		"nine":   "ninth",
+		"twelve": "twelfth",
+	}
+	enMonths = [...]string{
+		"January", "February", "March", "April", "May", "June", "July",
+		"August", "September", "October", "November", "December",
+	}
+)
+
+func enCardinal(n int64, _ form) string {
+	if n == 0 {
+		return enOnes[0]
+	}
+	var words []string
+	scale := int64(1)
+	for i := 1; i < len(enScales); i++ {
+		scale *= 1000
+	}
+	for i := len(enScales) - 1; i >= 0; i-- {
+		if g := n / scale % 1000; g > 0 {
+			words = append(words, enBelow1000(int(g)))
+			if enScales[i] != "" {
+				words = append(words, enScales[i])
+			}
+		}
+		scale /= 1000
+	}
+	return strings.Join(words, " ")
+}
+
+func enBelow1000(n int) string {
+	var words []string
+	if h := n / 100; h > 0 {
+		words = append(words, enOnes[h], "hundred")
+	}
+	switch r := n % 100; {
+	case r == 0:
+	case r < 20:
+		words = append(words, enOnes[r])
+	case r%10 == 0:
+		words = append(words, enTens[r/10])
+	default:
+		words = append(words, enTens[r/10]+"-"+enOnes[r%10])
+	}
+	return strings.Join(words, " ")
+}
+
+func enOrdinal(n int64, _ form) string {
+	head, last := lastWord(enCardinal(n, standalone))
+	switch {
+	case enOrdinals[last] != "":
+		last = enOrdinals[last]
+	case strings.HasSuffix(last, "y"):
+		last = strings.TrimSuffix(last, "y") + "ieth"
+	default:
+		last += "th"
+	}
+	return head + last
+}
+
+// enYear reads a year the way it's usually said, in pairs of digits:
+// "nineteen ninety-nine", "nineteen oh five", "twenty twenty-six".
+func enYear(y int) string {
+	switch {
+	case y < 1000 || y > 9999, y%1000 == 0, y >= 2000 && y < 2010:
+		return enCardinal(int64(y), standalone)
+	case y%100 == 0:
+		return enCardinal(int64(y/100), standalone) + " hundred"
+	case y%100 < 10:
+		return enCardinal(int64(y/100), standalone) + " oh " + enOnes[y%100]
+	default:
+		return enCardinal(int64(y/100), standalone) + " " + enCardinal(int64(y%100), standalone)
+	}
+}
+
+func enDate(y, m, d int) string {
+	return enMonths[m-1] + " " + enOrdinal(int64(d), standalone) + ", " + enYear(y)
+}
+
+// enTime reads h:mm, either in 12 hour time with a period (am or pm), or
+// in 24 hour time.
+func enTime(h, m int, period string) string {
+	if h == 0 && m == 0 && period == "" {
+		return "midnight"
+	}
+	hours := enCardinal(int64(h), standalone)
+	var minutes string
+	switch {
+	case m == 0 && period == "" && h > 12:
+		minutes = "hundred"
+	case m == 0 && period == "":
+		minutes = "o'clock"
+	case m == 0:
+	case m < 10:
+		minutes = "oh " + enOnes[m]
+	default:
+		minutes = enCardinal(int64(m), standalone)
+	}
+	words := []string{hours}
+	if minutes != "" {
+		words = append(words, minutes)
+	}
+	switch strings.ToLower(period) {
+	case "a":
+		words = append(words, "ay em")
+	case "p":
+		words = append(words, "pee em")
+	}
+	return strings.Join(words, " ")
+}
+
+var english = &language{
+	cardinal: enCardinal,
+	ordinal:  enOrdinal,
+	singular: func(num number) bool {
+		return num.digits == "1" && num.frac == ""
+	},
+	point:          "point",
+	minus:          "minus",
+	fractionDigits: true,
+	groupSeps:      ",",
+	decimalSeps:    ".",
+	and:            "and",
+	date:           enDate,
+	ordinalExpr:    `\b(\d+)(st|nd|rd|th)`,
+	ordinalForm:    func(string) form { return standalone },
+	currencies: map[string]currency{
+		"USD": {noun{"dollar", "dollars", masculine}, noun{"cent", "cents", masculine}},
+		"EUR": {noun{"euro", "euros", masculine}, noun{"cent", "cents", masculine}},
+		"GBP": {noun{"pound", "pounds", masculine}, noun{"penny", "pence", masculine}},
+		"JPY": {noun: noun{"yen", "yen", masculine}},
+	},
+	units: map[string]noun{
+		"km/h": {"kilometer per hour", "kilometers per hour", masculine},
+		"m/s":  {"meter per second", "meters per second", masculine},
+		"mph":  {"mile per hour", "miles per hour", masculine},
+		"km":   {"kilometer", "kilometers", masculine},
+		"m":    {"meter", "meters", masculine},
+		"cm":   {"centimeter", "centimeters", masculine},
+		"mm":   {"millimeter", "millimeters", masculine},
+		"mi":   {"mile", "miles", masculine},
+		"ft":   {"foot", "feet", masculine},
+		"kg":   {"kilogram", "kilograms", masculine},
+		"g":    {"gram", "grams", masculine},
+		"mg":   {"milligram", "milligrams", masculine},
+		"lb":   {"pound", "pounds", masculine},
+		"lbs":  {"pound", "pounds", masculine},
+		"oz":   {"ounce", "ounces", masculine},
+		"l":    {"liter", "liters", masculine},
+		"L":    {"liter", "liters", masculine},
+		"ml":   {"milliliter", "milliliters", masculine},
+		"mL":   {"milliliter", "milliliters", masculine},
+		"h":    {"hour", "hours", masculine},
+		"min":  {"minute", "minutes", masculine},
+		"s":    {"second", "seconds", masculine},
+		"ms":   {"millisecond", "milliseconds", masculine},
+		"Hz":   {"hertz", "hertz", masculine},
+		"kHz":  {"kilohertz", "kilohertz", masculine},
+		"MHz":  {"megahertz", "megahertz", masculine},
+		"GHz":  {"gigahertz", "gigahertz", masculine},
+		"W":    {"watt", "watts", masculine},
+		"kW":   {"kilowatt", "kilowatts", masculine},
+		"kWh":  {"kilowatt hour", "kilowatt hours", masculine},
+		"KB":   {"kilobyte", "kilobytes", masculine},
+		"kB":   {"kilobyte", "kilobytes", masculine},
+		"MB":   {"megabyte", "megabytes", masculine},
+		"GB":   {"gigabyte", "gigabytes", masculine},
+		"TB":   {"terabyte", "terabytes", masculine},
+		"°C":   {"degree Celsius", "degrees Celsius", masculine},
+		"°F":   {"degree Fahrenheit", "degrees Fahrenheit", masculine},
+		"°":    {"degree", "degrees", masculine},
+		"%":    {"percent", "percent", masculine},
+	},
+	abbreviations: map[string]string{
+		"Dr.":     "Doctor",
+		"Mr.":     "Mister",
+		"Mrs.":    "Missus",
+		"Ms.":     "Miz",
+		"Prof.":   "Professor",
+		"Jr.":     "Junior",
+		"Sr.":     "Senior",
+		"Mt.":     "Mount",
+		"Ave.":    "Avenue",
+		"Rd.":     "Road",
+		"Blvd.":   "Boulevard",
+		"Inc.":    "Incorporated",
+		"Ltd.":    "Limited",
+		"Co.":     "Company",
+		"etc.":    "et cetera",
+		"e.g.":    "for example",
+		"i.e.":    "that is",
+		"vs.":     "versus",
+		"approx.": "approximately",
+	},
+	symbols: map[rune]string{
+		'.': "dot",
+		'/': "slash",
+		':': "colon",
+		'-': "dash",
+		'_': "underscore",
+		'?': "question mark",
+		'=': "equals",
+		'&': "and",
+		'#': "hash",
+		'%': "percent",
+		'~': "tilde",
+		'+': "plus",
+		'@': "at",
+	},
+	extra: func(l *language) []Rule {
+		months := strings.Join(enMonths[:], "|")
+		return []Rule{
+			// October 16th, 2026, or October 16.
+			MustRule(`\b(`+months+`)\s+(\d{1,2})(?:st|nd|rd|th)?(?:,?\s+(\d{4}))?\b`, func(m *Match) (string, bool) {
+				d, _ := strconv.Atoi(m.Group(2))
+				if d < 1 || d > 31 {
+					return "", false
+				}
+				s := m.Group(1) + " " + enOrdinal(int64(d), standalone)
+				if y := m.Group(3); y != "" {
+					year, _ := strconv.Atoi(y)
+					s += ", " + enYear(year)
+				}
+				return s, true
+			}),
+			// 14:30, 2:05 pm, 9:00 a.m.
+			MustRule(`\b([01]?\d|2[0-3]):([0-5]\d)(?:\s?([AaPp])\.?[Mm]\b\.?)?`, func(m *Match) (string, bool) {
+				h, _ := strconv.Atoi(m.Group(1))
+				min, _ := strconv.Atoi(m.Group(2))
+				return enTime(h, min, m.Group(3)), true
+			}),
+			// 9am, 10 p.m.
+			MustRule(`\b(1[0-2]|0?[1-9])\s?([AaPp])\.?[Mm]\b\.?`, func(m *Match) (string, bool) {
+				h, _ := strconv.Atoi(m.Group(1))
+				return enTime(h, 0, m.Group(2)), true
+			}),
+			// Saint before a name, Street after one.
+			MustRule(`\bSt\.`, func(m *Match) (string, bool) {
+				prev := strings.TrimRightFunc(m.Preceding(), unicode.IsSpace)
+				_, prevWord := lastWord(prev)
+				next, _ := utf8.DecodeRuneInString(strings.TrimLeftFunc(m.Following(), unicode.IsSpace))
+				if unicode.IsUpper(next) && !startsUpper(prevWord) {
+					return "Saint", true
+				}
+				// "Main St. Visit", the period ends the sentence.
+				if unicode.IsUpper(next) {
+					return "Street.", true
+				}
+				return endSentence(m, "Street"), true
+			}),
+			// No. 5
+			MustRule(`\b[Nn]o\.`, func(m *Match) (string, bool) {
+				next, _ := utf8.DecodeRuneInString(strings.TrimLeft(m.Following(), " "))
+				if next < '0' || next > '9' {
+					return "", false
+				}
+				return "number", true
+			}),
+		}
+	},
+}
+
+func startsUpper(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsUpper(r)
+}
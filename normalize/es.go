@@ -0,0 +1,298 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package normalize
+
+import (
+	"strconv"
+	"strings"
+)
+
+var (
+	esUnits = [...]string{
+		"cero", "uno", "dos", "tres", "cuatro", "cinco", "seis", "siete", "ocho", "nueve",
+		"diez", "once", "doce", "trece", "catorce", "quince", "dieciséis", "diecisiete",
+		"dieciocho", "diecinueve", "veinte", "veintiuno", "veintidós", "veintitrés",
+		"veinticuatro", "veinticinco", "veintiséis", "veintisiete", "veintiocho",
+		"veintinueve",
+	}
+	esTens = [...]string{
+		"", "", "", "treinta", "cuarenta", "cincuenta", "sesenta", "setenta", "ochenta", "noventa",
+	}
+	esHundreds = [...]string{
+		"", "ciento", "doscientos", "trescientos", "cuatrocientos", "quinientos",
+		"seiscientos", "setecientos", "ochocientos", "novecientos",
+	}
+	esOrdinalUnits = [...]string{
+		"", "primero", "segundo", "tercero", "cuarto", "quinto", "sexto", "séptimo",
+		"octavo", "noveno",
+	}
+	esOrdinalTens = [...]string{
+		"", "décimo", "vigésimo", "trigésimo", "cuadragésimo", "quincuagésimo",
+		"sexagésimo", "septuagésimo", "octogésimo", "nonagésimo",
+	}
+	esOrdinalHundreds = [...]string{
+		"", "centésimo", "ducentésimo", "tricentésimo", "cuadringentésimo",
+		"quingentésimo", "sexcentésimo", "septingentésimo", "octingentésimo",
+		"noningentésimo",
+	}
+	// esOrdinalTeens 11th to 19th, written as one word.
+	esOrdinalTeens = [...]string{
+		"", "undécimo", "duodécimo", "decimotercero", "decimocuarto", "decimoquinto",
+		"decimosexto", "decimoséptimo", "decimoctavo", "decimonoveno",
+	}
+	esMonths = [...]string{
+		"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto",
+		"septiembre", "octubre", "noviembre", "diciembre",
+	}
+)
+
+// esBelow100 spells n < 100. Before a masculine noun, uno becomes un, and
+// una before a feminine one.
+func esBelow100(n int, f form) string {
+	var w string
+	switch {
+	case n < 30:
+		w = esUnits[n]
+	case n%10 == 0:
+		w = esTens[n/10]
+	default:
+		w = esTens[n/10] + " y " + esUnits[n%10]
+	}
+	if strings.HasSuffix(w, "uno") {
+		switch f {
+		case masculine:
+			w = strings.TrimSuffix(w, "uno") + "un"
+			if w == "veintiun" {
+				w = "veintiún"
+			}
+		case feminine:
+			w = strings.TrimSuffix(w, "uno") + "una"
+		}
+	}
+	return w
+}
+
+func esBelow1000(n int, f form) string {
+	if n == 100 {
+		return "cien"
+	}
+	var words []string
+	if h := n / 100; h > 0 {
+		w := esHundreds[h]
+		if f == feminine && h > 1 {
+			w = strings.TrimSuffix(w, "os") + "as"
+		}
+		words = append(words, w)
+	}
+	if r := n % 100; r > 0 || n == 0 {
+		words = append(words, esBelow100(r, f))
+	}
+	return strings.Join(words, " ")
+}
+
+func esBelowMillion(n int, f form) string {
+	var words []string
+	switch th := n / 1000; {
+	case th == 1:
+		words = append(words, "mil")
+	case th > 1:
+		// "veintiún mil", even on its own.
+		tf := masculine
+		if f == feminine {
+			tf = feminine
+		}
+		words = append(words, esBelow1000(th, tf), "mil")
+	}
+	if r := n % 1000; r > 0 {
+		words = append(words, esBelow1000(r, f))
+	}
+	return strings.Join(words, " ")
+}
+
+// esCardinal spells n in the long scale, a billón being a million
+// millions.
+func esCardinal(n int64, f form) string {
+	if n == 0 {
+		return esUnits[0]
+	}
+	var words []string
+	for _, scale := range []struct {
+		size     int64
+		one, any string
+	}{
+		{1e12, "billón", "billones"},
+		{1e6, "millón", "millones"},
+	} {
+		switch g := n / scale.size % 1e6; {
+		case g == 1:
+			words = append(words, "un", scale.one)
+		case g > 1:
+			words = append(words, esBelowMillion(int(g), masculine), scale.any)
+		}
+	}
+	if r := n % 1e6; r > 0 {
+		words = append(words, esBelowMillion(int(r), f))
+	}
+	return strings.Join(words, " ")
+}
+
+// esOrdinal spells ordinals up to 999, larger ones are read as cardinals.
+func esOrdinal(n int64, f form) string {
+	if n < 1 || n > 999 {
+		return esCardinal(n, f)
+	}
+	var words []string
+	if h := n / 100; h > 0 {
+		words = append(words, esOrdinalHundreds[h])
+	}
+	switch t, u := n/10%10, n%10; {
+	case t == 1 && u > 0:
+		words = append(words, esOrdinalTeens[u])
+	default:
+		if t > 0 {
+			words = append(words, esOrdinalTens[t])
+		}
+		if u > 0 {
+			words = append(words, esOrdinalUnits[u])
+		}
+	}
+	if f == feminine {
+		for i, w := range words {
+			words[i] = strings.TrimSuffix(w, "o") + "a"
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func esDate(y, m, d int) string {
+	return esCardinal(int64(d), standalone) + " de " + esMonths[m-1] + " de " + esCardinal(int64(y), standalone)
+}
+
+// esTime reads h:mm as "catorce y treinta", "nueve en punto", or "cero
+// horas".
+func esTime(h, m int) string {
+	if h == 0 && m == 0 {
+		return "cero horas"
+	}
+	hours := esCardinal(int64(h), feminine)
+	if m == 0 {
+		return hours + " en punto"
+	}
+	return hours + " y " + esCardinal(int64(m), standalone)
+}
+
+var spanish = &language{
+	cardinal: esCardinal,
+	ordinal:  esOrdinal,
+	singular: func(num number) bool {
+		return num.digits == "1" && num.frac == ""
+	},
+	point: "coma",
+	minus: "menos",
+	// a period or a space separates thousands, though a period separates
+	// decimals in some countries, e.g. 3.5, where it doesn't read as
+	// thousands.
+	groupSeps:   ". \u00a0",
+	decimalSeps: ",.",
+	and:         "con",
+	of:          "de",
+	date:        esDate,
+	dayFirst:    true,
+	ordinalExpr: `\b(\d+)\.?([ºª])`,
+	ordinalForm: func(suffix string) form {
+		if suffix == "ª" {
+			return feminine
+		}
+		return masculine
+	},
+	currencies: map[string]currency{
+		"USD": {noun{"dólar", "dólares", masculine}, noun{"centavo", "centavos", masculine}},
+		"EUR": {noun{"euro", "euros", masculine}, noun{"céntimo", "céntimos", masculine}},
+		"GBP": {noun{"libra", "libras", feminine}, noun{"penique", "peniques", masculine}},
+		"JPY": {noun: noun{"yen", "yenes", masculine}},
+	},
+	units: map[string]noun{
+		"km/h": {"kilómetro por hora", "kilómetros por hora", masculine},
+		"m/s":  {"metro por segundo", "metros por segundo", masculine},
+		"km":   {"kilómetro", "kilómetros", masculine},
+		"m":    {"metro", "metros", masculine},
+		"cm":   {"centímetro", "centímetros", masculine},
+		"mm":   {"milímetro", "milímetros", masculine},
+		"kg":   {"kilogramo", "kilogramos", masculine},
+		"g":    {"gramo", "gramos", masculine},
+		"mg":   {"miligramo", "miligramos", masculine},
+		"l":    {"litro", "litros", masculine},
+		"L":    {"litro", "litros", masculine},
+		"ml":   {"mililitro", "mililitros", masculine},
+		"h":    {"hora", "horas", feminine},
+		"min":  {"minuto", "minutos", masculine},
+		"s":    {"segundo", "segundos", masculine},
+		"ms":   {"milisegundo", "milisegundos", masculine},
+		"Hz":   {"hercio", "hercios", masculine},
+		"kHz":  {"kilohercio", "kilohercios", masculine},
+		"MHz":  {"megahercio", "megahercios", masculine},
+		"GHz":  {"gigahercio", "gigahercios", masculine},
+		"W":    {"vatio", "vatios", masculine},
+		"kW":   {"kilovatio", "kilovatios", masculine},
+		"kWh":  {"kilovatio hora", "kilovatios hora", masculine},
+		"KB":   {"kilobyte", "kilobytes", masculine},
+		"kB":   {"kilobyte", "kilobytes", masculine},
+		"MB":   {"megabyte", "megabytes", masculine},
+		"GB":   {"gigabyte", "gigabytes", masculine},
+		"TB":   {"terabyte", "terabytes", masculine},
+		"°C":   {"grado centígrado", "grados centígrados", masculine},
+		"°F":   {"grado Fahrenheit", "grados Fahrenheit", masculine},
+		"°":    {"grado", "grados", masculine},
+		"%":    {"por ciento", "por ciento", masculine},
+	},
+	abbreviations: map[string]string{
+		"Sr.":     "señor",
+		"Sra.":    "señora",
+		"Srta.":   "señorita",
+		"Dr.":     "doctor",
+		"Dra.":    "doctora",
+		"Lic.":    "licenciado",
+		"Ing.":    "ingeniero",
+		"Ud.":     "usted",
+		"Uds.":    "ustedes",
+		"Vd.":     "usted",
+		"etc.":    "etcétera",
+		"pág.":    "página",
+		"págs.":   "páginas",
+		"Av.":     "avenida",
+		"Avda.":   "avenida",
+		"aprox.":  "aproximadamente",
+		"núm.":    "número",
+		"nº":      "número",
+		"n.º":     "número",
+		"p. ej.":  "por ejemplo",
+		"EE. UU.": "Estados Unidos",
+		"EE.UU.":  "Estados Unidos",
+	},
+	symbols: map[rune]string{
+		'.': "punto",
+		'/': "barra",
+		':': "dos puntos",
+		'-': "guion",
+		'_': "guion bajo",
+		'?': "interrogación",
+		'=': "igual",
+		'&': "y",
+		'#': "almohadilla",
+		'%': "por ciento",
+		'~': "virgulilla",
+		'+': "más",
+		'@': "arroba",
+	},
+	extra: func(l *language) []Rule {
+		return []Rule{
+			// 14:30
+			MustRule(`\b([01]?\d|2[0-3]):([0-5]\d)\b`, func(m *Match) (string, bool) {
+				h, _ := strconv.Atoi(m.Group(1))
+				min, _ := strconv.Atoi(m.Group(2))
+				return esTime(h, min), true
+			}),
+		}
+	},
+}
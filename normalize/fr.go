@@ -0,0 +1,256 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package normalize
+
+import (
+	"strconv"
+	"strings"
+)
+
+var (
+	frUnits = [...]string{
+		"zéro", "un", "deux", "trois", "quatre", "cinq", "six", "sept", "huit", "neuf",
+		"dix", "onze", "douze", "treize", "quatorze", "quinze", "seize", "dix-sept",
+		"dix-huit", "dix-neuf",
+	}
+	// frTens 70 and 90 are counted from 60 and 80: soixante-dix,
+	// quatre-vingt-dix.
+	frTens = [...]string{
+		"", "", "vingt", "trente", "quarante", "cinquante", "soixante", "soixante",
+		"quatre-vingt", "quatre-vingt",
+	}
+	frMonths = [...]string{
+		"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août",
+		"septembre", "octobre", "novembre", "décembre",
+	}
+)
+
+// frBelow100 spells n < 100. Quatre-vingts takes an s when nothing
+// follows, if last.
+func frBelow100(n int, last bool) string {
+	if n < 20 {
+		return frUnits[n]
+	}
+	t, u := n/10, n%10
+	if t == 7 || t == 9 {
+		u += 10
+	}
+	switch {
+	case u == 0 && t == 8 && last:
+		return "quatre-vingts"
+	case u == 0:
+		return frTens[t]
+	case (u == 1 || u == 11) && t < 8:
+		// vingt et un, soixante et onze.
+		return frTens[t] + " et " + frUnits[u]
+	default:
+		return frTens[t] + "-" + frUnits[u]
+	}
+}
+
+// frBelow1000 spells n < 1000. Cents takes an s when nothing follows, if
+// last.
+func frBelow1000(n int, last bool) string {
+	var words []string
+	h, r := n/100, n%100
+	switch {
+	case h == 1:
+		words = append(words, "cent")
+	case h > 1 && r == 0 && last:
+		words = append(words, frUnits[h], "cents")
+	case h > 1:
+		words = append(words, frUnits[h], "cent")
+	}
+	if r > 0 || n == 0 {
+		words = append(words, frBelow100(r, last))
+	}
+	return strings.Join(words, " ")
+}
+
+func frCardinal(n int64, f form) string {
+	var words []string
+	switch g := n / 1e9; {
+	case g == 1:
+		words = append(words, "un milliard")
+	case g > 1 && g < 1000:
+		words = append(words, frBelow1000(int(g), true), "milliards")
+	case g >= 1000:
+		words = append(words, frCardinal(g, masculine), "milliards")
+	}
+	switch g := n / 1e6 % 1000; {
+	case g == 1:
+		words = append(words, "un million")
+	case g > 1:
+		words = append(words, frBelow1000(int(g), true), "millions")
+	}
+	switch g := n / 1000 % 1000; {
+	case g == 1:
+		words = append(words, "mille")
+	case g > 1:
+		// no s before mille: quatre-vingt mille, deux cent mille.
+		words = append(words, frBelow1000(int(g), false), "mille")
+	}
+	if r := n % 1000; r > 0 || n == 0 {
+		words = append(words, frBelow1000(int(r), true))
+	}
+	s := strings.Join(words, " ")
+	if f == feminine && (s == "un" || strings.HasSuffix(s, " un") || strings.HasSuffix(s, "-un")) {
+		s += "e"
+	}
+	return s
+}
+
+func frOrdinal(n int64, f form) string {
+	if n == 1 {
+		if f == feminine {
+			return "première"
+		}
+		return "premier"
+	}
+	head, last := lastWord(frCardinal(n, masculine))
+	switch last {
+	case "un":
+		last = "unième"
+	case "cinq":
+		last = "cinquième"
+	case "neuf":
+		last = "neuvième"
+	default:
+		last = strings.TrimSuffix(strings.TrimSuffix(last, "s"), "e") + "ième"
+	}
+	return head + last
+}
+
+func frDate(y, m, d int) string {
+	day := frCardinal(int64(d), masculine)
+	if d == 1 {
+		day = "premier"
+	}
+	return day + " " + frMonths[m-1] + " " + frCardinal(int64(y), masculine)
+}
+
+// frTime reads h:mm as "quatorze heures trente".
+func frTime(h, m int) string {
+	s := frCardinal(int64(h), feminine) + " heures"
+	if h < 2 {
+		s = strings.TrimSuffix(s, "s")
+	}
+	if m > 0 {
+		s += " " + frCardinal(int64(m), feminine)
+	}
+	return s
+}
+
+var french = &language{
+	cardinal: frCardinal,
+	ordinal:  frOrdinal,
+	// below 2, a noun is singular: 1,5 kilomètre.
+	singular: func(num number) bool {
+		n, ok := num.value()
+		return ok && n < 2
+	},
+	point:       "virgule",
+	minus:       "moins",
+	groupSeps:   " \u00a0\u202f",
+	decimalSeps: ",",
+	and:         "et",
+	of:          "de",
+	date:        frDate,
+	dayFirst:    true,
+	ordinalExpr: `\b(\d+)(ère|ème|er|re|nde|nd|e)`,
+	ordinalForm: func(suffix string) form {
+		switch suffix {
+		case "re", "ère", "nde":
+			return feminine
+		}
+		return masculine
+	},
+	currencies: map[string]currency{
+		"USD": {noun{"dollar", "dollars", masculine}, noun{"cent", "cents", masculine}},
+		"EUR": {noun{"euro", "euros", masculine}, noun{"centime", "centimes", masculine}},
+		"GBP": {noun{"livre", "livres", feminine}, noun{"penny", "pence", masculine}},
+		"JPY": {noun: noun{"yen", "yens", masculine}},
+	},
+	units: map[string]noun{
+		"km/h": {"kilomètre par heure", "kilomètres par heure", masculine},
+		"m/s":  {"mètre par seconde", "mètres par seconde", masculine},
+		"km":   {"kilomètre", "kilomètres", masculine},
+		"m":    {"mètre", "mètres", masculine},
+		"cm":   {"centimètre", "centimètres", masculine},
+		"mm":   {"millimètre", "millimètres", masculine},
+		"kg":   {"kilogramme", "kilogrammes", masculine},
+		"g":    {"gramme", "grammes", masculine},
+		"mg":   {"milligramme", "milligrammes", masculine},
+		"l":    {"litre", "litres", masculine},
+		"L":    {"litre", "litres", masculine},
+		"ml":   {"millilitre", "millilitres", masculine},
+		"min":  {"minute", "minutes", feminine},
+		"s":    {"seconde", "secondes", feminine},
+		"ms":   {"milliseconde", "millisecondes", feminine},
+		"Hz":   {"hertz", "hertz", masculine},
+		"kHz":  {"kilohertz", "kilohertz", masculine},
+		"MHz":  {"mégahertz", "mégahertz", masculine},
+		"GHz":  {"gigahertz", "gigahertz", masculine},
+		"W":    {"watt", "watts", masculine},
+		"kW":   {"kilowatt", "kilowatts", masculine},
+		"kWh":  {"kilowattheure", "kilowattheures", masculine},
+		"Ko":   {"kilooctet", "kilooctets", masculine},
+		"Mo":   {"mégaoctet", "mégaoctets", masculine},
+		"Go":   {"gigaoctet", "gigaoctets", masculine},
+		"To":   {"téraoctet", "téraoctets", masculine},
+		"°C":   {"degré Celsius", "degrés Celsius", masculine},
+		"°F":   {"degré Fahrenheit", "degrés Fahrenheit", masculine},
+		"°":    {"degré", "degrés", masculine},
+		"%":    {"pour cent", "pour cent", masculine},
+	},
+	abbreviations: map[string]string{
+		"M.":     "monsieur",
+		"MM.":    "messieurs",
+		"Mme":    "madame",
+		"Mmes":   "mesdames",
+		"Mlle":   "mademoiselle",
+		"Mlles":  "mesdemoiselles",
+		"Dr":     "docteur",
+		"Dr.":    "docteur",
+		"Pr":     "professeur",
+		"Pr.":    "professeur",
+		"St":     "saint",
+		"St.":    "saint",
+		"Ste":    "sainte",
+		"Ste.":   "sainte",
+		"etc.":   "et cetera",
+		"p. ex.": "par exemple",
+		"env.":   "environ",
+		"av.":    "avenue",
+		"bd":     "boulevard",
+		"bd.":    "boulevard",
+		"n°":     "numéro",
+		"nº":     "numéro",
+	},
+	symbols: map[rune]string{
+		'.': "point",
+		'/': "slash",
+		':': "deux-points",
+		'-': "tiret",
+		'_': "tiret bas",
+		'?': "point d'interrogation",
+		'=': "égal",
+		'&': "et",
+		'#': "dièse",
+		'%': "pour cent",
+		'~': "tilde",
+		'+': "plus",
+		'@': "arobase",
+	},
+	extra: func(l *language) []Rule {
+		return []Rule{
+			// 14h30, 14 h 30, 14:30, 9h
+			MustRule(`\b([01]?\d|2[0-3])(?::([0-5]\d)\b|\s?h\s?([0-5]\d)\b|\s?h\b)`, func(m *Match) (string, bool) {
+				h, _ := strconv.Atoi(m.Group(1))
+				min, _ := strconv.Atoi(m.Group(2) + m.Group(3))
+				return frTime(h, min), true
+			}),
+		}
+	},
+}
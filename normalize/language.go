@@ -0,0 +1,438 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package normalize
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/djangulo/go-espeak/internal/lang"
+)
+
+// form of a numeral: on its own, or counting a noun of a gender, e.g.
+// "uno", "un kilómetro", "una hora" in es.
+type form int
+
+const (
+	standalone form = iota
+	masculine
+	feminine
+)
+
+// noun the singular and plural of a noun, and its gender.
+type noun struct {
+	one, other string
+	gender     form
+}
+
+// currency a currency's unit, and its hundredth, if any.
+type currency struct {
+	noun
+	cent noun
+}
+
+// maxCardinal numbers as large or larger are read digit by digit.
+const maxCardinal = 1e15
+
+// symbolCurrencies ISO codes of the currency symbols.
+var symbolCurrencies = map[string]string{
+	"$": "USD",
+	"€": "EUR",
+	"£": "GBP",
+	"¥": "JPY",
+}
+
+// language the words and rules of a language.
+type language struct {
+	// cardinal spells n, 0 <= n < maxCardinal.
+	cardinal func(n int64, f form) string
+	// ordinal spells the ordinal of n, n >= 0.
+	ordinal func(n int64, f form) string
+	// singular returns whether a noun counted by num is singular.
+	singular func(num number) bool
+	// point read at the decimal separator, minus before negative numbers.
+	point, minus string
+	// fractionDigits the decimals are read digit by digit, rather than as a
+	// number.
+	fractionDigits bool
+	// groupSeps separate thousands, decimalSeps the decimals.
+	groupSeps, decimalSeps string
+	// and joins a currency unit and its cents.
+	and string
+	// of comes between exact millions and a noun, e.g. "un millón de
+	// euros".
+	of string
+	// date spells a date, month counted from 1.
+	date func(year, month, day int) string
+	// dayFirst numeric dates are day/month/year, rather than month/day/year.
+	dayFirst bool
+	// ordinalExpr matches an ordinal, its number and suffix as submatches.
+	ordinalExpr string
+	// ordinalForm returns the form of an ordinal from its suffix.
+	ordinalForm func(suffix string) form
+
+	currencies    map[string]currency
+	units         map[string]noun
+	abbreviations map[string]string
+	// symbols words read for the punctuation of URLs and addresses.
+	symbols map[rune]string
+	// extra rules of the language, preceding the common ones.
+	extra func(l *language) []Rule
+
+	numRe *regexp.Regexp
+	rules []Rule
+}
+
+var languages = map[string]*language{
+	"en": build(english),
+	"es": build(spanish),
+	"fr": build(french),
+}
+
+// lookup returns the language of a language tag, or of its primary
+// language, nil if there's none.
+func lookup(tag string) *language {
+	if l, ok := languages[tag]; ok {
+		return l
+	}
+	if i := strings.IndexByte(tag, '-'); i > 0 {
+		return languages[tag[:i]]
+	}
+	return nil
+}
+
+// numberExpr returns the expression of a number of l, capturing its sign,
+// integer and decimal digits if capture.
+func (l *language) numberExpr(capture bool) string {
+	integer := `\d{1,3}(?:[` + l.groupSeps + `]\d{3})+|\d+`
+	if capture {
+		return `([-−]?)(` + integer + `)(?:[` + l.decimalSeps + `](\d+))?`
+	}
+	return `[-−]?(?:` + integer + `)(?:[` + l.decimalSeps + `]\d+)?`
+}
+
+// amountExpr returns the expression of an amount of money: a number with
+// the thousands separated by a period, comma, or any of l's, and cents
+// after a period or a comma, whichever l writes numbers with, as "$1,234.50"
+// is read in es too.
+func (l *language) amountExpr() string {
+	return `[-−]?(?:\d{1,3}(?:[,.` + l.groupSeps + `]\d{3})+|\d+)(?:[.,]\d{1,2}\b)?`
+}
+
+// build compiles the rules of l.
+func build(l *language) *language {
+	l.numRe = regexp.MustCompile("^" + l.numberExpr(true) + "$")
+	num := l.numberExpr(false)
+	amount := l.amountExpr()
+
+	codes := make([]string, 0, len(l.currencies))
+	for c := range l.currencies {
+		codes = append(codes, c)
+	}
+	sort.Strings(codes)
+	symbols := `[$€£¥]|\b(?:` + strings.Join(codes, "|") + `)\b`
+
+	units := make([]string, 0, len(l.units))
+	for u := range l.units {
+		units = append(units, u)
+	}
+	sort.Slice(units, func(i, j int) bool {
+		if len(units[i]) != len(units[j]) {
+			return len(units[i]) > len(units[j])
+		}
+		return units[i] < units[j]
+	})
+	for i, u := range units {
+		units[i] = regexp.QuoteMeta(u)
+	}
+
+	l.rules = []Rule{
+		MustRule(`(?:https?://|www\.)[^\s<>"'\[\]]*[^\s<>"'\[\].,;:!?)]`, l.expandAddress),
+		MustRule(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`, l.expandAddress),
+		MustRule(`\b(\d{4})-(\d{2})-(\d{2})\b`, func(m *Match) (string, bool) {
+			return l.expandDate(m.Group(1), m.Group(2), m.Group(3))
+		}),
+		MustRule(`\b(\d{1,2})/(\d{1,2})/(\d{4})\b`, func(m *Match) (string, bool) {
+			if l.dayFirst {
+				return l.expandDate(m.Group(3), m.Group(2), m.Group(1))
+			}
+			return l.expandDate(m.Group(3), m.Group(1), m.Group(2))
+		}),
+	}
+	if l.extra != nil {
+		l.rules = append(l.rules, l.extra(l)...)
+	}
+	l.rules = append(l.rules,
+		MustRule(`(`+symbols+`)\s?(`+amount+`)`, func(m *Match) (string, bool) {
+			return l.expandAmount(m.Group(2), m.Group(1))
+		}),
+		MustRule(`(`+amount+`)\s?(`+symbols+`)`, func(m *Match) (string, bool) {
+			return l.expandAmount(m.Group(1), m.Group(2))
+		}),
+		MustRule(`(`+num+`)\s?(`+strings.Join(units, "|")+`)`, func(m *Match) (string, bool) {
+			unit := m.Group(2)
+			if last, _ := utf8.DecodeLastRuneInString(unit); lang.IsWordRune(last) && lang.IsWordRune(m.After()) {
+				return "", false
+			}
+			num, ok := l.parse(m.Group(1))
+			if !ok {
+				return "", false
+			}
+			return l.counted(num, l.units[unit]), true
+		}),
+		MustRule(l.ordinalExpr, func(m *Match) (string, bool) {
+			if lang.IsWordRune(m.After()) {
+				return "", false
+			}
+			n, err := strconv.ParseInt(m.Group(1), 10, 64)
+			if err != nil {
+				return "", false
+			}
+			return l.spellOrdinal(n, l.ordinalForm(m.Group(2))), true
+		}),
+		MustRule(num, func(m *Match) (string, bool) {
+			num, ok := l.parse(m.String())
+			if !ok {
+				return "", false
+			}
+			// a hyphen, as in "COVID-19", rather than a minus.
+			if num.neg && lang.IsWordRune(m.Before()) {
+				num.neg = false
+			}
+			return l.spellNumber(num, standalone), true
+		}),
+		// decimals with no integer part, as in ".5", but not the end of a
+		// sentence, or of a number, running into one.
+		MustRule(`([-−]?)[`+l.decimalSeps+`](\d+)`, func(m *Match) (string, bool) {
+			if before := m.Before(); lang.IsWordRune(before) || strings.ContainsRune(l.decimalSeps, before) {
+				return "", false
+			}
+			return l.spellNumber(number{neg: m.Group(1) != "", frac: m.Group(2)}, standalone), true
+		}),
+		Abbreviations(l.abbreviations),
+	)
+	return l
+}
+
+// number a numeral, as written.
+type number struct {
+	neg bool
+	// digits of the integer part, if any, frac of the decimals.
+	digits, frac string
+}
+
+// value returns the integer part of num, false if it's too large to be
+// read as a number.
+func (num number) value() (int64, bool) {
+	n, err := strconv.ParseInt(num.digits, 10, 64)
+	if err != nil || n >= maxCardinal {
+		return 0, false
+	}
+	return n, true
+}
+
+// parse reads a number of l.
+func (l *language) parse(s string) (number, bool) {
+	sub := l.numRe.FindStringSubmatch(s)
+	if sub == nil {
+		return number{}, false
+	}
+	return number{neg: sub[1] != "", digits: onlyDigits(sub[2]), frac: sub[3]}, true
+}
+
+// parseAmount reads an amount of money, as matched by amountExpr. A period
+// or comma followed by one or two digits is the decimal separator.
+func parseAmount(s string) (number, bool) {
+	var num number
+	for _, minus := range []string{"-", "−"} {
+		if strings.HasPrefix(s, minus) {
+			num.neg, s = true, s[len(minus):]
+		}
+	}
+	if i := strings.LastIndexAny(s, ".,"); i >= 0 && len(s)-i <= 3 {
+		num.frac, s = s[i+1:], s[:i]
+	}
+	num.digits = onlyDigits(s)
+	return num, num.digits != ""
+}
+
+// onlyDigits returns the digits of s.
+func onlyDigits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// signed spells n, negative or not.
+func (l *language) signed(n int64, f form) string {
+	num := number{digits: strconv.FormatInt(n, 10)}
+	if n < 0 {
+		num.neg, num.digits = true, num.digits[1:]
+	}
+	return l.spellNumber(num, f)
+}
+
+// spellOrdinal spells the ordinal of n, of form f. Those too large to
+// spell are read as cardinals, digit by digit.
+func (l *language) spellOrdinal(n int64, f form) string {
+	if n <= -maxCardinal || n >= maxCardinal {
+		return l.signed(n, standalone)
+	}
+	if n < 0 {
+		return l.minus + " " + l.ordinal(-n, f)
+	}
+	return l.ordinal(n, f)
+}
+
+// spellNumber spells num, as counting a noun of form f.
+func (l *language) spellNumber(num number, f form) string {
+	var words []string
+	if num.neg {
+		words = append(words, l.minus)
+	}
+	if num.frac != "" {
+		f = standalone
+	}
+	if n, ok := num.value(); ok {
+		words = append(words, l.cardinal(n, f))
+	} else if num.digits != "" {
+		words = append(words, l.digits(num.digits))
+	}
+	if num.frac != "" {
+		words = append(words, l.point, l.fraction(num.frac))
+	}
+	return strings.Join(words, " ")
+}
+
+// digits spells s digit by digit.
+func (l *language) digits(s string) string {
+	words := make([]string, 0, len(s))
+	for _, d := range s {
+		words = append(words, l.cardinal(int64(d-'0'), standalone))
+	}
+	return strings.Join(words, " ")
+}
+
+// fraction spells the decimals s, either digit by digit, or its leading
+// zeros followed by the rest as a number, e.g. "cero cinco" for 05 in es.
+func (l *language) fraction(s string) string {
+	if l.fractionDigits {
+		return l.digits(s)
+	}
+	rest := strings.TrimLeft(s, "0")
+	if rest == "" || len(rest) > 3 {
+		return l.digits(s)
+	}
+	var words []string
+	if zeros := len(s) - len(rest); zeros > 0 {
+		words = append(words, l.digits(s[:zeros]))
+	}
+	n, _ := strconv.ParseInt(rest, 10, 64)
+	return strings.Join(append(words, l.cardinal(n, standalone)), " ")
+}
+
+// counted spells num followed by nn, in singular or plural.
+func (l *language) counted(num number, nn noun) string {
+	words := l.spellNumber(num, nn.gender)
+	if n, ok := num.value(); ok && num.frac == "" && l.of != "" && n >= 1e6 && n%1e6 == 0 {
+		words += " " + l.of
+	}
+	if l.singular(num) {
+		return words + " " + nn.one
+	}
+	return words + " " + nn.other
+}
+
+// expandAmount spells the amount s in the currency of symbol, a symbol or
+// an ISO code.
+func (l *language) expandAmount(s, symbol string) (string, bool) {
+	code := symbol
+	if c, ok := symbolCurrencies[symbol]; ok {
+		code = c
+	}
+	c, ok := l.currencies[code]
+	if !ok {
+		return "", false
+	}
+	num, ok := parseAmount(s)
+	if !ok {
+		return "", false
+	}
+	if _, ok := num.value(); !ok || len(num.frac) > 2 || (num.frac != "" && c.cent.one == "") {
+		return l.counted(num, c.noun), true
+	}
+	cents := int64(0)
+	if num.frac != "" {
+		cents, _ = strconv.ParseInt((num.frac + "0")[:2], 10, 64)
+	}
+	units := num
+	units.frac = ""
+	var words []string
+	if n, _ := units.value(); n > 0 || cents == 0 {
+		words = append(words, l.counted(units, c.noun))
+		units.neg = false
+	}
+	if cents > 0 {
+		if len(words) > 0 {
+			words = append(words, l.and)
+		}
+		words = append(words, l.counted(number{neg: units.neg, digits: strconv.FormatInt(cents, 10)}, c.cent))
+	}
+	return strings.Join(words, " "), true
+}
+
+// expandDate spells a date, as written digits, if valid.
+func (l *language) expandDate(year, month, day string) (string, bool) {
+	y, _ := strconv.Atoi(year)
+	m, _ := strconv.Atoi(month)
+	d, _ := strconv.Atoi(day)
+	if m < 1 || m > 12 || d < 1 || d > 31 {
+		return "", false
+	}
+	return l.date(y, m, d), true
+}
+
+// expandAddress spells a URL or email address, reading its punctuation as
+// words. The http(s) scheme and a trailing slash are left out.
+func (l *language) expandAddress(m *Match) (string, bool) {
+	s := m.String()
+	for _, scheme := range []string{"https://", "http://"} {
+		s = strings.TrimPrefix(s, scheme)
+	}
+	s = strings.TrimSuffix(s, "/")
+	var words []string
+	var run strings.Builder
+	flush := func() {
+		if run.Len() > 0 {
+			words = append(words, run.String())
+			run.Reset()
+		}
+	}
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			run.WriteRune(r)
+			continue
+		}
+		flush()
+		if w, ok := l.symbols[r]; ok {
+			words = append(words, w)
+		}
+	}
+	flush()
+	return strings.Join(words, " "), true
+}
+
+// lastWord splits s before its last word, words being separated by spaces
+// or hyphens.
+func lastWord(s string) (string, string) {
+	i := strings.LastIndexAny(s, " -")
+	return s[:i+1], s[i+1:]
+}
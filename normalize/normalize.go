@@ -0,0 +1,318 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+// Package normalize expands the parts of a text espeak reads poorly, or
+// differently per language, into words: numbers, ordinals, dates, times,
+// currency amounts, units, abbreviations, URLs and email addresses.
+//
+// Rules are included for en, es and fr, matching the predefined voices.
+// Domain rules can be added through the Rule interface, and take
+// precedence over the included ones. Set espeak.Parameters.Normalizer to
+// normalize text before synthesizing it.
+package normalize
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/djangulo/go-espeak/internal/lang"
+	"github.com/djangulo/go-espeak/textmap"
+)
+
+// Errors
+var (
+	// ErrUnknownLanguage there are no rules for the language.
+	ErrUnknownLanguage = errors.New("normalize: unknown language")
+)
+
+// Rule expands the matches of a regular expression into words.
+type Rule interface {
+	// Regexp matched against the text.
+	Regexp() *regexp.Regexp
+	// Expand returns the words read in place of m. Returning false leaves
+	// m as is, for other rules to match.
+	Expand(m *Match) (string, bool)
+}
+
+// Match a match of a Rule's Regexp within the text being normalized.
+type Match struct {
+	text  string
+	index []int
+}
+
+// String returns the matched text.
+func (m *Match) String() string {
+	return m.text[m.index[0]:m.index[1]]
+}
+
+// Group returns submatch i, "" if it didn't participate in the match.
+func (m *Match) Group(i int) string {
+	if 2*i+1 >= len(m.index) || m.index[2*i] < 0 {
+		return ""
+	}
+	return m.text[m.index[2*i]:m.index[2*i+1]]
+}
+
+// Preceding returns the text before the match.
+func (m *Match) Preceding() string {
+	return m.text[:m.index[0]]
+}
+
+// Following returns the text after the match.
+func (m *Match) Following() string {
+	return m.text[m.index[1]:]
+}
+
+// Before returns the rune before the match, 0 at the start of the text.
+func (m *Match) Before() rune {
+	r, _ := utf8.DecodeLastRuneInString(m.Preceding())
+	if r == utf8.RuneError {
+		return 0
+	}
+	return r
+}
+
+// After returns the rune after the match, 0 at the end of the text.
+func (m *Match) After() rune {
+	r, _ := utf8.DecodeRuneInString(m.Following())
+	if r == utf8.RuneError {
+		return 0
+	}
+	return r
+}
+
+// funcRule a Rule made of a regexp and a function.
+type funcRule struct {
+	re     *regexp.Regexp
+	expand func(m *Match) (string, bool)
+}
+
+func (r *funcRule) Regexp() *regexp.Regexp {
+	return r.re
+}
+
+func (r *funcRule) Expand(m *Match) (string, bool) {
+	return r.expand(m)
+}
+
+// NewRule returns a Rule expanding the matches of expr through expand.
+func NewRule(expr string, expand func(m *Match) (string, bool)) (Rule, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &funcRule{re: re, expand: expand}, nil
+}
+
+// MustRule is like NewRule, but panics if expr can't be compiled.
+func MustRule(expr string, expand func(m *Match) (string, bool)) Rule {
+	r, err := NewRule(expr, expand)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Abbreviations returns a Rule replacing the keys of words, matched as
+// whole words and case sensitively, with their values. An abbreviation
+// ending in a period keeps it at the end of the text or a line, where it
+// also ends the sentence.
+func Abbreviations(words map[string]string) Rule {
+	keys := make([]string, 0, len(words))
+	for k := range words {
+		keys = append(keys, k)
+	}
+	// longest first, so "Mmes" isn't read as "Mme" and an "s".
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+	quoted := make([]string, len(keys))
+	for i, k := range keys {
+		quoted[i] = regexp.QuoteMeta(k)
+	}
+	return &funcRule{
+		re: regexp.MustCompile(strings.Join(quoted, "|")),
+		expand: func(m *Match) (string, bool) {
+			s := m.String()
+			if lang.IsWordRune(m.Before()) {
+				return "", false
+			}
+			if last, _ := utf8.DecodeLastRuneInString(s); lang.IsWordRune(last) && lang.IsWordRune(m.After()) {
+				return "", false
+			}
+			return endSentence(m, words[s]), true
+		},
+	}
+}
+
+// endSentence returns the expansion of m, an abbreviation, followed by a
+// period if m ends in one at the end of the text or a line.
+func endSentence(m *Match, expansion string) string {
+	if strings.HasSuffix(m.String(), ".") && (m.After() == 0 || m.After() == '\n') {
+		return expansion + "."
+	}
+	return expansion
+}
+
+// langRule a Rule added for a language.
+type langRule struct {
+	language string
+	rule     Rule
+}
+
+// Normalizer expands text into words, through the rules of its language.
+// It is safe for concurrent use.
+type Normalizer struct {
+	mu     sync.RWMutex
+	custom []langRule
+}
+
+// New returns a *Normalizer with the rules included for en, es and fr.
+func New() *Normalizer {
+	return &Normalizer{}
+}
+
+// Add rules for language, e.g. "en", which covers "en-us", or "en-us".
+// An empty language applies to every language. Added rules take
+// precedence over the included ones, and over those added later.
+func (n *Normalizer) Add(language string, rules ...Rule) {
+	language = lang.Normalize(language)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, r := range rules {
+		n.custom = append(n.custom, langRule{language, r})
+	}
+}
+
+// Rules returns the rules for language, in order of precedence, those added
+// first.
+func (n *Normalizer) Rules(language string) []Rule {
+	language = lang.Normalize(language)
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	var rules []Rule
+	for _, r := range n.custom {
+		if r.language == "" ||
+			r.language == language ||
+			strings.HasPrefix(language, r.language+"-") {
+			rules = append(rules, r.rule)
+		}
+	}
+	if l := lookup(language); l != nil {
+		rules = append(rules, l.rules...)
+	}
+	return rules
+}
+
+// candidate a match of rule, not yet expanded.
+type candidate struct {
+	rule  int
+	index []int
+}
+
+// Normalize expands text, in language, into words. Where matches overlap,
+// the leftmost wins, then that of the rule of highest precedence. Phonemes
+// within [[ ]] are left as is. The returned *textmap.Map maps offsets of
+// out to those of text, it's nil if nothing was expanded.
+func (n *Normalizer) Normalize(text, language string) (out string, m *textmap.Map) {
+	rules := n.Rules(language)
+	protected := phonemeSpans(text)
+	var cands []candidate
+	for i, r := range rules {
+		for _, loc := range r.Regexp().FindAllStringSubmatchIndex(text, -1) {
+			if loc[0] == loc[1] || overlaps(protected, loc[0], loc[1]) {
+				continue
+			}
+			cands = append(cands, candidate{rule: i, index: loc})
+		}
+	}
+	sort.SliceStable(cands, func(i, j int) bool {
+		if cands[i].index[0] != cands[j].index[0] {
+			return cands[i].index[0] < cands[j].index[0]
+		}
+		return cands[i].rule < cands[j].rule
+	})
+
+	var b textmap.Builder
+	pos, expanded := 0, false
+	for _, c := range cands {
+		if c.index[0] < pos {
+			continue
+		}
+		match := &Match{text: text, index: c.index}
+		words, ok := rules[c.rule].Expand(match)
+		if !ok {
+			continue
+		}
+		// keep expansions from running into the surrounding words.
+		if first, _ := utf8.DecodeRuneInString(words); lang.IsWordRune(first) && lang.IsWordRune(match.Before()) {
+			words = " " + words
+		}
+		if last, _ := utf8.DecodeLastRuneInString(words); lang.IsWordRune(last) && lang.IsWordRune(match.After()) {
+			words += " "
+		}
+		b.Keep(text[pos:c.index[0]])
+		b.Replace(match.String(), words)
+		pos = c.index[1]
+		expanded = true
+	}
+	if !expanded {
+		return text, nil
+	}
+	b.Keep(text[pos:])
+	return b.String(), b.Map()
+}
+
+// phonemeSpans returns the [start, end) offsets of the [[ ]] blocks of
+// text.
+func phonemeSpans(text string) [][2]int {
+	var spans [][2]int
+	for off := 0; ; {
+		i := strings.Index(text[off:], "[[")
+		if i < 0 {
+			return spans
+		}
+		start := off + i
+		j := strings.Index(text[start+2:], "]]")
+		if j < 0 {
+			return append(spans, [2]int{start, len(text)})
+		}
+		off = start + 2 + j + 2
+		spans = append(spans, [2]int{start, off})
+	}
+}
+
+func overlaps(spans [][2]int, start, end int) bool {
+	for _, s := range spans {
+		if start < s[1] && s[0] < end {
+			return true
+		}
+	}
+	return false
+}
+
+// Cardinal spells n in language, e.g. "twenty-one" in en.
+func Cardinal(n int64, language string) (string, error) {
+	l := lookup(lang.Normalize(language))
+	if l == nil {
+		return "", fmt.Errorf("%w %q", ErrUnknownLanguage, language)
+	}
+	return l.signed(n, standalone), nil
+}
+
+// Ordinal spells the ordinal of n in language, e.g. "twenty-first" in en.
+func Ordinal(n int64, language string) (string, error) {
+	l := lookup(lang.Normalize(language))
+	if l == nil {
+		return "", fmt.Errorf("%w %q", ErrUnknownLanguage, language)
+	}
+	return l.spellOrdinal(n, masculine), nil
+}
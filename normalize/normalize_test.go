@@ -0,0 +1,202 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package normalize
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestNormalizer_Normalize(t *testing.T) {
+	n := New()
+	for _, tt := range []struct {
+		name, language, text, want string
+	}{
+		{"en number", "en", "It costs 1,234 points.", "It costs one thousand two hundred thirty-four points."},
+		{"en decimal", "en", "3.14", "three point one four"},
+		{"en leading decimal", "en", "only .5 or -.25 left...5", "only point five or minus point two five left...five"},
+		{"en negative", "en", "-4 degrees", "minus four degrees"},
+		{"en hyphen", "en", "COVID-19", "COVID nineteen"},
+		{"en ordinal", "en", "the 21st and 112th", "the twenty-first and one hundred twelfth"},
+		{"en large ordinal", "en", "the 10000000000000000th", "the one zero zero zero zero zero zero zero zero zero zero zero zero zero zero zero zero"},
+		{"en currency", "en", "$1,234.56", "one thousand two hundred thirty-four dollars and fifty-six cents"},
+		{"en cents", "en", "$0.01", "one cent"},
+		{"en european", "en", "€1.234,50", "one thousand two hundred thirty-four euros and fifty cents"},
+		{"en code", "en", "5 EUR", "five euros"},
+		{"en millions", "en", "$2,000,000", "two million dollars"},
+		{"en units", "en", "1 kg and 3.5 km at 90km/h", "one kilogram and three point five kilometers at ninety kilometers per hour"},
+		{"en percent", "en", "45% off", "forty-five percent off"},
+		{"en not a unit", "en", "5 minutes", "five minutes"},
+		{"en iso date", "en", "on 2026-10-16.", "on October sixteenth, twenty twenty-six."},
+		{"en us date", "en", "10/16/1905", "October sixteenth, nineteen oh five"},
+		{"en month date", "en", "October 1st, 2026", "October first, twenty twenty-six"},
+		{"en time", "en", "at 2:05 pm", "at two oh five pee em"},
+		{"en midnight", "en", "from 0:00 to 0:30", "from midnight to zero thirty"},
+		{"en 24 hour time", "en", "at 14:00", "at fourteen hundred"},
+		{"en am", "en", "at 9am", "at nine ay em"},
+		{"en abbreviations", "en", "Dr. Smith, Mrs. Jones", "Doctor Smith, Missus Jones"},
+		{"en abbreviation ends sentence", "en", "apples, pears, etc.", "apples, pears, et cetera."},
+		{"en saint", "en", "St. Louis", "Saint Louis"},
+		{"en street", "en", "Main St. Visit us", "Main Street. Visit us"},
+		{"en number sign", "en", "No. 5", "number five"},
+		{"en url", "en", "see https://example.com/docs/.", "see example dot com slash docs."},
+		{"en email", "en", "mail a.b@example.org", "mail a dot b at example dot org"},
+		{"en phonemes", "en", "[[w'0n]] 1", "[[w'0n]] one"},
+		{"en spaced", "en", "5x", "five x"},
+		{"es number", "es", "1.234,5", "mil doscientos treinta y cuatro coma cinco"},
+		{"es apocope", "es", "21 km y 21 h", "veintiún kilómetros y veintiuna horas"},
+		{"es feminine", "es", "1 h", "una hora"},
+		{"es hundreds", "es", "100 y 200 h", "cien y doscientas horas"},
+		{"es currency", "es", "1.234,56 €", "mil doscientos treinta y cuatro euros con cincuenta y seis céntimos"},
+		{"es millions", "es", "1.000.000 €", "un millón de euros"},
+		{"es dollars", "es", "$1,234.50", "mil doscientos treinta y cuatro dólares con cincuenta centavos"},
+		{"es cents", "es", "0,05 €", "cinco céntimos"},
+		{"es ordinal", "es", "1.ª y 3º", "primera y tercero"},
+		{"es date", "es", "16/10/2026", "dieciséis de octubre de dos mil veintiséis"},
+		{"es time", "es", "a las 14:30", "a las catorce y treinta"},
+		{"es midnight", "es", "a las 0:00", "a las cero horas"},
+		{"es leading decimal", "es", "queda ,5", "queda coma cinco"},
+		{"es abbreviations", "es", "el Sr. Pérez", "el señor Pérez"},
+		{"es region", "es-419", "2 €", "dos euros"},
+		{"fr number", "fr", "80, 81, 71 et 91", "quatre-vingts, quatre-vingt-un, soixante et onze et quatre-vingt-onze"},
+		{"fr hundreds", "fr", "200 et 280 000", "deux cents et deux cent quatre-vingt mille"},
+		{"fr currency", "fr", "1 234,56 €", "mille deux cent trente-quatre euros et cinquante-six centimes"},
+		{"fr cents", "fr", "$0.01", "un cent"},
+		{"fr dollars", "fr", "$1,234.50", "mille deux cent trente-quatre dollars et cinquante cents"},
+		{"fr euros", "fr", "3,5 €", "trois euros et cinquante centimes"},
+		{"fr singular", "fr", "1,5 km", "un virgule cinq kilomètre"},
+		{"fr ordinal", "fr", "1er, 1re et 2e", "premier, première et deuxième"},
+		{"fr date", "fr", "01/10/2026", "premier octobre deux mille vingt-six"},
+		{"fr time", "fr", "à 14h30 ou 1h", "à quatorze heures trente ou une heure"},
+		{"fr abbreviations", "fr", "M. Dupont", "monsieur Dupont"},
+		{"fr abbreviation period", "fr", "Dr. House et le Dr Martin, bd. Voltaire", "docteur House et le docteur Martin, boulevard Voltaire"},
+		{"unknown language", "xx", "5", "5"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := n.Normalize(tt.text, tt.language)
+			if got != tt.want {
+				t.Errorf("expected %q got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNormalizer_Normalize_map(t *testing.T) {
+	n := New()
+	text := "Pay $5 by 2026-10-16."
+	out, m := n.Normalize(text, "en")
+	if m == nil {
+		t.Fatal("expected a map")
+	}
+	for _, tt := range []struct {
+		word, orig string
+	}{
+		{"Pay", "Pay"},
+		{"five dollars", "$5"},
+		{"by", "by"},
+		{"sixteenth", "2026-10-16"},
+	} {
+		t.Run(tt.word, func(t *testing.T) {
+			i := strings.Index(out, tt.word)
+			start, end := m.Range(i, i+len(tt.word))
+			if got := text[start:end]; got != tt.orig {
+				t.Errorf("expected %q got %q", tt.orig, got)
+			}
+		})
+	}
+	if out, m := n.Normalize("nothing to do", "en"); m != nil || out != "nothing to do" {
+		t.Errorf("expected the text as is and a nil map, got %q %v", out, m)
+	}
+}
+
+func TestNormalizer_Add(t *testing.T) {
+	n := New()
+	n.Add("en", MustRule(`\b(\d+)x\b`, func(m *Match) (string, bool) {
+		return m.Group(1) + " times", true
+	}))
+	n.Add("", Abbreviations(map[string]string{"ACME": "Acme Corporation"}))
+	for _, tt := range []struct {
+		name, language, text, want string
+	}{
+		{"precedes built-ins", "en-gb", "5x", "5 times"},
+		{"other language", "es", "5x", "cinco x"},
+		{"every language", "fr", "ACME", "Acme Corporation"},
+		{"whole words", "en", "ACMEs", "ACMEs"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := n.Normalize(tt.text, tt.language)
+			if got != tt.want {
+				t.Errorf("expected %q got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCardinal(t *testing.T) {
+	for _, tt := range []struct {
+		n              int64
+		language, want string
+	}{
+		{0, "en", "zero"},
+		{-15, "en", "minus fifteen"},
+		{1000001, "en", "one million one"},
+		{1, "es", "uno"},
+		{21, "es", "veintiuno"},
+		{1000000000, "es", "mil millones"},
+		{2000000000000, "es", "dos billones"},
+		{1001, "fr", "mille un"},
+		{2000000, "fr", "deux millions"},
+		{1000000000, "fr", "un milliard"},
+		{math.MinInt64, "en", "minus nine two two three three seven two zero three six eight five four seven seven five eight zero eight"},
+		{math.MaxInt64, "fr", "neuf deux deux trois trois sept deux zéro trois six huit cinq quatre sept sept cinq huit zéro sept"},
+	} {
+		t.Run(tt.want, func(t *testing.T) {
+			got, err := Cardinal(tt.n, tt.language)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q got %q", tt.want, got)
+			}
+		})
+	}
+	if _, err := Cardinal(1, "xx"); !errors.Is(err, ErrUnknownLanguage) {
+		t.Errorf("expected %v got %v", ErrUnknownLanguage, err)
+	}
+}
+
+func TestOrdinal(t *testing.T) {
+	for _, tt := range []struct {
+		n              int64
+		language, want string
+	}{
+		{12, "en", "twelfth"},
+		{40, "en", "fortieth"},
+		{101, "en", "one hundred first"},
+		{13, "es", "decimotercero"},
+		{542, "es", "quingentésimo cuadragésimo segundo"},
+		{1, "fr", "premier"},
+		{21, "fr", "vingt et unième"},
+		{5, "fr", "cinquième"},
+		{9, "fr", "neuvième"},
+		{11, "fr", "onzième"},
+		{80, "fr", "quatre-vingtième"},
+		{-3, "en", "minus third"},
+		// too large to spell.
+		{1e15, "en", "one zero zero zero zero zero zero zero zero zero zero zero zero zero zero zero"},
+		{math.MinInt64, "en", "minus nine two two three three seven two zero three six eight five four seven seven five eight zero eight"},
+		{math.MaxInt64, "es", "nueve dos dos tres tres siete dos cero tres seis ocho cinco cuatro siete siete cinco ocho cero siete"},
+	} {
+		t.Run(tt.want, func(t *testing.T) {
+			got, err := Ordinal(tt.n, tt.language)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q got %q", tt.want, got)
+			}
+		})
+	}
+}
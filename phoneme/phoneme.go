@@ -0,0 +1,274 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+// Package phoneme converts phonetic transcriptions between espeak's phoneme
+// mnemonics (the Kirshenbaum-like codes accepted within [[ ]] when
+// synthesizing with the Phonemes flag), the International Phonetic Alphabet
+// and X-SAMPA.
+//
+// espeak's mnemonics differ between languages, so conversions go through
+// the phoneme table of a language. Tables for en-us, es, es-la and fr are
+// included, others can be added with Register.
+package phoneme
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Alphabet a phonetic notation.
+type Alphabet int
+
+const (
+	// Espeak espeak's phoneme mnemonics.
+	Espeak Alphabet = iota
+	// IPA International Phonetic Alphabet.
+	IPA
+	// XSAMPA Extended Speech Assessment Methods Phonetic Alphabet.
+	XSAMPA
+)
+
+func (a Alphabet) String() string {
+	switch a {
+	case Espeak:
+		return "espeak"
+	case IPA:
+		return "IPA"
+	case XSAMPA:
+		return "X-SAMPA"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseAlphabet returns the Alphabet named s: "espeak", "ipa" or "x-sampa"
+// (or "xsampa"), in any case.
+func ParseAlphabet(s string) (Alphabet, error) {
+	switch strings.ToLower(s) {
+	case "espeak":
+		return Espeak, nil
+	case "ipa":
+		return IPA, nil
+	case "x-sampa", "xsampa":
+		return XSAMPA, nil
+	default:
+		return 0, fmt.Errorf("%w %q", ErrAlphabet, s)
+	}
+}
+
+// Errors
+var (
+	// ErrUnknownLanguage there's no table for the language.
+	ErrUnknownLanguage = errors.New("phoneme: unknown language")
+	// ErrUnknownSymbol the transcription has a symbol missing from the table.
+	ErrUnknownSymbol = errors.New("phoneme: unknown symbol")
+	// ErrAlphabet the alphabet is not one of Espeak, IPA or XSAMPA.
+	ErrAlphabet = errors.New("phoneme: unknown alphabet")
+)
+
+// Phoneme a phoneme, or a mark such as stress, written in each alphabet.
+// An empty symbol can't be read in that alphabet, and is dropped when
+// written to it.
+type Phoneme struct {
+	Espeak string
+	IPA    string
+	XSAMPA string
+}
+
+func (p Phoneme) in(a Alphabet) string {
+	switch a {
+	case IPA:
+		return p.IPA
+	case XSAMPA:
+		return p.XSAMPA
+	default:
+		return p.Espeak
+	}
+}
+
+// ipaAliases alternative IPA spellings, read as the symbol they map to.
+var ipaAliases = map[string]string{
+	"g": "ɡ",
+	"ʧ": "tʃ",
+	"ʤ": "dʒ",
+	"ʦ": "ts",
+}
+
+// ipaTies join the letters of a phoneme, e.g. t͡ʃ, which is read as tʃ.
+var ipaTies = strings.NewReplacer("\u0361", "", "\u035c", "")
+
+// index symbols of an alphabet, to the index of their phoneme. Where
+// several phonemes share a symbol, the first in the table wins.
+type index struct {
+	symbols map[string]int
+	// maxLen of the symbols, in bytes.
+	maxLen int
+}
+
+// Table the phonemes of a language.
+type Table struct {
+	Language string
+	Phonemes []Phoneme
+	indexes  [3]index
+}
+
+// NewTable returns a *Table of language, made of phonemes, in order of
+// precedence.
+func NewTable(language string, phonemes ...[]Phoneme) *Table {
+	t := &Table{Language: strings.ToLower(language)}
+	for _, p := range phonemes {
+		t.Phonemes = append(t.Phonemes, p...)
+	}
+	for a := Espeak; a <= XSAMPA; a++ {
+		idx := index{symbols: make(map[string]int)}
+		for i, p := range t.Phonemes {
+			sym := p.in(a)
+			if sym == "" {
+				continue
+			}
+			if _, ok := idx.symbols[sym]; !ok {
+				idx.symbols[sym] = i
+			}
+			if len(sym) > idx.maxLen {
+				idx.maxLen = len(sym)
+			}
+		}
+		if a == IPA {
+			for alias, sym := range ipaAliases {
+				i, ok := idx.symbols[sym]
+				if _, taken := idx.symbols[alias]; ok && !taken {
+					idx.symbols[alias] = i
+				}
+			}
+		}
+		t.indexes[a] = idx
+	}
+	return t
+}
+
+// match returns the index of the phoneme of the longest symbol of a at the
+// start of s, and the symbol's length. Returns -1, 0 if there is none.
+func (t *Table) match(s string, a Alphabet) (int, int) {
+	idx := &t.indexes[a]
+	n := idx.maxLen
+	if n > len(s) {
+		n = len(s)
+	}
+	for ; n > 0; n-- {
+		if n < len(s) && !utf8.RuneStart(s[n]) {
+			continue
+		}
+		if i, ok := idx.symbols[s[:n]]; ok {
+			return i, n
+		}
+	}
+	return -1, 0
+}
+
+// Convert transcription s, in alphabet from, to alphabet to. Whitespace is
+// kept as is, any other symbol must be in t. Where symbols would run into
+// one another in espeak's mnemonics, e.g. "a" and "I" reading as "aI", a
+// "|" is inserted between them, as espeak does.
+func (t *Table) Convert(s string, from, to Alphabet) (string, error) {
+	if from < Espeak || from > XSAMPA || to < Espeak || to > XSAMPA {
+		return "", ErrAlphabet
+	}
+	if from == IPA {
+		s = ipaTies.Replace(s)
+	}
+	var b strings.Builder
+	prev := ""
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if unicode.IsSpace(r) {
+			b.WriteRune(r)
+			prev = ""
+			i += size
+			continue
+		}
+		p, n := t.match(s[i:], from)
+		if p < 0 {
+			return "", fmt.Errorf("%w %q at %d, in %s %s", ErrUnknownSymbol, r, i, t.Language, from)
+		}
+		sym := t.Phonemes[p].in(to)
+		if sym != "" {
+			if to == Espeak && prev != "" {
+				if _, m := t.match(prev+sym, Espeak); m != len(prev) {
+					b.WriteByte('|')
+				}
+			}
+			b.WriteString(sym)
+			prev = sym
+		}
+		i += n
+	}
+	return b.String(), nil
+}
+
+var (
+	tablesMu sync.RWMutex
+	tables   = make(map[string]*Table)
+)
+
+// Register adds t to the tables used by Convert, replacing any other of the
+// same language.
+func Register(t *Table) {
+	tablesMu.Lock()
+	defer tablesMu.Unlock()
+	tables[t.Language] = t
+}
+
+// Lookup returns the table of language, e.g. "en-us", or that of its
+// primary language, e.g. "fr" for "fr-fr".
+func Lookup(language string) (*Table, error) {
+	language = strings.ToLower(strings.Replace(language, "_", "-", -1))
+	tablesMu.RLock()
+	defer tablesMu.RUnlock()
+	if t, ok := tables[language]; ok {
+		return t, nil
+	}
+	if i := strings.IndexByte(language, '-'); i > 0 {
+		if t, ok := tables[language[:i]]; ok {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownLanguage, language)
+}
+
+// Languages returns the languages with a table, sorted.
+func Languages() []string {
+	tablesMu.RLock()
+	defer tablesMu.RUnlock()
+	langs := make([]string, 0, len(tables))
+	for l := range tables {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Convert transcription s, in alphabet from, to alphabet to, through the
+// table of language. See Table.Convert.
+func Convert(s string, from, to Alphabet, language string) (string, error) {
+	t, err := Lookup(language)
+	if err != nil {
+		return "", err
+	}
+	return t.Convert(s, from, to)
+}
+
+// EmbedIPA converts an IPA pronunciation to espeak's mnemonics for
+// language, enclosed in [[ ]], to be placed in text synthesized with the
+// Phonemes flag.
+func EmbedIPA(ipa, language string) (string, error) {
+	s, err := Convert(ipa, IPA, Espeak, language)
+	if err != nil {
+		return "", err
+	}
+	return "[[" + s + "]]", nil
+}
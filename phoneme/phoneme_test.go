@@ -0,0 +1,138 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package phoneme
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	for _, tt := range []struct {
+		language            string
+		espeak, ipa, xsampa string
+	}{
+		{"en-us", "h@l'oU w'3:ld", "həlˈoʊ wˈɜːld", `h@l"oU w"3:ld`},
+		{"en-us", "T'INk", "θˈɪŋk", `T"INk`},
+		{"en-us", "tS'3:tS dZ'VdZ", "tʃˈɜːtʃ dʒˈʌdʒ", `tS"3:tS dZ"VdZ`},
+		{"en-us", "b'Et#3", "bˈɛɾɚ", "b\"E4@`"},
+		{"en-us", "k'A@", "kˈɑːɹ", `k"A:r\`},
+		{"es", "T'jelo", "θˈjelo", `T"jelo`},
+		{"es", "p'eRo", "pˈero", `p"ero`},
+		{"es", "ka'BaLo", "kaˈβaʎo", `ka"BaLo`},
+		{"es-la", "'aJo", "ˈaɲo", `"aJo`},
+		{"fr", "b'O~ZuR", "bˈɔ̃ʒuʁ", `b"O~ZuR`},
+		{"fr-fr", "H'i", "ɥˈi", `H"i`},
+	} {
+		t.Run(tt.language+" "+tt.espeak, func(t *testing.T) {
+			for _, c := range []struct {
+				from, to Alphabet
+				in, want string
+			}{
+				{Espeak, IPA, tt.espeak, tt.ipa},
+				{IPA, Espeak, tt.ipa, tt.espeak},
+				{Espeak, XSAMPA, tt.espeak, tt.xsampa},
+				{XSAMPA, Espeak, tt.xsampa, tt.espeak},
+				{IPA, XSAMPA, tt.ipa, tt.xsampa},
+				{XSAMPA, IPA, tt.xsampa, tt.ipa},
+			} {
+				got, err := Convert(c.in, c.from, c.to, tt.language)
+				if err != nil {
+					t.Errorf("%s to %s: unexpected error: %v", c.from, c.to, err)
+					continue
+				}
+				if got != c.want {
+					t.Errorf("%s to %s: expected %q got %q", c.from, c.to, c.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestConvert_espeakSymbols(t *testing.T) {
+	for _, tt := range []struct {
+		name, in, want string
+		from, to       Alphabet
+	}{
+		// a followed by I would read as the diphthong aI.
+		{"separator", "æɪ", "a|I", IPA, Espeak},
+		{"no separator", "aɪ", "aI", IPA, Espeak},
+		{"separator dropped", "a|I", "æɪ", Espeak, IPA},
+		{"pauses dropped", "h@l'oU_:w'3:ld", "həlˈoʊwˈɜːld", Espeak, IPA},
+		{"ties dropped", "t͡ʃˈɜːt͡ʃ", "tS'3:tS", IPA, Espeak},
+		{"ascii g", "ɡˈoʊ ɡˈoʊ", "g'oU g'oU", IPA, Espeak},
+		{"aliases", "ʧˈɜːʧ", "tS'3:tS", IPA, Espeak},
+		{"syllables", "wɜː.ld", "w3:ld", IPA, Espeak},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Convert(tt.in, tt.from, tt.to, "en-us")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConvert_errors(t *testing.T) {
+	if _, err := Convert("hello", Espeak, IPA, "xx"); !errors.Is(err, ErrUnknownLanguage) {
+		t.Errorf("expected %v got %v", ErrUnknownLanguage, err)
+	}
+	// θ is not a phoneme of es-la.
+	if _, err := Convert("θˈjelo", IPA, Espeak, "es-la"); !errors.Is(err, ErrUnknownSymbol) {
+		t.Errorf("expected %v got %v", ErrUnknownSymbol, err)
+	}
+	if _, err := Convert("hello", Espeak, Alphabet(7), "en-us"); !errors.Is(err, ErrAlphabet) {
+		t.Errorf("expected %v got %v", ErrAlphabet, err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, tt := range []struct {
+		language, want string
+	}{
+		{"en-us", "en-us"},
+		{"EN_US", "en-us"},
+		{"es", "es"},
+		{"es-la", "es-la"},
+		{"es-mx", "es"},
+		{"fr-fr", "fr"},
+	} {
+		table, err := Lookup(tt.language)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.language, err)
+			continue
+		}
+		if table.Language != tt.want {
+			t.Errorf("%s: expected table %s got %s", tt.language, tt.want, table.Language)
+		}
+	}
+
+	Register(NewTable("xx", []Phoneme{{"a", "ɐ", "6"}}))
+	if got, err := Convert("aa", Espeak, XSAMPA, "xx"); err != nil || got != "66" {
+		t.Errorf("expected %q got %q, %v", "66", got, err)
+	}
+}
+
+func TestEmbedIPA(t *testing.T) {
+	got, err := EmbedIPA("həlˈoʊ", "en-us")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := "[[h@l'oU]]"; got != want {
+		t.Errorf("expected %q got %q", want, got)
+	}
+}
+
+func TestParseAlphabet(t *testing.T) {
+	for s, want := range map[string]Alphabet{"espeak": Espeak, "IPA": IPA, "x-sampa": XSAMPA, "XSAMPA": XSAMPA} {
+		if got, err := ParseAlphabet(s); err != nil || got != want {
+			t.Errorf("%s: expected %v got %v, %v", s, want, got, err)
+		}
+	}
+	if _, err := ParseAlphabet("arpabet"); !errors.Is(err, ErrAlphabet) {
+		t.Errorf("expected %v got %v", ErrAlphabet, err)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package phoneme
+
+// marks stress, length and boundaries, shared by every language.
+var marks = []Phoneme{
+	{"'", "ˈ", `"`},
+	{",", "ˌ", "%"},
+	{":", "ː", ":"},
+	{"", ".", "."},
+	// espeak's separator, pauses and stress modifiers.
+	{"|", "", ""},
+	{"_:", "", ""},
+	{"_!", "", ""},
+	{"_", "", ""},
+	{"%", "", ""},
+	{"=", "", ""},
+	// IPA linking.
+	{"", "‿", ""},
+}
+
+// consonants shared by every language.
+var consonants = []Phoneme{
+	{"p", "p", "p"},
+	{"b", "b", "b"},
+	{"t", "t", "t"},
+	{"d", "d", "d"},
+	{"k", "k", "k"},
+	{"g", "ɡ", "g"},
+	{"f", "f", "f"},
+	{"s", "s", "s"},
+	{"z", "z", "z"},
+	{"tS", "tʃ", "tS"},
+	{"m", "m", "m"},
+	{"n", "n", "n"},
+	{"N", "ŋ", "N"},
+	{"l", "l", "l"},
+	{"j", "j", "j"},
+	{"w", "w", "w"},
+}
+
+// EnglishUS en-us, as spoken by espeak's english-us voice.
+var EnglishUS = NewTable("en-us",
+	[]Phoneme{
+		{"a", "æ", "{"},
+		{"aa", "æ", "{"},
+		{"A:", "ɑː", "A:"},
+		{"A@", "ɑːɹ", `A:r\`},
+		{"A", "ɑ", "A"},
+		{"0", "ɑː", "A:"},
+		{"E", "ɛ", "E"},
+		{"e@", "ɛɹ", `Er\`},
+		{"eI", "eɪ", "eI"},
+		{"I", "ɪ", "I"},
+		{"I2", "ɪ", "I"},
+		{"I#", "ɪ", "I"},
+		{"i", "i", "i"},
+		{"i:", "iː", "i:"},
+		{"i@", "ɪɹ", `Ir\`},
+		{"i@3", "ɪɹ", `Ir\`},
+		{"O:", "ɔː", "O:"},
+		{"O", "ɔ", "O"},
+		{"O@", "ɔːɹ", `O:r\`},
+		{"o@", "ɔːɹ", `O:r\`},
+		{"OI", "ɔɪ", "OI"},
+		{"oU", "oʊ", "oU"},
+		{"U", "ʊ", "U"},
+		{"u:", "uː", "u:"},
+		{"U@", "ʊɹ", `Ur\`},
+		{"V", "ʌ", "V"},
+		{"@", "ə", "@"},
+		{"@2", "ə", "@"},
+		{"@5", "ə", "@"},
+		{"a#", "ə", "@"},
+		{"3", "ɚ", "@`"},
+		{"3:", "ɜː", "3:"},
+		{"@L", "l\u0329", "l="},
+		{"aI", "aɪ", "aI"},
+		{"aU", "aʊ", "aU"},
+		{"aI@", "aɪɚ", "aI@`"},
+		{"aI3", "aɪɚ", "aI@`"},
+		{"aU@", "aʊɚ", "aU@`"},
+	},
+	consonants,
+	[]Phoneme{
+		{"v", "v", "v"},
+		{"T", "θ", "T"},
+		{"D", "ð", "D"},
+		{"S", "ʃ", "S"},
+		{"Z", "ʒ", "Z"},
+		{"dZ", "dʒ", "dZ"},
+		{"h", "h", "h"},
+		{"r", "ɹ", `r\`},
+		{"t#", "ɾ", "4"},
+		{"?", "ʔ", "?"},
+		{"n-", "n\u0329", "n="},
+		{"W", "ʍ", "W"},
+		{"x", "x", "x"},
+	},
+	marks,
+)
+
+// spanishVowels and spanishConsonants shared by es and es-la.
+var (
+	spanishVowels = []Phoneme{
+		{"a", "a", "a"},
+		{"e", "e", "e"},
+		{"E", "ɛ", "E"},
+		{"i", "i", "i"},
+		{"o", "o", "o"},
+		{"O", "ɔ", "O"},
+		{"u", "u", "u"},
+	}
+	spanishConsonants = []Phoneme{
+		{"B", "β", "B"},
+		{"D", "ð", "D"},
+		{"Q", "ɣ", "G"},
+		{"x", "x", "x"},
+		{"J", "ɲ", "J"},
+		{"L", "ʎ", "L"},
+		{"r", "ɾ", "4"},
+		{"R", "r", "r"},
+		{"jj", "ʝ", `j\`},
+		{"dZ", "dʒ", "dZ"},
+	}
+)
+
+// Spanish es, as spoken by espeak's spanish voice.
+var Spanish = NewTable("es",
+	spanishVowels,
+	consonants,
+	spanishConsonants,
+	[]Phoneme{
+		{"T", "θ", "T"},
+	},
+	marks,
+)
+
+// SpanishLatinAmerica es-la, as spoken by espeak's spanish-latin-am voice,
+// which lacks θ.
+var SpanishLatinAmerica = NewTable("es-la",
+	spanishVowels,
+	consonants,
+	spanishConsonants,
+	[]Phoneme{
+		{"S", "ʃ", "S"},
+	},
+	marks,
+)
+
+// French fr, as spoken by espeak's french voice.
+var French = NewTable("fr",
+	[]Phoneme{
+		{"a", "a", "a"},
+		{"A", "ɑ", "A"},
+		{"e", "e", "e"},
+		{"E", "ɛ", "E"},
+		{"E:", "ɛː", "E:"},
+		{"i", "i", "i"},
+		{"o", "o", "o"},
+		{"O", "ɔ", "O"},
+		{"u", "u", "u"},
+		{"y", "y", "y"},
+		{"Y", "ø", "2"},
+		{"W", "œ", "9"},
+		{"@", "ə", "@"},
+		{"E~", "ɛ̃", "E~"},
+		{"A~", "ɑ̃", "A~"},
+		{"O~", "ɔ̃", "O~"},
+		{"W~", "œ̃", "9~"},
+	},
+	consonants,
+	[]Phoneme{
+		{"v", "v", "v"},
+		{"S", "ʃ", "S"},
+		{"Z", "ʒ", "Z"},
+		{"dZ", "dʒ", "dZ"},
+		{"J", "ɲ", "J"},
+		{"R", "ʁ", "R"},
+		{"H", "ɥ", "H"},
+	},
+	marks,
+)
+
+func init() {
+	for _, t := range []*Table{EnglishUS, Spanish, SpanishLatinAmerica, French} {
+		Register(t)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package espeak
+
+/*
+#cgo CFLAGS: -I/usr/include/espeak
+#cgo LDFLAGS: -lportaudio -lespeak
+#include <stdlib.h>
+#include <speak_lib.h>
+*/
+import "C"
+import (
+	"strings"
+	"unicode/utf8"
+	"unsafe"
+)
+
+// PhonemeMode analogous to espeak_TextToPhonemes' phonememode: the phoneme
+// alphabet, and how multi-letter phonemes are marked.
+type PhonemeMode uint32
+
+const (
+	// Mnemonics espeak's ascii phoneme names, as used within [[ ]].
+	Mnemonics PhonemeMode = 0
+	// IPA International Phonetic Alphabet, as UTF-8.
+	IPA PhonemeMode = 1 << 1
+)
+
+const (
+	// phonemeTie bit 7, the character in bits 8-23 is a tie rather than a
+	// separator.
+	phonemeTie      PhonemeMode = 1 << 7
+	phonemeCharMask PhonemeMode = 0xffff << 8
+)
+
+// TieBar U+0361, combining double inverted breve, the usual IPA tie.
+const TieBar = '\u0361'
+
+// WithSeparator returns m with phonemes separated by sep, e.g. '_'. sep must
+// be in the Basic Multilingual Plane.
+func (m PhonemeMode) WithSeparator(sep rune) PhonemeMode {
+	return m&^(phonemeTie|phonemeCharMask) | PhonemeMode(sep&0xffff)<<8
+}
+
+// WithTie returns m with the letters of multi-letter phonemes joined by
+// tie, e.g. TieBar. tie must be in the Basic Multilingual Plane.
+func (m PhonemeMode) WithTie(tie rune) PhonemeMode {
+	return m.WithSeparator(tie) | phonemeTie
+}
+
+// Phonemize translates text into phonemes, using voice, in the alphabet
+// and format of mode. Every clause of text is translated, separated by a
+// space in the output. A nil voice is the synthesizer's.
+func (s *Synthesizer) Phonemize(text string, voice *Voice, mode PhonemeMode) (string, error) {
+	if text == "" {
+		return "", ErrEmptyText
+	}
+	voice, params, err := s.resolve(voice, nil)
+	if err != nil {
+		return "", err
+	}
+	textMode := CharsAuto
+	if utf8.ValidString(text) {
+		textMode = CharsUTF8
+	}
+	var out string
+	err = eng.do(func() error {
+		if err := s.activate(voice, params); err != nil {
+			return err
+		}
+		out = textToPhonemes(text, textMode, mode)
+		return nil
+	})
+	return out, err
+}
+
+// textToPhonemes calls espeak_TextToPhonemes until it has consumed text,
+// it translates a clause at a time. It must be called from the engine, as
+// the result is in a static buffer.
+func textToPhonemes(text string, textMode FlagType, mode PhonemeMode) string {
+	cText := C.CString(text)
+	defer C.free(unsafe.Pointer(cText))
+	ptr := unsafe.Pointer(cText)
+	clauses := make([]string, 0)
+	for ptr != nil {
+		prev := ptr
+		phonemes := C.espeak_TextToPhonemes(&ptr, C.int(textMode), C.int(mode))
+		if phonemes != nil {
+			if clause := strings.TrimSpace(C.GoString(phonemes)); clause != "" {
+				clauses = append(clauses, clause)
+			}
+		}
+		if ptr == prev {
+			break
+		}
+	}
+	return strings.Join(clauses, " ")
+}
+
+// Phonemize translates text into phonemes through the default synthesizer.
+// See Synthesizer.Phonemize.
+func Phonemize(text string, voice *Voice, mode PhonemeMode) (string, error) {
+	s, err := defaultSynthesizer()
+	if err != nil {
+		return "", err
+	}
+	return s.Phonemize(text, voice, mode)
+}
@@ -0,0 +1,77 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package espeak
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPhonemize(t *testing.T) {
+	t.Run("clauses", func(t *testing.T) {
+		// every clause is translated, not only the first.
+		got, err := Phonemize("Hello world, this is a test. Again!", nil, Mnemonics)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		first, err := Phonemize("Hello world", nil, Mnemonics)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		last, err := Phonemize("Again", nil, Mnemonics)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.HasPrefix(got, first) || !strings.HasSuffix(got, last) {
+			t.Errorf("expected %q to start with %q and end with %q", got, first, last)
+		}
+	})
+	t.Run("IPA", func(t *testing.T) {
+		mnemonics, err := Phonemize("cat", ENUSMale, Mnemonics)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ipa, err := Phonemize("cat", ENUSMale, IPA)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ipa == mnemonics {
+			t.Errorf("expected IPA to differ from mnemonics, both %q", ipa)
+		}
+	})
+	t.Run("separator", func(t *testing.T) {
+		plain, _ := Phonemize("test", nil, Mnemonics)
+		got, err := Phonemize("test", nil, Mnemonics.WithSeparator('_'))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Replace(got, "_", "", -1) != plain || !strings.Contains(got, "_") {
+			t.Errorf("expected %q separated by _, got %q", plain, got)
+		}
+	})
+	t.Run("empty", func(t *testing.T) {
+		if _, err := Phonemize("", nil, IPA); !errors.Is(err, ErrEmptyText) {
+			t.Errorf("expected %v got %v", ErrEmptyText, err)
+		}
+	})
+}
+
+func TestPhonemeMode(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		mode PhonemeMode
+		want PhonemeMode
+	}{
+		{"IPA", IPA, 0x02},
+		{"separator", Mnemonics.WithSeparator('_'), '_' << 8},
+		{"tie", IPA.WithTie(TieBar), 0x0361<<8 | 1<<7 | 0x02},
+		{"tie replaced by separator", IPA.WithTie(TieBar).WithSeparator(' '), ' '<<8 | 0x02},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.mode != tt.want {
+				t.Errorf("expected %#x got %#x", tt.want, tt.mode)
+			}
+		})
+	}
+}
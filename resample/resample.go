@@ -0,0 +1,344 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+// Package resample converts audio between sample rates, e.g. espeak's
+// 22050Hz to 8kHz for telephony or 48kHz for video. It implements a
+// polyphase, Kaiser windowed sinc filter, usable on streams: samples are
+// fed as they come to Process, and Flush returns the tail once the input
+// ends.
+package resample
+
+import (
+	"errors"
+	"math"
+)
+
+// Quality of the conversion, trading speed for a flatter passband and a
+// steeper cutoff.
+type Quality int
+
+const (
+	// Default is Medium.
+	Default Quality = iota
+	// Low 8 zero crossings per side of the sinc.
+	Low
+	// Medium 16 zero crossings per side of the sinc.
+	Medium
+	// High 32 zero crossings per side of the sinc.
+	High
+)
+
+func (q Quality) String() string {
+	switch q {
+	case Default:
+		return "default"
+	case Low:
+		return "low"
+	case Medium:
+		return "medium"
+	case High:
+		return "high"
+	default:
+		return "unknown"
+	}
+}
+
+type filterSpec struct {
+	// zeroCrossings of the sinc on each side.
+	zeroCrossings int
+	// beta shape of the Kaiser window, larger is more attenuation in the
+	// stopband.
+	beta float64
+	// cutoff fraction of the lower Nyquist frequency passed through.
+	cutoff float64
+}
+
+var specs = map[Quality]filterSpec{
+	Low:    {8, 5, 0.85},
+	Medium: {16, 7, 0.92},
+	High:   {32, 9.5, 0.95},
+}
+
+// maxPhases caps the filter table size. Ratios needing more phases
+// interpolate between them.
+const maxPhases = 1024
+
+// Errors
+var (
+	// ErrSampleRate a sample rate is not positive.
+	ErrSampleRate = errors.New("resample: invalid sample rate")
+	// ErrChannels the number of channels is not positive.
+	ErrChannels = errors.New("resample: invalid number of channels")
+	// ErrQuality the quality is unknown.
+	ErrQuality = errors.New("resample: invalid quality")
+)
+
+// Resampler converts a stream of interleaved samples between sample rates.
+// It is not safe for concurrent use.
+type Resampler struct {
+	inRate, outRate int32
+	channels        int
+	// l/m is outRate/inRate, reduced.
+	l, m int64
+	// phases rows of taps coefficients, and one more, to interpolate past
+	// the last.
+	phases int64
+	taps   int
+	table  []float64
+	// buf input frames, interleaved, buf[0] being frame bufStart.
+	buf      []float64
+	bufStart int64
+	// pos+frac/l is the position of the next output frame, in input frames.
+	pos, frac int64
+	// in, out frames consumed and produced.
+	in, out int64
+}
+
+// New returns a *Resampler from inRate to outRate, of audio with channels
+// interleaved channels.
+func New(inRate, outRate int32, channels int, q Quality) (*Resampler, error) {
+	if inRate <= 0 || outRate <= 0 {
+		return nil, ErrSampleRate
+	}
+	if channels <= 0 {
+		return nil, ErrChannels
+	}
+	if q == Default {
+		q = Medium
+	}
+	spec, ok := specs[q]
+	if !ok {
+		return nil, ErrQuality
+	}
+	g := gcd(int64(inRate), int64(outRate))
+	r := &Resampler{
+		inRate:   inRate,
+		outRate:  outRate,
+		channels: channels,
+		l:        int64(outRate) / g,
+		m:        int64(inRate) / g,
+	}
+	if r.l == r.m {
+		return r, nil
+	}
+
+	r.phases = r.l
+	if r.phases > maxPhases {
+		r.phases = maxPhases
+	}
+	// cutoff, relative to the input's Nyquist frequency, low enough to
+	// filter out what the output can't represent when downsampling.
+	fc := spec.cutoff
+	if r.l < r.m {
+		fc *= float64(r.l) / float64(r.m)
+	}
+	halfWidth := float64(spec.zeroCrossings) / fc
+	half := int(math.Ceil(halfWidth))
+	r.taps = 2 * half
+	r.table = make([]float64, (r.phases+1)*int64(r.taps))
+	i0Beta := besselI0(spec.beta)
+	for p := int64(0); p <= r.phases; p++ {
+		row := r.table[p*int64(r.taps) : (p+1)*int64(r.taps)]
+		frac := float64(p) / float64(r.phases)
+		sum := 0.0
+		for j := range row {
+			// distance from the output position to the input frame of
+			// tap j.
+			t := frac + float64(half-1-j)
+			if math.Abs(t) >= halfWidth {
+				continue
+			}
+			x := t / halfWidth
+			row[j] = fc * sinc(fc*t) * besselI0(spec.beta*math.Sqrt(1-x*x)) / i0Beta
+			sum += row[j]
+		}
+		// unity gain at DC for every phase.
+		for j := range row {
+			row[j] /= sum
+		}
+	}
+	return r, nil
+}
+
+// InRate returns the input sample rate.
+func (r *Resampler) InRate() int32 {
+	return r.inRate
+}
+
+// OutRate returns the output sample rate.
+func (r *Resampler) OutRate() int32 {
+	return r.outRate
+}
+
+// Process feeds in, interleaved samples, to the resampler, returning the
+// output samples that could be computed. Some are held back until more
+// input arrives, or Flush is called.
+func (r *Resampler) Process(in []float32) []float32 {
+	for _, v := range in {
+		r.buf = append(r.buf, float64(v))
+	}
+	r.in = r.bufStart + int64(len(r.buf)/r.channels)
+	return r.produce(nil, false)
+}
+
+// Flush returns the output samples held back, treating the input as
+// ended. The Resampler is Reset afterwards.
+func (r *Resampler) Flush() []float32 {
+	out := r.produce(nil, true)
+	r.Reset()
+	return out
+}
+
+// Reset discards the state of the stream, to start a new one.
+func (r *Resampler) Reset() {
+	r.buf = r.buf[:0]
+	r.bufStart, r.pos, r.frac, r.in, r.out = 0, 0, 0, 0, 0
+}
+
+// ProcessInt16 is like Process, for 16 bit samples.
+func (r *Resampler) ProcessInt16(in []int16) []int16 {
+	f := make([]float32, len(in))
+	for i, v := range in {
+		f[i] = float32(v) / (1 << 15)
+	}
+	return toInt16(r.Process(f))
+}
+
+// FlushInt16 is like Flush, for 16 bit samples.
+func (r *Resampler) FlushInt16() []int16 {
+	return toInt16(r.Flush())
+}
+
+// produce computes the output frames whose filter taps are all available,
+// or, if final, every frame left, taking missing input as silence.
+func (r *Resampler) produce(out []float32, final bool) []float32 {
+	if r.l == r.m {
+		n := len(r.buf) - len(r.buf)%r.channels
+		for _, v := range r.buf[:n] {
+			out = append(out, float32(v))
+		}
+		r.out, r.bufStart = r.in, r.in
+		r.buf = r.buf[:copy(r.buf, r.buf[n:])]
+		return out
+	}
+
+	half := int64(r.taps / 2)
+	// total output frames, once the input ends.
+	total := (r.in*r.l + r.m - 1) / r.m
+	for {
+		if final && r.out >= total {
+			break
+		}
+		if !final && r.pos+half >= r.in {
+			break
+		}
+		// the phase of frac, and the weight of the next one.
+		pf := r.frac * r.phases
+		p := pf / r.l
+		w := float64(pf%r.l) / float64(r.l)
+		row0 := r.table[p*int64(r.taps) : (p+1)*int64(r.taps)]
+		row1 := r.table[(p+1)*int64(r.taps) : (p+2)*int64(r.taps)]
+		first := r.pos - half + 1
+		for c := 0; c < r.channels; c++ {
+			acc := 0.0
+			for j := 0; j < r.taps; j++ {
+				i := first + int64(j)
+				if i < r.bufStart || i >= r.in {
+					// before the stream, or past its end.
+					continue
+				}
+				coef := row0[j]
+				if w != 0 {
+					coef += w * (row1[j] - row0[j])
+				}
+				acc += coef * r.buf[(i-r.bufStart)*int64(r.channels)+int64(c)]
+			}
+			out = append(out, float32(acc))
+		}
+		r.out++
+		r.frac += r.m
+		r.pos += r.frac / r.l
+		r.frac %= r.l
+	}
+
+	// drop the frames no output needs anymore.
+	if drop := r.pos - half + 1 - r.bufStart; drop > 0 {
+		n := drop * int64(r.channels)
+		if n > int64(len(r.buf)) {
+			n = int64(len(r.buf)) - int64(len(r.buf)%r.channels)
+			drop = n / int64(r.channels)
+		}
+		r.buf = r.buf[:copy(r.buf, r.buf[n:])]
+		r.bufStart += drop
+	}
+	return out
+}
+
+// Float32 converts the interleaved samples in, at inRate, to outRate.
+func Float32(in []float32, inRate, outRate int32, channels int, q Quality) ([]float32, error) {
+	r, err := New(inRate, outRate, channels, q)
+	if err != nil {
+		return nil, err
+	}
+	return append(r.Process(in), r.Flush()...), nil
+}
+
+// Int16 converts the interleaved samples in, at inRate, to outRate.
+func Int16(in []int16, inRate, outRate int32, channels int, q Quality) ([]int16, error) {
+	r, err := New(inRate, outRate, channels, q)
+	if err != nil {
+		return nil, err
+	}
+	return append(r.ProcessInt16(in), r.FlushInt16()...), nil
+}
+
+// Position converts a position in samples (or frames) at inRate to
+// outRate.
+func Position(n int, inRate, outRate int32) int {
+	if inRate <= 0 {
+		return n
+	}
+	return int(int64(n) * int64(outRate) / int64(inRate))
+}
+
+func toInt16(in []float32) []int16 {
+	out := make([]int16, len(in))
+	for i, v := range in {
+		x := math.Round(float64(v) * (1 << 15))
+		switch {
+		case math.IsNaN(x):
+			x = 0
+		case x > math.MaxInt16:
+			x = math.MaxInt16
+		case x < math.MinInt16:
+			x = math.MinInt16
+		}
+		out[i] = int16(x)
+	}
+	return out
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// besselI0 zeroth order modified Bessel function of the first kind, by its
+// power series.
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; term > sum*1e-12; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+	}
+	return sum
+}
+
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
@@ -0,0 +1,160 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package resample
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func sine(freq float64, rate int32, n int) []float32 {
+	out := make([]float32, n)
+	for i := range out {
+		out[i] = float32(0.5 * math.Sin(2*math.Pi*freq*float64(i)/float64(rate)))
+	}
+	return out
+}
+
+// rms of s, skipping edge samples, where the filter sees silence.
+func rms(s []float32, edge int) float64 {
+	sum := 0.0
+	s = s[edge : len(s)-edge]
+	for _, v := range s {
+		sum += float64(v) * float64(v)
+	}
+	return math.Sqrt(sum / float64(len(s)))
+}
+
+func TestResampler(t *testing.T) {
+	const in = 22050
+	for _, out := range []int32{8000, 16000, 22050, 44100, 48000, 44099} {
+		for _, q := range []Quality{Low, Medium, High} {
+			r, err := New(in, out, 1, q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			input := sine(440, in, in/2)
+			got := append(r.Process(input), r.Flush()...)
+			want := sine(440, out, int((int64(len(input))*int64(out)+in-1)/in))
+			if len(got) != len(want) {
+				t.Fatalf("%d -> %d %s: expected %d samples got %d", in, out, q, len(want), len(got))
+			}
+			// the filter is centered, the output is in phase with the
+			// input.
+			maxErr := 0.0
+			edge := len(got) / 10
+			for i := edge; i < len(got)-edge; i++ {
+				maxErr = math.Max(maxErr, math.Abs(float64(got[i]-want[i])))
+			}
+			if maxErr > 0.01 {
+				t.Errorf("%d -> %d %s: max error %f", in, out, q, maxErr)
+			}
+		}
+	}
+}
+
+func TestResampler_antialiasing(t *testing.T) {
+	// 6kHz is above the Nyquist frequency of 8kHz.
+	input := sine(6000, 22050, 22050)
+	for _, q := range []Quality{Low, Medium, High} {
+		got, err := Float32(input, 22050, 8000, 1, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := rms(got, 800); v > 0.005 {
+			t.Errorf("%s: expected 6kHz to be filtered out, rms %f", q, v)
+		}
+	}
+}
+
+func TestResampler_streaming(t *testing.T) {
+	input := make([]float32, 10000)
+	rng := rand.New(rand.NewSource(1))
+	for i := range input {
+		input[i] = float32(rng.Float64() - 0.5)
+	}
+	for _, out := range []int32{8000, 22050, 48000} {
+		// stereo, the channels being input and its negative.
+		stereo := make([]float32, len(input)*2)
+		for i, v := range input {
+			stereo[2*i], stereo[2*i+1] = v, -v
+		}
+		want, err := Float32(stereo, 22050, out, 2, High)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, _ := New(22050, out, 2, High)
+		var got []float32
+		for rest := stereo; len(rest) > 0; {
+			// odd sizes split frames across calls.
+			n := rng.Intn(301)
+			if n > len(rest) {
+				n = len(rest)
+			}
+			got = append(got, r.Process(rest[:n])...)
+			rest = rest[n:]
+		}
+		got = append(got, r.Flush()...)
+		if len(got) != len(want) {
+			t.Fatalf("%d: expected %d samples got %d", out, len(want), len(got))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("%d: sample %d: expected %v got %v", out, i, want[i], got[i])
+			}
+			if i%2 == 1 && got[i] != -got[i-1] {
+				t.Fatalf("%d: channels mixed at frame %d: %v %v", out, i/2, got[i-1], got[i])
+			}
+		}
+	}
+}
+
+func TestInt16(t *testing.T) {
+	in := []int16{0, 1000, -1000, math.MaxInt16, math.MinInt16}
+	got, err := Int16(in, 16000, 16000, 1, Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range in {
+		if got[i] != in[i] {
+			t.Errorf("sample %d: expected %d got %d", i, in[i], got[i])
+		}
+	}
+	got, err = Int16(in, 16000, 8000, 1, Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Errorf("expected %d samples got %d", 3, len(got))
+	}
+}
+
+func TestNew_errors(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		in, out  int32
+		channels int
+		q        Quality
+		want     error
+	}{
+		{"input rate", 0, 8000, 1, Default, ErrSampleRate},
+		{"output rate", 8000, -1, 1, Default, ErrSampleRate},
+		{"channels", 8000, 16000, 0, Default, ErrChannels},
+		{"quality", 8000, 16000, 1, Quality(42), ErrQuality},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.in, tt.out, tt.channels, tt.q); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func BenchmarkResampler(b *testing.B) {
+	input := sine(440, 22050, 22050)
+	for i := 0; i < b.N; i++ {
+		Float32(input, 22050, 48000, 1, Medium)
+	}
+}
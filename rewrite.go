@@ -0,0 +1,138 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package espeak
+
+import (
+	"path"
+	"strings"
+
+	"github.com/djangulo/go-espeak/textmap"
+)
+
+// rewrite the text given to espeak in place of the caller's, e.g. after
+// applying a lexicon, and how to map the events back to the original.
+type rewrite struct {
+	text string
+	// flags needed to synthesize text, e.g. Phonemes.
+	flags FlagType
+	// m nil if text is the original.
+	m                *textmap.Map
+	origIdx, textIdx textIndex
+}
+
+// rewriteText applies params' lexicon, then its normalizer, to text, in
+// the language of voice. With the SSML flag, markup is left as is.
+func rewriteText(text string, flags FlagType, voice *Voice, params *Parameters) (*rewrite, error) {
+	rw := &rewrite{text: text}
+	lang := voice.Language()
+	markup := flags&SSML != 0
+	if params.Lexicon != nil {
+		phonemes := false
+		out, m, err := rewriteContent(text, markup, func(s string) (string, *textmap.Map, error) {
+			out, m, ph, err := params.Lexicon.Apply(s, lang)
+			phonemes = phonemes || ph
+			return out, m, err
+		})
+		if err != nil {
+			return nil, err
+		}
+		rw.text, rw.m = out, m
+		if phonemes {
+			rw.flags |= Phonemes
+		}
+	}
+	if params.Normalizer != nil {
+		out, m, _ := rewriteContent(rw.text, markup, func(s string) (string, *textmap.Map, error) {
+			out, m := params.Normalizer.Normalize(s, lang)
+			return out, m, nil
+		})
+		rw.text, rw.m = out, textmap.Compose(rw.m, m)
+	}
+	if rw.m != nil {
+		rw.origIdx = newTextIndex(text)
+		rw.textIdx = newTextIndex(rw.text)
+	}
+	return rw, nil
+}
+
+// rewriteContent rewrites text through fn, or, if markup, only the text
+// between its tags.
+func rewriteContent(
+	text string,
+	markup bool,
+	fn func(string) (string, *textmap.Map, error),
+) (string, *textmap.Map, error) {
+	if !markup {
+		return fn(text)
+	}
+	var b textmap.Builder
+	changed := false
+	for len(text) > 0 {
+		i := strings.IndexByte(text, '<')
+		if i < 0 {
+			i = len(text)
+		}
+		if i > 0 {
+			out, m, err := fn(text[:i])
+			if err != nil {
+				return "", nil, err
+			}
+			b.Rewrite(text[:i], out, m)
+			changed = changed || m != nil
+			text = text[i:]
+			continue
+		}
+		j := strings.IndexByte(text, '>')
+		if j < 0 {
+			j = len(text) - 1
+		}
+		b.Keep(text[:j+1])
+		text = text[j+1:]
+	}
+	if !changed {
+		return b.String(), nil, nil
+	}
+	return b.String(), b.Map(), nil
+}
+
+// remap returns the function converting the text positions of events from
+// the rewritten text to the original, nil if there's nothing to convert.
+func (rw *rewrite) remap() func([]Event) {
+	if rw.m == nil {
+		return nil
+	}
+	return rw.mapEvents
+}
+
+// mapEvents converts the text positions of events, in characters of the
+// rewritten text, to those of the original. A word that was replaced spans
+// the whole of what it replaced.
+func (rw *rewrite) mapEvents(events []Event) {
+	for i := range events {
+		e := &events[i]
+		if e.TextPosition < 1 {
+			continue
+		}
+		start := rw.textIdx.byteOffset(e.TextPosition)
+		if e.Length > 0 {
+			end := rw.textIdx.byteOffset(e.TextPosition + e.Length)
+			s, t := rw.m.Range(start, end)
+			e.TextPosition = rw.origIdx.position(s)
+			e.Length = rw.origIdx.position(t) - e.TextPosition
+			continue
+		}
+		e.TextPosition = rw.origIdx.position(rw.m.Offset(start))
+	}
+}
+
+// Language returns the first language of v, without the priority byte
+// espeak prefixes it with, or failing that, the last element of its
+// identifier, e.g. "es" for "europe/es".
+func (v *Voice) Language() string {
+	lang := strings.TrimLeftFunc(v.Languages, func(r rune) bool { return r < ' ' })
+	if lang == "" && v.Identifier != "" {
+		lang = path.Base(v.Identifier)
+	}
+	return lang
+}
@@ -0,0 +1,357 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package espeak
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DocumentChunk a part of a document, synthesized on its own.
+type DocumentChunk struct {
+	// Text to synthesize. Chunks of an SSML document are SSML documents of
+	// their own, the elements open at their start reopened, and those open
+	// at their end closed.
+	Text string
+	// Offset and Length, in bytes, of the part of the document covered.
+	Offset, Length int
+	// Paragraph the chunk starts a paragraph.
+	Paragraph bool
+	// Language of the chunk, the xml:lang of the innermost SSML element
+	// that sets it, or the language SplitDocument was given.
+	Language string
+}
+
+// unit a sentence, or what precedes a paragraph or SSML <s> break.
+type unit struct {
+	start, end int
+	paragraph  bool
+	language   string
+	// open the elements open at start, closing at end.
+	open, closing []tag
+}
+
+// tag an SSML start tag.
+type tag struct {
+	name, raw, language string
+}
+
+var langAttrRe = regexp.MustCompile(`\bxml:lang\s*=\s*["']([^"']*)["']`)
+
+// SplitDocument splits text, in language, into chunks of sentences of at
+// most maxChunk bytes, or of a single longer sentence. Chunks don't span
+// paragraphs, separated by blank lines, or SSML <p> elements. If flags has
+// SSML, text is an SSML document, split between its elements. A maxChunk
+// of 0, or less, puts every sentence in a chunk of its own.
+func SplitDocument(text, language string, flags FlagType, maxChunk int) []DocumentChunk {
+	var units []unit
+	if flags&SSML != 0 {
+		units = splitMarkup(text, language)
+	} else {
+		units = splitPlain(text, language)
+	}
+
+	var chunks []DocumentChunk
+	var cur *unit
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		chunks = append(chunks, DocumentChunk{
+			Text:      cur.text(text),
+			Offset:    cur.start,
+			Length:    cur.end - cur.start,
+			Paragraph: cur.paragraph,
+			Language:  cur.language,
+		})
+		cur = nil
+	}
+	for i := range units {
+		u := units[i]
+		if cur != nil && !u.paragraph && u.language == cur.language && u.end-cur.start <= maxChunk {
+			cur.end, cur.closing = u.end, u.closing
+			continue
+		}
+		flush()
+		cur = &u
+	}
+	flush()
+	return chunks
+}
+
+// text returns the text of u, with the SSML elements open at its start
+// reopened, and those open at its end closed.
+func (u *unit) text(doc string) string {
+	if len(u.open) == 0 && len(u.closing) == 0 {
+		return doc[u.start:u.end]
+	}
+	var b strings.Builder
+	for _, t := range u.open {
+		b.WriteString(t.raw)
+	}
+	b.WriteString(doc[u.start:u.end])
+	for i := len(u.closing) - 1; i >= 0; i-- {
+		b.WriteString("</" + u.closing[i].name + ">")
+	}
+	return b.String()
+}
+
+// splitMarkup splits an SSML document into units, between sentences of its
+// text, and after its </s> and </p> end tags.
+func splitMarkup(doc, language string) []unit {
+	var (
+		units []unit
+		stack []tag
+		// start of the current unit, and the elements open there.
+		start     int
+		open      []tag
+		paragraph = true
+		// speakable the unit has something to synthesize, in lang.
+		speakable bool
+		lang      string
+		// pending a sentence ended with the text before the next tag, the
+		// unit ends after the end tags that follow, if any.
+		pending bool
+	)
+	speak := func() {
+		if !speakable {
+			speakable, lang = true, langOf(stack, language)
+		}
+	}
+	// cut ends the current unit at end.
+	cut := func(end int) {
+		pending = false
+		if !speakable {
+			// nothing but markup since start, reopened by the next unit.
+			start, open = end, append([]tag(nil), stack...)
+			return
+		}
+		units = append(units, unit{
+			start:     start,
+			end:       end,
+			paragraph: paragraph,
+			language:  lang,
+			open:      open,
+			closing:   append([]tag(nil), stack...),
+		})
+		start, open = end, append([]tag(nil), stack...)
+		paragraph, speakable = false, false
+	}
+	for off := 0; off < len(doc); {
+		if doc[off] != '<' {
+			end := strings.IndexByte(doc[off:], '<')
+			if end < 0 {
+				end = len(doc)
+			} else {
+				end += off
+			}
+			if pending && strings.TrimSpace(doc[off:end]) != "" {
+				cut(off)
+			}
+			prev := off
+			for _, br := range sentenceBreaks(doc[off:end], langOf(stack, language)) {
+				if strings.TrimSpace(doc[prev:off+br.off]) != "" {
+					speak()
+				}
+				prev = off + br.off
+				if !br.paragraph && strings.TrimSpace(doc[prev:end]) == "" {
+					pending = true
+					continue
+				}
+				cut(prev)
+				paragraph = paragraph || br.paragraph
+			}
+			if strings.TrimSpace(doc[prev:end]) != "" {
+				speak()
+			}
+			off = end
+			continue
+		}
+		end := strings.IndexByte(doc[off:], '>')
+		if end < 0 {
+			end = len(doc)
+		} else {
+			end += off + 1
+		}
+		raw := doc[off:end]
+		if strings.HasPrefix(raw, "</") {
+			name := strings.TrimSpace(strings.TrimSuffix(raw[2:], ">"))
+			if n := len(stack); n > 0 && stack[n-1].name == name {
+				stack = stack[:n-1]
+			}
+			switch name {
+			case "p":
+				cut(end)
+				paragraph = true
+			case "s":
+				cut(end)
+			}
+			off = end
+			continue
+		}
+		if pending {
+			cut(off)
+		}
+		switch {
+		case strings.HasPrefix(raw, "<?"), strings.HasPrefix(raw, "<!"):
+		case strings.HasSuffix(raw, "/>"):
+			// a <break/>, <mark/> or <audio/> is worth synthesizing.
+			speak()
+		default:
+			t := tag{name: tagName(raw), raw: raw}
+			if m := langAttrRe.FindStringSubmatch(raw); m != nil {
+				t.language = m[1]
+			}
+			if t.name == "p" {
+				paragraph = true
+			}
+			stack = append(stack, t)
+		}
+		off = end
+	}
+	cut(len(doc))
+	return units
+}
+
+func tagName(raw string) string {
+	name := strings.TrimPrefix(raw, "<")
+	if i := strings.IndexAny(name, " \t\r\n/>"); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// langOf returns the language set by the innermost of tags, or language.
+func langOf(tags []tag, language string) string {
+	for i := len(tags) - 1; i >= 0; i-- {
+		if tags[i].language != "" {
+			return tags[i].language
+		}
+	}
+	return language
+}
+
+var (
+	// sentenceEnds end a sentence when followed by a space.
+	sentenceEnds = ".!?…"
+	// fullStops end a sentence regardless of what follows, in languages
+	// written without spaces.
+	fullStops = "。！？"
+	// closers may follow the end of a sentence, still belonging to it.
+	closers = `"')]»”’」』`
+	// openers may precede the first letter of a sentence.
+	openers = `"'([«“‘¿¡「『`
+	// abbreviations that don't end a sentence, by primary language.
+	abbreviations = map[string][]string{
+		"en": {"Mr", "Mrs", "Ms", "Dr", "Prof", "Sr", "Jr", "St", "Mt", "No", "vs", "etc", "e.g", "i.e", "Inc", "Ltd", "Co", "Jan", "Feb", "Aug", "Sept", "Oct", "Nov", "Dec"},
+		"es": {"Sr", "Sra", "Srta", "Dr", "Dra", "Lic", "Ing", "Ud", "Uds", "Vd", "etc", "pág", "núm", "Av", "Avda", "aprox", "p. ej", "EE. UU", "EE.UU"},
+		"fr": {"M", "MM", "Mme", "Mlle", "Dr", "Pr", "St", "Ste", "etc", "env", "av", "bd", "p. ex", "cf"},
+		"de": {"Hr", "Fr", "Dr", "Prof", "Nr", "bzw", "usw", "ca", "z.B", "d.h", "u.a", "vgl", "Str"},
+		"pt": {"Sr", "Sra", "Dr", "Dra", "Prof", "etc", "pág", "nº", "Av"},
+	}
+)
+
+// splitPlain splits plain text into units: sentences, and what precedes a
+// blank line.
+func splitPlain(text, language string) []unit {
+	var units []unit
+	start, paragraph := 0, true
+	cut := func(end int) {
+		if strings.TrimSpace(text[start:end]) != "" {
+			units = append(units, unit{start: start, end: end, paragraph: paragraph, language: language})
+			paragraph = false
+		}
+		start = end
+	}
+	for _, br := range sentenceBreaks(text, language) {
+		cut(br.off)
+		paragraph = paragraph || br.paragraph
+	}
+	cut(len(text))
+	return units
+}
+
+// textBreak where a sentence ends, or, if paragraph, a blank line starts.
+type textBreak struct {
+	off       int
+	paragraph bool
+}
+
+// sentenceBreaks returns where the sentences and paragraphs of text, in
+// language, end.
+func sentenceBreaks(text, language string) []textBreak {
+	primary := strings.ToLower(language)
+	if i := strings.IndexAny(primary, "-_"); i > 0 {
+		primary = primary[:i]
+	}
+	var breaks []textBreak
+	for i := 0; i < len(text); {
+		r, n := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == '\n':
+			j := i + n
+			for j < len(text) && (text[j] == ' ' || text[j] == '\t' || text[j] == '\r') {
+				j++
+			}
+			if j < len(text) && text[j] == '\n' {
+				breaks = append(breaks, textBreak{i, true})
+			}
+			i = j
+			continue
+		case strings.ContainsRune(sentenceEnds, r), strings.ContainsRune(fullStops, r):
+			end := i + n
+			for end < len(text) {
+				r, n := utf8.DecodeRuneInString(text[end:])
+				if !strings.ContainsRune(sentenceEnds, r) && !strings.ContainsRune(fullStops, r) && !strings.ContainsRune(closers, r) {
+					break
+				}
+				end += n
+			}
+			if isSentenceEnd(text, i, end, r, primary) {
+				breaks = append(breaks, textBreak{end, false})
+			}
+			i = end
+			continue
+		}
+		i += n
+	}
+	return breaks
+}
+
+// isSentenceEnd returns whether the punctuation r at text[i:end] ends a
+// sentence.
+func isSentenceEnd(text string, i, end int, r rune, primary string) bool {
+	if strings.ContainsRune(fullStops, r) {
+		return true
+	}
+	next, _ := utf8.DecodeRuneInString(text[end:])
+	if end < len(text) && !unicode.IsSpace(next) {
+		return false
+	}
+	// "e.g. this", a sentence goes on in lower case.
+	rest := strings.TrimLeftFunc(text[end:], func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(openers, r)
+	})
+	if first, _ := utf8.DecodeRuneInString(rest); unicode.IsLower(first) || unicode.IsDigit(first) {
+		return false
+	}
+	if r != '.' || end != i+1 {
+		return true
+	}
+	before := text[:i]
+	word := before[strings.LastIndexFunc(before, unicode.IsSpace)+1:]
+	word = strings.TrimLeft(word, openers)
+	// an initial, "J. R. R. Tolkien".
+	if first, n := utf8.DecodeRuneInString(word); n == len(word) && unicode.IsUpper(first) {
+		return false
+	}
+	for _, abbr := range abbreviations[primary] {
+		if word == abbr || strings.HasSuffix(before, abbr) && strings.Contains(abbr, " ") {
+			return false
+		}
+	}
+	return true
+}
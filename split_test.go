@@ -0,0 +1,88 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package espeak
+
+import "testing"
+
+func TestSplitDocument(t *testing.T) {
+	type chunk struct {
+		text      string
+		paragraph bool
+		language  string
+	}
+	for _, tt := range []struct {
+		name, text, language string
+		flags                FlagType
+		maxChunk             int
+		want                 []chunk
+	}{
+		{
+			"sentences",
+			"Hello Mr. Smith. How are you? Fine, e.g. okay. J. R. R. Tolkien wrote it... and more!",
+			"en", 0, 0,
+			[]chunk{
+				{"Hello Mr. Smith.", true, "en"},
+				{" How are you?", false, "en"},
+				{" Fine, e.g. okay.", false, "en"},
+				{" J. R. R. Tolkien wrote it... and more!", false, "en"},
+			},
+		},
+		{
+			"language",
+			"Hola Sr. Pérez. ¿Cómo está? Bien.",
+			"es-419", 0, 0,
+			[]chunk{
+				{"Hola Sr. Pérez.", true, "es-419"},
+				{" ¿Cómo está?", false, "es-419"},
+				{" Bien.", false, "es-419"},
+			},
+		},
+		{
+			"full stops",
+			"你好。再见！",
+			"cmn", 0, 0,
+			[]chunk{{"你好。", true, "cmn"}, {"再见！", false, "cmn"}},
+		},
+		{
+			"paragraphs",
+			"One. Two.\n\nThree. Four.",
+			"en", 0, 100,
+			[]chunk{{"One. Two.", true, "en"}, {"\n\nThree. Four.", true, "en"}},
+		},
+		{
+			"max chunk",
+			"One. Two. Three.",
+			"en", 0, 10,
+			[]chunk{{"One. Two.", true, "en"}, {" Three.", false, "en"}},
+		},
+		{
+			"ssml",
+			`<speak xml:lang="en"><p><s>One.</s><s>Two <emphasis>big</emphasis> things. Three.</s></p>` +
+				`<p xml:lang="es">Hola Sr. Pérez.<break time="1s"/></p></speak>`,
+			"fr", SSML, 0,
+			[]chunk{
+				{`<speak xml:lang="en"><p><s>One.</s></p></speak>`, true, "en"},
+				{`<speak xml:lang="en"><p><s>Two <emphasis>big</emphasis> things.</s></p></speak>`, false, "en"},
+				{`<speak xml:lang="en"><p><s> Three.</s></p></speak>`, false, "en"},
+				{`<speak xml:lang="en"><p xml:lang="es">Hola Sr. Pérez.</p></speak>`, true, "es"},
+				{`<speak xml:lang="en"><p xml:lang="es"><break time="1s"/></p></speak>`, false, "es"},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitDocument(tt.text, tt.language, tt.flags, tt.maxChunk)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %d chunks got %d: %+v", len(tt.want), len(got), got)
+			}
+			for i, want := range tt.want {
+				c := got[i]
+				if c.Text != want.text || c.Paragraph != want.paragraph || c.Language != want.language {
+					t.Errorf("chunk %d: expected %+v got %+v", i, want, c)
+				}
+				if tt.flags&SSML == 0 && tt.text[c.Offset:c.Offset+c.Length] != c.Text {
+					t.Errorf("chunk %d: offset and length don't match its text", i)
+				}
+			}
+		})
+	}
+}
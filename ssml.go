@@ -0,0 +1,33 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package espeak
+
+import (
+	"context"
+
+	"github.com/djangulo/go-espeak/ssml"
+)
+
+// SynthSSML is like Synthesize, but renders doc and synthesizes it with the
+// SSML flag set. Returns an error wrapping ssml.ErrUnsupported if doc has
+// anything espeak doesn't support. Events and word timings refer to the
+// rendered document. A lexicon and normalizer only rewrite the text between
+// its tags.
+func (s *Synthesizer) SynthSSML(ctx context.Context, doc *ssml.Element, voice *Voice, params *Parameters) (*Result, error) {
+	text, err := doc.Render()
+	if err != nil {
+		return nil, err
+	}
+	return s.synthesizeResult(ctx, text, CharsAuto|SSML|EndPause, voice, params)
+}
+
+// SynthSSML synthesizes doc through the default synthesizer. See
+// Synthesizer.SynthSSML.
+func SynthSSML(ctx context.Context, doc *ssml.Element, voice *Voice, params *Parameters) (*Result, error) {
+	s, err := defaultSynthesizer()
+	if err != nil {
+		return nil, err
+	}
+	return s.SynthSSML(ctx, doc, voice, params)
+}
@@ -0,0 +1,273 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+// Package ssml builds and validates Speech Synthesis Markup Language
+// documents, limited to the elements and attributes espeak supports.
+//
+// espeak silently ignores what it doesn't support, so a misspelled
+// attribute, or an element from a later SSML version, goes unnoticed. Render
+// and Validate report those instead. Synthesize documents with
+// espeak.SynthSSML, which sets the SSML flag.
+package ssml
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors
+var (
+	// ErrUnsupported the document has elements or attributes espeak doesn't
+	// support, or values it can't read.
+	ErrUnsupported = errors.New("ssml: unsupported")
+	// ErrSyntax the document is not well-formed XML.
+	ErrSyntax = errors.New("ssml: syntax error")
+)
+
+// Node a part of a document, either an *Element or Text.
+type Node interface {
+	render(b *strings.Builder)
+}
+
+// Text content of an element, escaped when rendered.
+type Text string
+
+func (t Text) render(b *strings.Builder) {
+	escape(b, string(t), false)
+}
+
+// Attr an attribute of an element.
+type Attr struct {
+	Name, Value string
+}
+
+// Element an SSML element.
+type Element struct {
+	Name     string
+	Attrs    []Attr
+	Children []Node
+}
+
+// NewElement returns an *Element named name, with children.
+func NewElement(name string, children ...Node) *Element {
+	return &Element{Name: name, Children: children}
+}
+
+// Attr returns the value of the attribute name, "" if it's not set.
+func (e *Element) Attr(name string) string {
+	for _, a := range e.Attrs {
+		if a.Name == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// With sets the attribute name to value.
+func (e *Element) With(name, value string) *Element {
+	for i := range e.Attrs {
+		if e.Attrs[i].Name == name {
+			e.Attrs[i].Value = value
+			return e
+		}
+	}
+	e.Attrs = append(e.Attrs, Attr{name, value})
+	return e
+}
+
+// Append adds children to e.
+func (e *Element) Append(children ...Node) *Element {
+	e.Children = append(e.Children, children...)
+	return e
+}
+
+// WithLang xml:lang, e.g. "en-US", of speak, voice, p and s.
+func (e *Element) WithLang(tag string) *Element {
+	return e.With("xml:lang", tag)
+}
+
+// WithGender of voice: "male", "female" or "neutral".
+func (e *Element) WithGender(gender string) *Element {
+	return e.With("gender", gender)
+}
+
+// WithAge of voice, in years.
+func (e *Element) WithAge(age int) *Element {
+	return e.With("age", strconv.Itoa(age))
+}
+
+// WithVariant of voice, counting from 1.
+func (e *Element) WithVariant(variant int) *Element {
+	return e.With("variant", strconv.Itoa(variant))
+}
+
+// WithRate of prosody: "x-slow" to "x-fast", "default", a multiplier of the
+// current rate, e.g. "1.5", or a relative change, e.g. "+20%".
+func (e *Element) WithRate(rate string) *Element {
+	return e.With("rate", rate)
+}
+
+// WithPitch of prosody: "x-low" to "x-high", "default", or a relative
+// change, e.g. "-10%".
+func (e *Element) WithPitch(pitch string) *Element {
+	return e.With("pitch", pitch)
+}
+
+// WithRange of prosody, the pitch range: "x-low" to "x-high", "default",
+// or a relative change, e.g. "+50%".
+func (e *Element) WithRange(rng string) *Element {
+	return e.With("range", rng)
+}
+
+// WithVolume of prosody: "silent", "x-soft" to "x-loud", "default", or a
+// relative change, e.g. "+10%".
+func (e *Element) WithVolume(volume string) *Element {
+	return e.With("volume", volume)
+}
+
+// WithFormat of say-as, e.g. "glyphs".
+func (e *Element) WithFormat(format string) *Element {
+	return e.With("format", format)
+}
+
+// WithDetail of say-as, e.g. the number of digits read together with
+// "tts:digits".
+func (e *Element) WithDetail(detail string) *Element {
+	return e.With("detail", detail)
+}
+
+func (e *Element) render(b *strings.Builder) {
+	b.WriteByte('<')
+	b.WriteString(e.Name)
+	for _, a := range e.Attrs {
+		b.WriteByte(' ')
+		b.WriteString(a.Name)
+		b.WriteString(`="`)
+		escape(b, a.Value, true)
+		b.WriteByte('"')
+	}
+	if len(e.Children) == 0 {
+		b.WriteString("/>")
+		return
+	}
+	b.WriteByte('>')
+	for _, c := range e.Children {
+		c.render(b)
+	}
+	b.WriteString("</")
+	b.WriteString(e.Name)
+	b.WriteByte('>')
+}
+
+// String renders e, whether espeak supports it or not.
+func (e *Element) String() string {
+	var b strings.Builder
+	e.render(&b)
+	return b.String()
+}
+
+// Render renders e, a <speak> document, returning an error wrapping
+// ErrUnsupported if espeak doesn't support any of it.
+func (e *Element) Render() (string, error) {
+	s := e.String()
+	issues, err := Validate(s)
+	if err != nil {
+		return "", err
+	}
+	if len(issues) > 0 {
+		return "", fmt.Errorf("%w: %v", ErrUnsupported, issues[0])
+	}
+	return s, nil
+}
+
+func escape(b *strings.Builder, s string, attr bool) {
+	for _, r := range s {
+		switch {
+		case r == '&':
+			b.WriteString("&amp;")
+		case r == '<':
+			b.WriteString("&lt;")
+		case r == '>':
+			b.WriteString("&gt;")
+		case r == '"' && attr:
+			b.WriteString("&quot;")
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+// Speak the root of a document.
+func Speak(children ...Node) *Element {
+	return NewElement("speak", children...)
+}
+
+// Voice switches to the voice named name, or, if name is "", to the one
+// best matching the attributes set through WithLang, WithGender, WithAge
+// and WithVariant.
+func Voice(name string, children ...Node) *Element {
+	e := NewElement("voice", children...)
+	if name != "" {
+		e.With("name", name)
+	}
+	return e
+}
+
+// Prosody changes the rate, pitch, range or volume of its children, set
+// through WithRate, WithPitch, WithRange and WithVolume.
+func Prosody(children ...Node) *Element {
+	return NewElement("prosody", children...)
+}
+
+// Break a pause of strength: "none", "x-weak", "weak", "medium", "strong" or
+// "x-strong".
+func Break(strength string) *Element {
+	return NewElement("break").With("strength", strength)
+}
+
+// BreakTime a pause of d, rounded to milliseconds.
+func BreakTime(d time.Duration) *Element {
+	return NewElement("break").With("time", strconv.FormatInt(d.Round(time.Millisecond).Milliseconds(), 10)+"ms")
+}
+
+// Emphasis stresses its children.
+func Emphasis(children ...Node) *Element {
+	return NewElement("emphasis", children...)
+}
+
+// SayAs reads its children as interpretAs: "characters" spells them,
+// "tts:char" reads their punctuation and symbols, "tts:key" reads them as
+// key names, and "tts:digits" reads numbers digit by digit, or in groups of
+// WithDetail digits.
+func SayAs(interpretAs string, children ...Node) *Element {
+	return NewElement("say-as", children...).With("interpret-as", interpretAs)
+}
+
+// Audio plays the sound at src, reported as a PlayEvent. espeak doesn't
+// play it, children are read if the caller doesn't either.
+func Audio(src string, children ...Node) *Element {
+	return NewElement("audio", children...).With("src", src)
+}
+
+// Mark reports a MarkEvent named name when reached.
+func Mark(name string) *Element {
+	return NewElement("mark").With("name", name)
+}
+
+// P a paragraph.
+func P(children ...Node) *Element {
+	return NewElement("p", children...)
+}
+
+// S a sentence.
+func S(children ...Node) *Element {
+	return NewElement("s", children...)
+}
+
+// Sub reads alias in place of text.
+func Sub(alias, text string) *Element {
+	return NewElement("sub", Text(text)).With("alias", alias)
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package ssml
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestElement_Render(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		doc  *Element
+		want string
+		err  error
+	}{
+		{
+			"sentences",
+			Speak(P(S(Text("Hello")), S(Text("World")))).WithLang("en-US"),
+			`<speak xml:lang="en-US"><p><s>Hello</s><s>World</s></p></speak>`,
+			nil,
+		},
+		{
+			"escapes",
+			Speak(Text(`Tom & "Jerry" <3`), Sub(`a "b"`, "c")),
+			`<speak>Tom &amp; "Jerry" &lt;3<sub alias="a &quot;b&quot;">c</sub></speak>`,
+			nil,
+		},
+		{
+			"voice",
+			Speak(Voice("", Text("hi")).WithGender("female").WithAge(30).WithVariant(2)),
+			`<speak><voice gender="female" age="30" variant="2">hi</voice></speak>`,
+			nil,
+		},
+		{
+			"prosody",
+			Speak(Prosody(Emphasis(Text("fast"))).WithRate("+20%").WithPitch("x-high").WithRange("default").WithVolume("soft")),
+			`<speak><prosody rate="+20%" pitch="x-high" range="default" volume="soft"><emphasis>fast</emphasis></prosody></speak>`,
+			nil,
+		},
+		{
+			"breaks",
+			Speak(Break("strong"), BreakTime(1500*time.Millisecond)),
+			`<speak><break strength="strong"/><break time="1500ms"/></speak>`,
+			nil,
+		},
+		{
+			"say-as",
+			Speak(SayAs("tts:digits", Text("1234")).WithDetail("2")),
+			`<speak><say-as interpret-as="tts:digits" detail="2">1234</say-as></speak>`,
+			nil,
+		},
+		{
+			"audio and mark",
+			Speak(Mark("start"), Audio("beep.wav", Text("beep"))),
+			`<speak><mark name="start"/><audio src="beep.wav">beep</audio></speak>`,
+			nil,
+		},
+		{"bad value", Speak(Prosody(Text("x")).WithRate("fastt")), "", ErrUnsupported},
+		{"bad attribute", Speak(Emphasis(Text("x")).With("level", "strong")), "", ErrUnsupported},
+		{"bad element", Speak(NewElement("phoneme", Text("x"))), "", ErrUnsupported},
+		{"not a document", P(Text("x")), "", ErrUnsupported},
+		{"content of an empty element", Speak(Mark("m").Append(Text("x"))), "", ErrUnsupported},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.doc.Render()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v got %v", tt.err, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	doc := `<speak version="1.0" xmlns="http://www.w3.org/2001/10/synthesis" xml:lang="en">
+  <phoneme ph="x">a</phoneme>
+  <prosody rate="fastt" foo="1">b</prosody>
+  <say-as>c</say-as><mark name="m"/>
+</speak>`
+	issues, err := Validate(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Issue{
+		{Line: 2, Column: 3, Offset: strings.Index(doc, "<phoneme"), Element: "phoneme", Message: "unsupported element"},
+		{Line: 3, Column: 3, Offset: strings.Index(doc, "<prosody"), Element: "prosody", Attribute: "rate", Message: `unsupported value "fastt" of "rate"`},
+		{Line: 3, Column: 3, Offset: strings.Index(doc, "<prosody"), Element: "prosody", Attribute: "foo", Message: `unsupported attribute "foo"`},
+		{Line: 4, Column: 3, Offset: strings.Index(doc, "<say-as"), Element: "say-as", Attribute: "interpret-as", Message: `missing attribute "interpret-as"`},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("expected %d issues got %d: %v", len(want), len(issues), issues)
+	}
+	for i := range want {
+		if issues[i] != want[i] {
+			t.Errorf("issue %d: expected %+v got %+v", i, want[i], issues[i])
+		}
+	}
+
+	for _, tt := range []struct {
+		name, doc string
+	}{
+		{"mismatched", "<speak><p></s></speak>"},
+		{"unclosed", "<speak><p>"},
+		{"empty", ""},
+		{"text outside", "<speak/>text"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Validate(tt.doc); !errors.Is(err, ErrSyntax) {
+				t.Errorf("expected %v got %v", ErrSyntax, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package ssml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Issue something espeak doesn't support in a document.
+type Issue struct {
+	// Line and Column, counting from 1, and byte Offset of the start of the
+	// element.
+	Line, Column int
+	Offset       int
+	// Element the issue was found in, Attribute the offending attribute, if
+	// any.
+	Element, Attribute string
+	Message            string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%d:%d: <%s>: %s", i.Line, i.Column, i.Element, i.Message)
+}
+
+// check returns whether an attribute value is supported, nil allows any.
+type check func(value string) bool
+
+// elementSpec the attributes and content espeak supports for an element.
+type elementSpec struct {
+	attrs    map[string]check
+	required []string
+	// empty the element has no content, textOnly no child elements.
+	empty, textOnly bool
+}
+
+var (
+	langRe     = regexp.MustCompile(`^[A-Za-z]{1,8}(?:[-_][A-Za-z0-9]{1,8})*$`)
+	numberRe   = regexp.MustCompile(`^[+-]?(?:\d+(?:\.\d*)?|\.\d+)%?$`)
+	durationRe = regexp.MustCompile(`^(?:\d+(?:\.\d*)?|\.\d+)(?:ms|s)$`)
+	uintRe     = regexp.MustCompile(`^\d+$`)
+)
+
+func oneOf(values ...string) check {
+	return func(v string) bool {
+		for _, s := range values {
+			if v == s {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// numberOr allows a number, a percentage, or one of keywords.
+func numberOr(keywords ...string) check {
+	kw := oneOf(keywords...)
+	return func(v string) bool {
+		return kw(v) || numberRe.MatchString(v)
+	}
+}
+
+var (
+	lang  = check(langRe.MatchString)
+	count = check(uintRe.MatchString)
+	// elements supported by espeak.
+	elements = map[string]elementSpec{
+		"speak": {attrs: map[string]check{"xml:lang": lang, "xml:base": nil, "version": oneOf("1.0", "1.1")}},
+		"voice": {attrs: map[string]check{
+			"name":     nil,
+			"xml:lang": lang,
+			"gender":   oneOf("male", "female", "neutral"),
+			"age":      count,
+			"variant":  count,
+		}},
+		"prosody": {attrs: map[string]check{
+			"rate":   numberOr("x-slow", "slow", "medium", "fast", "x-fast", "default"),
+			"pitch":  numberOr("x-low", "low", "medium", "high", "x-high", "default"),
+			"range":  numberOr("x-low", "low", "medium", "high", "x-high", "default"),
+			"volume": numberOr("silent", "x-soft", "soft", "medium", "loud", "x-loud", "default"),
+		}},
+		"break": {
+			attrs: map[string]check{
+				"strength": oneOf("none", "x-weak", "weak", "medium", "strong", "x-strong"),
+				"time":     durationRe.MatchString,
+			},
+			empty: true,
+		},
+		"emphasis": {},
+		"say-as": {
+			attrs: map[string]check{
+				"interpret-as": oneOf("characters", "tts:char", "tts:key", "tts:digits"),
+				"format":       oneOf("glyphs"),
+				"detail":       count,
+			},
+			required: []string{"interpret-as"},
+		},
+		"audio": {attrs: map[string]check{"src": nil}, required: []string{"src"}},
+		"mark":  {attrs: map[string]check{"name": nil}, required: []string{"name"}, empty: true},
+		"p":     {attrs: map[string]check{"xml:lang": lang}},
+		"s":     {attrs: map[string]check{"xml:lang": lang}},
+		"sub":   {attrs: map[string]check{"alias": nil}, required: []string{"alias"}, textOnly: true},
+	}
+)
+
+// open an element being validated.
+type open struct {
+	name string
+	spec elementSpec
+	ok   bool
+	// at the position of its start tag.
+	at Issue
+}
+
+// Validate parses doc and returns the elements, attributes and values espeak
+// doesn't support, in document order. The error wraps ErrSyntax if doc is
+// not well-formed.
+func Validate(doc string) ([]Issue, error) {
+	data := []byte(doc)
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = true
+	var (
+		issues []Issue
+		stack  []open
+		root   bool
+	)
+	for {
+		off := int(d.InputOffset())
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			line, col := position(data, int(d.InputOffset()))
+			return issues, fmt.Errorf("%w: %d:%d: %v", ErrSyntax, line, col, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := qualified(t.Name)
+			line, col := position(data, off)
+			at := Issue{Line: line, Column: col, Offset: off, Element: name}
+			report := func(attr, format string, args ...interface{}) {
+				is := at
+				is.Attribute = attr
+				is.Message = fmt.Sprintf(format, args...)
+				issues = append(issues, is)
+			}
+			if len(stack) == 0 {
+				if root {
+					report("", "more than one root element")
+				}
+				root = true
+				if name != "speak" {
+					report("", "root element is not <speak>")
+				}
+			} else if parent := stack[len(stack)-1]; parent.ok {
+				switch {
+				case parent.spec.empty:
+					report("", "<%s> has no content", parent.name)
+				case parent.spec.textOnly:
+					report("", "<%s> may only hold text", parent.name)
+				}
+			}
+			spec, ok := elements[name]
+			if !ok {
+				report("", "unsupported element")
+			} else {
+				for _, a := range t.Attr {
+					attr := qualified(a.Name)
+					if a.Name.Space == "xmlns" || attr == "xmlns" {
+						continue
+					}
+					check, known := spec.attrs[attr]
+					switch {
+					case !known:
+						report(attr, "unsupported attribute %q", attr)
+					case check != nil && !check(a.Value):
+						report(attr, "unsupported value %q of %q", a.Value, attr)
+					}
+				}
+				for _, attr := range spec.required {
+					if !hasAttr(t.Attr, attr) {
+						report(attr, "missing attribute %q", attr)
+					}
+				}
+			}
+			stack = append(stack, open{name: name, spec: spec, ok: ok, at: at})
+		case xml.EndElement:
+			name := qualified(t.Name)
+			if len(stack) == 0 || stack[len(stack)-1].name != name {
+				line, col := position(data, off)
+				return issues, fmt.Errorf("%w: %d:%d: unexpected </%s>", ErrSyntax, line, col, name)
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) == 0 {
+				if strings.TrimSpace(string(t)) != "" {
+					line, col := position(data, off)
+					return issues, fmt.Errorf("%w: %d:%d: text outside of the root element", ErrSyntax, line, col)
+				}
+				continue
+			}
+			if top := stack[len(stack)-1]; top.ok && top.spec.empty && strings.TrimSpace(string(t)) != "" {
+				is := top.at
+				is.Message = "has no content"
+				issues = append(issues, is)
+			}
+		}
+	}
+	if len(stack) > 0 {
+		top := stack[len(stack)-1]
+		return issues, fmt.Errorf("%w: %d:%d: <%s> is not closed", ErrSyntax, top.at.Line, top.at.Column, top.name)
+	}
+	if !root {
+		return issues, fmt.Errorf("%w: no root element", ErrSyntax)
+	}
+	return issues, nil
+}
+
+// qualified returns n as written, prefix included.
+func qualified(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+func hasAttr(attrs []xml.Attr, name string) bool {
+	for _, a := range attrs {
+		if qualified(a.Name) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// position returns the line and column, in characters, of the byte at off.
+func position(data []byte, off int) (int, int) {
+	if off > len(data) {
+		off = len(data)
+	}
+	line := 1 + bytes.Count(data[:off], []byte("\n"))
+	start := bytes.LastIndexByte(data[:off], '\n') + 1
+	return line, 1 + utf8.RuneCount(data[start:off])
+}
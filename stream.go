@@ -0,0 +1,214 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package espeak
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+
+	"github.com/djangulo/go-espeak/resample"
+)
+
+// chunkBuffer number of chunks produced ahead of the consumer. Synthesis
+// blocks once it's full, which bounds a stream's memory to roughly
+// chunkBuffer*bufferLength mS of audio.
+const chunkBuffer = 4
+
+// Chunk is a buffer of samples, as passed by espeak to the synth callback.
+type Chunk struct {
+	Samples []int16
+	// Events reported by espeak along with the samples. Their Sample is
+	// counted from the start of the synthesis, not of the chunk.
+	Events []Event
+	// Err is set on the last chunk if synthesis failed or was cancelled.
+	Err error
+}
+
+// SynthChunks synthesizes text, using voice, modified by params, sending
+// the samples through the returned channel as espeak produces them. The
+// channel is closed when synthesis is done. If it fails, or ctx is done
+// before it finishes, the last chunk carries the error, unless the channel
+// is full and ctx is done, it being left unread.
+//
+// espeak is held until synthesis ends, so the channel must be drained, or
+// ctx cancelled, for other calls to proceed.
+func (s *Synthesizer) SynthChunks(ctx context.Context, text string, voice *Voice, params *Parameters) (<-chan Chunk, error) {
+	if text == "" {
+		return nil, ErrEmptyText
+	}
+	voice, params, err := s.resolveText(text, CharsAuto, voice, params)
+	if err != nil {
+		return nil, err
+	}
+	if s.plays() {
+		return nil, ErrOutputMode
+	}
+	rs, err := s.resampler(params)
+	if err != nil {
+		return nil, err
+	}
+
+	rw, err := rewriteText(text, CharsAuto, voice, params)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(chan Chunk, chunkBuffer)
+	id, j := registry.newJob(ctx.Done(), chunks)
+	j.remap = rw.remap()
+	go func() {
+		defer close(chunks)
+		defer registry.removeData(id)
+		if err := s.synthesize(ctx, id, j, rw.text, CharsAuto|EndPause|rw.flags, voice, params); err != nil {
+			sendChunk(ctx, chunks, Chunk{Err: err})
+		}
+	}()
+	if rs != nil {
+		return resampleChunks(ctx, chunks, rs, s.sampleRate), nil
+	}
+	return chunks, nil
+}
+
+// sendChunk sends c through chunks, unless ctx is done before it can.
+// Returns whether it was sent.
+func sendChunk(ctx context.Context, chunks chan<- Chunk, c Chunk) bool {
+	// the error of a cancelled synthesis goes through, if there's room.
+	select {
+	case chunks <- c:
+		return true
+	default:
+	}
+	select {
+	case chunks <- c:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// resampleChunks converts the chunks of in, at inRate, through rs, until
+// in is closed or ctx is done.
+func resampleChunks(ctx context.Context, in <-chan Chunk, rs *resample.Resampler, inRate int32) <-chan Chunk {
+	out := make(chan Chunk, chunkBuffer)
+	go func() {
+		defer close(out)
+		for chunk := range in {
+			if chunk.Err == nil {
+				chunk.Samples = rs.ProcessInt16(chunk.Samples)
+				resampleEvents(chunk.Events, inRate, rs.OutRate())
+			}
+			if !sendChunk(ctx, out, chunk) {
+				return
+			}
+		}
+		if tail := rs.FlushInt16(); len(tail) > 0 {
+			sendChunk(ctx, out, Chunk{Samples: tail})
+		}
+	}()
+	return out
+}
+
+// SynthStream synthesizes text, using voice, modified by params, returning
+// a *Stream that reads the samples as espeak produces them. The Stream must
+// be read to completion or closed, see SynthChunks.
+func (s *Synthesizer) SynthStream(ctx context.Context, text string, voice *Voice, params *Parameters) (*Stream, error) {
+	voice, params, err := s.resolve(voice, params)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	chunks, err := s.SynthChunks(ctx, text, voice, params)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &Stream{
+		chunks:     chunks,
+		cancel:     cancel,
+		sampleRate: s.outputRate(params),
+	}, nil
+}
+
+// SynthChunks synthesizes text through the default synthesizer. See
+// Synthesizer.SynthChunks.
+func SynthChunks(ctx context.Context, text string, voice *Voice, params *Parameters) (<-chan Chunk, error) {
+	s, err := defaultSynthesizer()
+	if err != nil {
+		return nil, err
+	}
+	return s.SynthChunks(ctx, text, voice, params)
+}
+
+// SynthStream synthesizes text through the default synthesizer. See
+// Synthesizer.SynthStream.
+func SynthStream(ctx context.Context, text string, voice *Voice, params *Parameters) (*Stream, error) {
+	s, err := defaultSynthesizer()
+	if err != nil {
+		return nil, err
+	}
+	return s.SynthStream(ctx, text, voice, params)
+}
+
+// Stream is an io.ReadCloser of synthesized audio, as raw little endian,
+// 16 bit, mono PCM.
+type Stream struct {
+	chunks     <-chan Chunk
+	cancel     context.CancelFunc
+	sampleRate int32
+	buf        []byte
+	err        error
+}
+
+// SampleRate of the stream's audio.
+func (st *Stream) SampleRate() int32 {
+	return st.sampleRate
+}
+
+// Channels of the stream's audio, always 1.
+func (st *Stream) Channels() int {
+	return 1
+}
+
+// BitsPerSample of the stream's audio, always 16.
+func (st *Stream) BitsPerSample() int {
+	return 16
+}
+
+// Read implements the io.Reader interface.
+func (st *Stream) Read(p []byte) (int, error) {
+	for len(st.buf) == 0 {
+		if st.err != nil {
+			return 0, st.err
+		}
+		chunk, ok := <-st.chunks
+		switch {
+		case !ok:
+			st.err = io.EOF
+		case chunk.Err != nil:
+			st.err = chunk.Err
+		default:
+			st.buf = make([]byte, len(chunk.Samples)*2)
+			for i, v := range chunk.Samples {
+				binary.LittleEndian.PutUint16(st.buf[i*2:], uint16(v))
+			}
+		}
+	}
+	n := copy(p, st.buf)
+	st.buf = st.buf[n:]
+	return n, nil
+}
+
+// Close implements the io.Closer interface. It stops synthesis, if still
+// running, and waits for espeak to be released.
+func (st *Stream) Close() error {
+	st.cancel()
+	for range st.chunks {
+	}
+	st.buf = nil
+	if st.err == nil {
+		st.err = io.ErrClosedPipe
+	}
+	return nil
+}
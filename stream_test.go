@@ -0,0 +1,123 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package espeak
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSynthStream(t *testing.T) {
+	text := "test speech, for streaming"
+	want, err := GenSamples(text, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("read all", func(t *testing.T) {
+		st, err := SynthStream(context.Background(), text, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer st.Close()
+		if st.SampleRate() <= 0 {
+			t.Errorf("expected a positive sample rate, got %d", st.SampleRate())
+		}
+		b, err := ioutil.ReadAll(st)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got := make([]int16, len(b)/2)
+		binary.Read(bytes.NewReader(b), binary.LittleEndian, got)
+		if !equalSamples(got, want) {
+			t.Errorf("expected %d samples got %d (or differing data)", len(want), len(got))
+		}
+	})
+	t.Run("close early", func(t *testing.T) {
+		st, err := SynthStream(context.Background(), text, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := make([]byte, 2)
+		if _, err := io.ReadFull(st, p); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := st.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := st.Read(p); err == nil {
+			t.Error("expected an error reading a closed stream")
+		}
+		// espeak should have been released
+		if _, err := GenSamples(text, nil, nil); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		st, err := SynthStream(ctx, text, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer st.Close()
+		if _, err := ioutil.ReadAll(st); !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %v got %v", context.Canceled, err)
+		}
+	})
+	t.Run("chunks", func(t *testing.T) {
+		chunks, err := SynthChunks(context.Background(), text, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int16
+		for c := range chunks {
+			if c.Err != nil {
+				t.Fatalf("unexpected error: %v", c.Err)
+			}
+			got = append(got, c.Samples...)
+		}
+		if !equalSamples(got, want) {
+			t.Errorf("expected %d samples got %d (or differing data)", len(want), len(got))
+		}
+	})
+	t.Run("cancelled unread", func(t *testing.T) {
+		long := strings.Repeat(text+". ", 50)
+		for _, params := range []*Parameters{nil, NewParameters(WithSampleRate(8000))} {
+			before := runtime.NumGoroutine()
+			ctx, cancel := context.WithCancel(context.Background())
+			chunks, err := SynthChunks(ctx, long, nil, params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			deadline := time.Now().Add(5 * time.Second)
+			for len(chunks) < cap(chunks) && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			cancel()
+			// nothing is read, yet synthesis must wind down.
+			for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if n := runtime.NumGoroutine(); n > before {
+				t.Errorf("expected %d goroutines got %d", before, n)
+			}
+		}
+		if _, err := GenSamples(text, nil, nil); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+	t.Run("empty text", func(t *testing.T) {
+		if _, err := SynthStream(context.Background(), "", nil, nil); !errors.Is(err, ErrEmptyText) {
+			t.Errorf("expected %v got %v", ErrEmptyText, err)
+		}
+	})
+}
@@ -0,0 +1,654 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package espeak
+
+/*
+#cgo CFLAGS: -I/usr/include/espeak
+#cgo LDFLAGS: -lportaudio -lespeak
+#include <stdlib.h>
+#include <speak_lib.h>
+
+extern int processSamples(short *wav, int numsamples, espeak_EVENT *events);
+*/
+import "C"
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+	"unsafe"
+
+	"github.com/djangulo/go-espeak/resample"
+	"github.com/djangulo/go-espeak/wav"
+)
+
+// config is the set of arguments espeak_Initialize is called with.
+type config struct {
+	output       AudioOutput
+	bufferLength int
+	path         string
+	options      InitOption
+}
+
+// library tracks the configuration the process wide espeak library was last
+// initialized with. espeak only holds a single configuration at a time, so
+// every Synthesizer re-initializes it whenever its own config differs.
+// It must only be accessed from the engine.
+type library struct {
+	initialized bool
+	config      config
+	sampleRate  int32
+	// open number of Synthesizers that have not been closed.
+	open int
+}
+
+var lib = &library{}
+
+// load initializes espeak with c, unless it already is.
+func (l *library) load(c config) (int32, error) {
+	if l.initialized && l.config == c {
+		return l.sampleRate, nil
+	}
+	var cPath *C.char
+	if c.path != "" {
+		cPath = C.CString(c.path)
+		defer C.free(unsafe.Pointer(cPath))
+	}
+	sr := C.espeak_Initialize(
+		c.output.toC(),
+		C.int(c.bufferLength),
+		cPath,
+		C.int(c.options&(PhonemeEvents|PhonemeIPA)),
+	)
+	if int(sr) == -1 {
+		l.initialized = false
+		return 0, EErrInternal
+	}
+	l.initialized = true
+	l.config = c
+	l.sampleRate = int32(sr)
+	return l.sampleRate, nil
+}
+
+// terminate calls espeak_Terminate.
+func (l *library) terminate() error {
+	l.initialized = false
+	return ErrFromCode(C.espeak_Terminate())
+}
+
+// Synthesizer is an espeak session. It owns its output mode, voice,
+// parameters and sample rate, and applies them to espeak on every call, so
+// synthesizers with different configurations can be used side by side.
+// It is safe for concurrent use by multiple goroutines.
+//
+// Voice and params arguments to its methods override the synthesizer's own
+// for that call only; nil means use the synthesizer's.
+type Synthesizer struct {
+	mu         sync.Mutex
+	config     config
+	voice      *Voice
+	params     *Parameters
+	sampleRate int32
+	closed     bool
+	// languageVoices the voice of each language, see AutoVoice.
+	languageVoices map[string]*Voice
+}
+
+// NewSynthesizer initializes espeak and returns a *Synthesizer using
+// DefaultVoice and default parameters. Arguments are the same as Init's.
+// Close should be called once the synthesizer is no longer needed.
+func NewSynthesizer(
+	output AudioOutput,
+	bufferLength int,
+	path *string,
+	options InitOption,
+) (*Synthesizer, error) {
+	if bufferLength == 0 {
+		bufferLength = 200
+	}
+	c := config{output: output, bufferLength: bufferLength, options: options}
+	if path != nil {
+		c.path = *path
+	}
+
+	var sr int32
+	err := eng.do(func() (err error) {
+		if sr, err = lib.load(c); err != nil {
+			return err
+		}
+		lib.open++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Synthesizer{
+		config:     c,
+		voice:      DefaultVoice,
+		params:     NewParameters(),
+		sampleRate: sr,
+	}, nil
+}
+
+// Close releases the synthesizer. espeak is terminated once all
+// synthesizers are closed. Using s after Close returns ErrClosed.
+func (s *Synthesizer) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrClosed
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	return eng.do(func() error {
+		lib.open--
+		if lib.open == 0 && lib.initialized {
+			return lib.terminate()
+		}
+		return nil
+	})
+}
+
+// Voice returns the synthesizer's voice.
+func (s *Synthesizer) Voice() *Voice {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.voice
+}
+
+// SetVoice sets the voice used when none is passed to a method. If v is nil,
+// DefaultVoice is used.
+func (s *Synthesizer) SetVoice(v *Voice) {
+	if v == nil {
+		v = DefaultVoice
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.voice = v
+}
+
+// Parameters returns the synthesizer's parameters.
+func (s *Synthesizer) Parameters() *Parameters {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.params
+}
+
+// SetParameters sets the parameters used when none are passed to a method.
+// If p is nil, default parameters are used.
+func (s *Synthesizer) SetParameters(p *Parameters) {
+	if p == nil {
+		p = NewParameters()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.params = p
+}
+
+// SampleRate returns the sample rate of the audio produced by s.
+func (s *Synthesizer) SampleRate() int32 {
+	return s.sampleRate
+}
+
+// Output returns the AudioOutput s was created with.
+func (s *Synthesizer) Output() AudioOutput {
+	return s.config.output
+}
+
+// Options returns the InitOptions s was created with.
+func (s *Synthesizer) Options() InitOption {
+	return s.config.options
+}
+
+// resolve returns the voice and params to use for a call, falling back to
+// the synthesizer's own. It returns ErrClosed if s has been closed.
+func (s *Synthesizer) resolve(voice *Voice, params *Parameters) (*Voice, *Parameters, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, nil, ErrClosed
+	}
+	if voice == nil {
+		voice = s.voice
+	}
+	if params == nil {
+		params = s.params
+	}
+	return voice, params, nil
+}
+
+// plays returns whether s sends audio to the sound card rather than to the
+// synth callback.
+func (s *Synthesizer) plays() bool {
+	return s.config.output == Playback || s.config.output == SynchPlayback
+}
+
+func (s *Synthesizer) useMbrola() bool {
+	return s.config.options&UseMbrola == UseMbrola
+}
+
+// activate loads the synthesizer's config into espeak, along with voice and
+// params. It must be called from the engine.
+func (s *Synthesizer) activate(voice *Voice, params *Parameters) error {
+	if _, err := lib.load(s.config); err != nil {
+		return err
+	}
+	if err := params.setVoiceParams(); err != nil {
+		return err
+	}
+	if voice.Name == "" {
+		// a spec, for espeak to pick the voice by.
+		return setVoiceByProps(voice)
+	}
+	return setVoiceByName(voice.Name)
+}
+
+// Synth activates the synthesizer's voice and parameters and calls
+// espeak_Synth. Arguments are the same as the package level Synth. It does
+// not wait for synthesis to finish, see Synchronize.
+func (s *Synthesizer) Synth(
+	text string,
+	flags FlagType,
+	startPos, endPos uint32,
+	posType PositionType,
+	uniqueIdent *uint64,
+	userData unsafe.Pointer,
+) error {
+	if text == "" {
+		return ErrEmptyText
+	}
+	voice, params, err := s.resolve(nil, nil)
+	if err != nil {
+		return err
+	}
+	return eng.do(func() error {
+		if err := s.activate(voice, params); err != nil {
+			return err
+		}
+		return synth(text, flags, startPos, endPos, posType, uniqueIdent, userData)
+	})
+}
+
+// GenSamples generates a []int16 sample slice containing the data of text,
+// using voice, modified by params. Returns ErrOutputMode if s plays audio
+// instead of retrieving it.
+func (s *Synthesizer) GenSamples(text string, voice *Voice, params *Parameters) ([]int16, error) {
+	return s.GenSamplesContext(context.Background(), text, voice, params)
+}
+
+// GenSamplesContext is like GenSamples, but stops synthesis and returns
+// ctx.Err() if ctx is done before it finishes.
+func (s *Synthesizer) GenSamplesContext(ctx context.Context, text string, voice *Voice, params *Parameters) ([]int16, error) {
+	r, err := s.synthesizeResult(ctx, text, CharsAuto|EndPause, voice, params)
+	if err != nil {
+		return nil, err
+	}
+	return r.Samples, nil
+}
+
+// Result of a synthesis.
+type Result struct {
+	// Samples 16 bit mono PCM.
+	Samples []int16
+	// Events reported by espeak while synthesizing, in order.
+	Events []Event
+	// Words timings of the words of the text, see AlignWords.
+	Words []WordTiming
+	// SampleRate of Samples.
+	SampleRate int32
+	// Voice the text was spoken with, the one picked by AutoVoice, if set.
+	// That of the first segment, for SynthSegments and SynthMixed.
+	Voice *Voice
+}
+
+// Synthesize is like GenSamplesContext, but also returns the events espeak
+// reported. Phoneme events are only reported if s was created with
+// PhonemeEvents.
+func (s *Synthesizer) Synthesize(ctx context.Context, text string, voice *Voice, params *Parameters) (*Result, error) {
+	return s.synthesizeResult(ctx, text, CharsAuto|EndPause, voice, params)
+}
+
+func (s *Synthesizer) synthesizeResult(
+	ctx context.Context,
+	text string,
+	flags FlagType,
+	voice *Voice,
+	params *Parameters,
+) (*Result, error) {
+	if text == "" {
+		return nil, ErrEmptyText
+	}
+	voice, params, err := s.resolveText(text, flags, voice, params)
+	if err != nil {
+		return nil, err
+	}
+	if s.plays() {
+		return nil, ErrOutputMode
+	}
+
+	rs, err := s.resampler(params)
+	if err != nil {
+		return nil, err
+	}
+
+	rw, err := rewriteText(text, flags, voice, params)
+	if err != nil {
+		return nil, err
+	}
+
+	id, j := registry.newJob(ctx.Done(), nil)
+	defer registry.removeData(id)
+	j.remap = rw.remap()
+
+	if err := s.synthesize(ctx, id, j, rw.text, flags|rw.flags, voice, params); err != nil {
+		return nil, err
+	}
+	r := &Result{SampleRate: s.sampleRate, Voice: voice}
+	r.Samples, r.Events = j.result()
+	if rs != nil {
+		r.Samples = append(rs.ProcessInt16(r.Samples), rs.FlushInt16()...)
+		resampleEvents(r.Events, s.sampleRate, rs.OutRate())
+		r.SampleRate = rs.OutRate()
+	}
+	r.Words = AlignWords(text, r.Events, r.SampleRate, len(r.Samples))
+	return r, nil
+}
+
+// outputRate returns the sample rate of audio synthesized with params.
+func (s *Synthesizer) outputRate(params *Parameters) int32 {
+	if params.SampleRate != 0 {
+		return params.SampleRate
+	}
+	return s.sampleRate
+}
+
+// resampler returns a *resample.Resampler from s's sample rate to the one
+// requested by params, or nil if there's nothing to convert.
+func (s *Synthesizer) resampler(params *Parameters) (*resample.Resampler, error) {
+	if params.SampleRate == 0 || params.SampleRate == s.sampleRate {
+		return nil, nil
+	}
+	return resample.New(s.sampleRate, params.SampleRate, 1, params.ResampleQuality)
+}
+
+// resampleEvents converts the sample positions of events from inRate to
+// outRate.
+func resampleEvents(events []Event, inRate, outRate int32) {
+	for i := range events {
+		events[i].Sample = resample.Position(events[i].Sample, inRate, outRate)
+	}
+}
+
+// synthesize runs text through espeak, with processSamples feeding the
+// samples to j, registered as id. Valid UTF-8 text is flagged as such,
+// rather than leaving it to espeak to guess.
+func (s *Synthesizer) synthesize(
+	ctx context.Context,
+	id uintptr,
+	j *job,
+	text string,
+	flags FlagType,
+	voice *Voice,
+	params *Parameters,
+) error {
+	if flags&0xf == CharsAuto && utf8.ValidString(text) {
+		flags |= CharsUTF8
+	}
+	return eng.doContext(ctx, func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.activate(voice, params); err != nil {
+			return err
+		}
+		C.espeak_SetSynthCallback((*C.t_espeak_callback)(C.processSamples))
+		if err := synth(
+			text,
+			flags,
+			0,
+			0,
+			Character,
+			nil,
+			unsafe.Pointer(&id)); err != nil {
+			return err
+		}
+		err := synchronize()
+		if j.wasAborted() {
+			// flush whatever espeak still has queued.
+			C.espeak_Cancel()
+			return ctx.Err()
+		}
+		return err
+	})
+}
+
+// TextToSpeech reproduces text, using voice, modified by params.
+// If outfile is an empty string or "play", the audio is spoken to the system
+// default's audio output, which requires s to have been created with
+// Playback or SynchPlayback. Otherwise outfile is appended with .wav and
+// saved to params.Dir/outfile[.wav]. Returns the number of bytes written to
+// file, if any.
+func (s *Synthesizer) TextToSpeech(text string, voice *Voice, outfile string, params *Parameters) (uint64, error) {
+	return s.TextToSpeechContext(context.Background(), text, voice, outfile, params)
+}
+
+// TextToSpeechContext is like TextToSpeech, but stops synthesis (or
+// playback) and returns ctx.Err() if ctx is done before it finishes. The
+// partially written file is removed in that case.
+func (s *Synthesizer) TextToSpeechContext(ctx context.Context, text string, voice *Voice, outfile string, params *Parameters) (uint64, error) {
+	if text == "" {
+		return 0, ErrEmptyText
+	}
+	voice, params, err := s.resolveText(text, CharsAuto, voice, params)
+	if err != nil {
+		return 0, err
+	}
+	if outfile == "" || outfile == "play" {
+		if !s.plays() {
+			return 0, ErrOutputMode
+		}
+		rw, err := rewriteText(text, CharsAuto, voice, params)
+		if err != nil {
+			return 0, err
+		}
+		return 0, eng.doContext(ctx, func() error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := s.activate(voice, params); err != nil {
+				return err
+			}
+			stop := cancelOnDone(ctx)
+			defer stop()
+			if err := synth(
+				rw.text,
+				CharsAuto|EndPause|rw.flags,
+				0,
+				0,
+				Character,
+				nil,
+				nil); err != nil {
+				return err
+			}
+			if err := synchronize(); err != nil {
+				return err
+			}
+			return ctx.Err()
+		})
+	}
+
+	outfile = ensureWavSuffix(outfile)
+	if err := os.MkdirAll(params.Dir, 0755); err != nil {
+		return 0, err
+	}
+	path := filepath.Join(params.Dir, outfile)
+	fh, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	written, err := s.writeWav(ctx, fh, text, voice, params)
+	if cerr := fh.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+	return written, nil
+}
+
+// writeWav synthesizes text into out, as a .wav file, writing the samples as
+// espeak produces them.
+func (s *Synthesizer) writeWav(ctx context.Context, out io.Writer, text string, voice *Voice, params *Parameters) (uint64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	chunks, err := s.SynthChunks(ctx, text, voice, params)
+	if err != nil {
+		return 0, err
+	}
+	w := wav.NewWriter(out, s.outputRate(params))
+	for chunk := range chunks {
+		switch {
+		case err != nil:
+			// draining, synthesis stops once cancelled.
+		case chunk.Err != nil:
+			err = chunk.Err
+		default:
+			if _, err = w.WriteSamples(chunk.Samples); err != nil {
+				cancel()
+			}
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := w.Close(); err != nil {
+		return 0, err
+	}
+	return w.Size(), nil
+}
+
+// ListVoices reads the voice files from espeak-data/voices and returns them
+// in a []*Voice object. If spec is nil, all available voices are listed.
+// If spec is given, then only the voices which are compatible with the spec
+// are listed, and they are listed in preference order. mbrola voices are
+// only listed if s was created with UseMbrola.
+func (s *Synthesizer) ListVoices(spec *Voice) ([]*Voice, error) {
+	if _, _, err := s.resolve(nil, nil); err != nil {
+		return nil, err
+	}
+	voices := make([]*Voice, 0)
+	err := eng.do(func() error {
+		if _, err := lib.load(s.config); err != nil {
+			return err
+		}
+		var voiceSpec *C.espeak_VOICE
+		if spec != nil {
+			voiceSpec = spec.cptr()
+		}
+		// out is Ctype const espeak_VOICE ** (pointer to array), owned by
+		// espeak.
+		out := C.espeak_ListVoices(voiceSpec)
+		// slice-ification of a C.espeak_VOICE ** into a slice
+		//     (*C.espeak_VOICE)(unsafe.Pointer(out)) gets the actual array
+		//     length is fixed at a 1000 as we don't know how many return
+		//     espeak_ListVoices returns a NULL terminated array
+		cVoices := (*[1 << 28]*C.espeak_VOICE)(
+			unsafe.Pointer(
+				(*C.espeak_VOICE)(unsafe.Pointer(out))))[:1000:1000]
+		for _, cv := range cVoices {
+			if cv == nil {
+				break
+			}
+			if !s.useMbrola() && strings.HasPrefix(C.GoString(cv.identifier), "mb") {
+				continue
+			}
+			voices = append(voices, voiceFromCptr(unsafe.Pointer(cv)))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return voices, nil
+}
+
+// VoiceFromSpec returns a random Voice from the group of voices that matches
+// spec. Is spec is nil, returns a random voice.
+func (s *Synthesizer) VoiceFromSpec(spec *Voice) (*Voice, error) {
+	candidates, err := s.ListVoices(spec)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, EErrNotFound
+	}
+	return candidates[rand.Intn(len(candidates))], nil
+}
+
+// FindVoice returns the installed voice called name, of identifier name,
+// e.g. "europe/es", or the one espeak prefers of language name, e.g. "es".
+// The error wraps EErrNotFound if there's none.
+func (s *Synthesizer) FindVoice(name string) (*Voice, error) {
+	voices, err := s.ListVoices(nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range voices {
+		if v.Name == name || v.Identifier == name {
+			return v, nil
+		}
+	}
+	voice, err := s.languageVoice(name)
+	if err != nil {
+		return nil, err
+	}
+	if voice == nil {
+		return nil, fmt.Errorf("%w: voice %q", EErrNotFound, name)
+	}
+	return voice, nil
+}
+
+// defaults backs the package level functions.
+var defaults struct {
+	sync.Mutex
+	synthesizer *Synthesizer
+	player      *Synthesizer
+}
+
+// defaultSynthesizer returns the package's Synchronous synthesizer,
+// creating it if needed.
+func defaultSynthesizer() (*Synthesizer, error) {
+	defaults.Lock()
+	defer defaults.Unlock()
+	if defaults.synthesizer == nil {
+		s, err := NewSynthesizer(Synchronous, 200, nil, PhonemeEvents)
+		if err != nil {
+			return nil, err
+		}
+		defaults.synthesizer = s
+	}
+	return defaults.synthesizer, nil
+}
+
+// defaultPlayer returns the package's Playback synthesizer, creating it if
+// needed.
+func defaultPlayer() (*Synthesizer, error) {
+	defaults.Lock()
+	defer defaults.Unlock()
+	if defaults.player == nil {
+		s, err := NewSynthesizer(Playback, -1, nil, PhonemeEvents)
+		if err != nil {
+			return nil, err
+		}
+		defaults.player = s
+	}
+	return defaults.player, nil
+}
@@ -0,0 +1,392 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package espeak
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/djangulo/go-espeak/lexicon"
+	"github.com/djangulo/go-espeak/normalize"
+	"github.com/djangulo/go-espeak/resample"
+	"github.com/djangulo/go-espeak/ssml"
+	"github.com/djangulo/go-espeak/wav"
+)
+
+func TestSynthesizer(t *testing.T) {
+	s, err := NewSynthesizer(Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.SampleRate() <= 0 {
+		t.Errorf("expected a positive sample rate, got %d", s.SampleRate())
+	}
+
+	t.Run("GenSamples", func(t *testing.T) {
+		samples, err := s.GenSamples("test speech", nil, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(samples) == 0 {
+			t.Errorf("0 samples generated")
+		}
+	})
+	t.Run("TextToSpeech", func(t *testing.T) {
+		tmp, err := ioutil.TempDir("", "go-espeak-synthesizer-test-*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(tmp)
+		written, err := s.TextToSpeech("test speech", ESSpainMale, "test", NewParameters(WithDir(tmp)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if written == 0 {
+			t.Errorf("0 bytes written")
+		}
+	})
+	t.Run("play on a Synchronous synthesizer", func(t *testing.T) {
+		_, err := s.TextToSpeech("test speech", nil, "play", nil)
+		if !errors.Is(err, ErrOutputMode) {
+			t.Errorf("expected %v got %v", ErrOutputMode, err)
+		}
+	})
+	t.Run("ListVoices", func(t *testing.T) {
+		voices, err := s.ListVoices(nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(voices) == 0 {
+			t.Errorf("no voices listed")
+		}
+	})
+	t.Run("voice and parameters", func(t *testing.T) {
+		p := NewParameters(WithRate(300))
+		s.SetVoice(FRFranceMale)
+		s.SetParameters(p)
+		if s.Voice() != FRFranceMale {
+			t.Errorf("expected voice %v got %v", FRFranceMale, s.Voice())
+		}
+		if s.Parameters() != p {
+			t.Errorf("expected parameters %v got %v", p, s.Parameters())
+		}
+		s.SetVoice(nil)
+		if s.Voice() != DefaultVoice {
+			t.Errorf("expected voice %v got %v", DefaultVoice, s.Voice())
+		}
+	})
+}
+
+func TestSynthesizer_Close(t *testing.T) {
+	s, err := NewSynthesizer(Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, tt := range []struct {
+		name string
+		fn   func() error
+	}{
+		{"Close", s.Close},
+		{"GenSamples", func() error { _, err := s.GenSamples("test", nil, nil); return err }},
+		{"TextToSpeech", func() error { _, err := s.TextToSpeech("test", nil, "test", nil); return err }},
+		{"ListVoices", func() error { _, err := s.ListVoices(nil); return err }},
+		{"Synth", func() error { return s.Synth("test", CharsAuto, 0, 0, Character, nil, nil) }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fn(); !errors.Is(err, ErrClosed) {
+				t.Errorf("expected %v got %v", ErrClosed, err)
+			}
+		})
+	}
+}
+
+func TestSynthesizer_GenSamplesContext(t *testing.T) {
+	s, err := NewSynthesizer(Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		samples, err := s.GenSamplesContext(ctx, "test speech", nil, nil)
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected %v got %v", context.Canceled, err)
+		}
+		if samples != nil {
+			t.Errorf("expected no samples, got %d", len(samples))
+		}
+	})
+	t.Run("deadline exceeded", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+		defer cancel()
+		time.Sleep(time.Millisecond)
+		_, err := s.TextToSpeechContext(ctx, strings.Repeat("test speech ", 1000), nil, "test", nil)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected %v got %v", context.DeadlineExceeded, err)
+		}
+	})
+	t.Run("registry released", func(t *testing.T) {
+		registry.Lock()
+		n := len(registry.jobs)
+		registry.Unlock()
+		if n != 0 {
+			t.Errorf("expected registry to be empty, has %d jobs", n)
+		}
+	})
+	t.Run("not cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		samples, err := s.GenSamplesContext(ctx, "test speech", nil, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(samples) == 0 {
+			t.Errorf("0 samples generated")
+		}
+	})
+}
+
+func TestSynthesizer_sampleRate(t *testing.T) {
+	s, err := NewSynthesizer(Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	text := "test speech, at another sample rate"
+	native, err := s.Synthesize(context.Background(), text, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := NewParameters(WithSampleRate(8000), WithResampleQuality(resample.High))
+	// expected length, rounded up.
+	want := (len(native.Samples)*8000 + int(s.SampleRate()) - 1) / int(s.SampleRate())
+
+	t.Run("Synthesize", func(t *testing.T) {
+		r, err := s.Synthesize(context.Background(), text, nil, params)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if r.SampleRate != 8000 {
+			t.Errorf("expected sample rate %d got %d", 8000, r.SampleRate)
+		}
+		if len(r.Samples) != want {
+			t.Errorf("expected %d samples got %d", want, len(r.Samples))
+		}
+		for i, e := range r.Events {
+			if w := resample.Position(native.Events[i].Sample, s.SampleRate(), 8000); e.Sample != w {
+				t.Errorf("event %d: expected sample %d got %d", i, w, e.Sample)
+			}
+		}
+	})
+	t.Run("SynthStream", func(t *testing.T) {
+		st, err := s.SynthStream(context.Background(), text, nil, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer st.Close()
+		if st.SampleRate() != 8000 {
+			t.Errorf("expected sample rate %d got %d", 8000, st.SampleRate())
+		}
+		b, err := ioutil.ReadAll(st)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(b)/2 != want {
+			t.Errorf("expected %d samples got %d", want, len(b)/2)
+		}
+	})
+	t.Run("TextToSpeech", func(t *testing.T) {
+		tmp, err := ioutil.TempDir("", "go-espeak-synthesizer-test-*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(tmp)
+		params := *params
+		params.Dir = tmp
+		if _, err := s.TextToSpeech(text, nil, "test", &params); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		fh, err := os.Open(filepath.Join(tmp, "test.wav"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer fh.Close()
+		r, err := wav.NewReader(fh)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if r.SampleRate() != 8000 {
+			t.Errorf("expected sample rate %d got %d", 8000, r.SampleRate())
+		}
+		if r.Frames() != int64(want) {
+			t.Errorf("expected %d samples got %d", want, r.Frames())
+		}
+	})
+	t.Run("invalid", func(t *testing.T) {
+		_, err := s.GenSamples(text, nil, NewParameters(WithSampleRate(-1)))
+		if !errors.Is(err, resample.ErrSampleRate) {
+			t.Errorf("expected %v got %v", resample.ErrSampleRate, err)
+		}
+	})
+}
+
+func TestSynthesizer_lexicon(t *testing.T) {
+	s, err := NewSynthesizer(Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	lex, err := lexicon.New(
+		lexicon.Entry{Word: "GNU", Phonemes: "gn'u:"},
+		lexicon.Entry{Regexp: `(\d+)km`, Replacement: "$1 kilometers", Language: "en"},
+		lexicon.Entry{Word: "niño", Replacement: "ninio", Language: "es"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := NewParameters(WithLexicon(lex))
+	text := "¡GNU runs 10km, niño!"
+	// both words of "10 kilometers" map back to "10km", "niño" is left as is
+	// by an english voice.
+	want := []string{"¡GNU", "runs", "10km", "10km,", "niño!"}
+
+	t.Run("Synthesize", func(t *testing.T) {
+		r, err := s.Synthesize(context.Background(), text, nil, params)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(r.Words) != len(want) {
+			t.Fatalf("expected %d words got %d: %+v", len(want), len(r.Words), r.Words)
+		}
+		for i, w := range r.Words {
+			if w.Text != want[i] {
+				t.Errorf("word %d: expected %q got %q", i, want[i], w.Text)
+			}
+		}
+	})
+	t.Run("SynthChunks", func(t *testing.T) {
+		chunks, err := s.SynthChunks(context.Background(), text, nil, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var events []Event
+		for chunk := range chunks {
+			if chunk.Err != nil {
+				t.Fatalf("unexpected error: %v", chunk.Err)
+			}
+			events = append(events, chunk.Events...)
+		}
+		words := AlignWords(text, events, s.SampleRate(), 0)
+		if len(words) != len(want) {
+			t.Fatalf("expected %d words got %d: %+v", len(want), len(words), words)
+		}
+		for i, w := range words {
+			if w.Text != want[i] {
+				t.Errorf("word %d: expected %q got %q", i, want[i], w.Text)
+			}
+		}
+	})
+	t.Run("language", func(t *testing.T) {
+		r, err := s.Synthesize(context.Background(), "el niño bueno", ESLatinMale, params)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// "ninio", a character longer, maps back to "niño".
+		for i, want := range []string{"el", "niño", "bueno"} {
+			if got := r.Words[i].Text; got != want {
+				t.Errorf("word %d: expected %q got %q", i, want, got)
+			}
+		}
+	})
+}
+
+func TestSynthesizer_normalizer(t *testing.T) {
+	s, err := NewSynthesizer(Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	lex, err := lexicon.New(lexicon.Entry{Word: "GNU", Phonemes: "gn'u:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := NewParameters(WithLexicon(lex), WithNormalizer(normalize.New()))
+	text := "GNU costs $5 today."
+	r, err := s.Synthesize(context.Background(), text, nil, params)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// "five dollars" maps back to "$5".
+	want := []string{"GNU", "costs", "$5", "$5", "today."}
+	if len(r.Words) != len(want) {
+		t.Fatalf("expected %d words got %d: %+v", len(want), len(r.Words), r.Words)
+	}
+	for i, w := range r.Words {
+		if w.Text != want[i] {
+			t.Errorf("word %d: expected %q got %q", i, want[i], w.Text)
+		}
+	}
+}
+
+func TestSynthesizer_SynthSSML(t *testing.T) {
+	s, err := NewSynthesizer(Synchronous, 200, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	doc := ssml.Speak(ssml.S(
+		ssml.Text("Wait 5 "),
+		ssml.BreakTime(500*time.Millisecond),
+		ssml.Mark("here"),
+		ssml.Text("seconds"),
+	))
+
+	t.Run("marks", func(t *testing.T) {
+		r, err := s.SynthSSML(context.Background(), doc, nil, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var marks []string
+		for _, e := range r.Events {
+			if e.Type == MarkEvent {
+				marks = append(marks, e.Name)
+			}
+		}
+		if len(marks) != 1 || marks[0] != "here" {
+			t.Errorf("expected mark %q got %v", "here", marks)
+		}
+	})
+	t.Run("normalizer skips markup", func(t *testing.T) {
+		params := NewParameters(WithNormalizer(normalize.New()))
+		r, err := s.SynthSSML(context.Background(), doc, nil, params)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var words []string
+		for _, w := range r.Words {
+			words = append(words, w.Text)
+		}
+		// "five" maps back to "5", "500ms" is left as is.
+		if len(words) < 2 || words[1] != "5" {
+			t.Errorf("expected the second word to be %q, got %q", "5", words)
+		}
+	})
+	t.Run("unsupported", func(t *testing.T) {
+		bad := ssml.Speak(ssml.Prosody(ssml.Text("hi")).WithRate("fastt"))
+		if _, err := s.SynthSSML(context.Background(), bad, nil, nil); !errors.Is(err, ssml.ErrUnsupported) {
+			t.Errorf("expected %v got %v", ssml.ErrUnsupported, err)
+		}
+	})
+}
@@ -0,0 +1,169 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+// Package textmap keeps track of where the parts of a rewritten text came
+// from, so that offsets into it, e.g. those of the words espeak reports,
+// can be mapped back to the text it was rewritten from.
+package textmap
+
+import (
+	"sort"
+	"strings"
+)
+
+// span a part of the rewritten text and the part of the original it
+// replaces.
+type span struct {
+	out, outLen int
+	in, inLen   int
+	// kept the part was copied as is, its bytes map one to one.
+	kept bool
+}
+
+// Map maps byte offsets of a rewritten text to the text it was rewritten
+// from. A nil *Map maps every offset to itself.
+type Map struct {
+	spans         []span
+	outLen, inLen int
+	// prev maps the original of this one to its own original.
+	prev *Map
+}
+
+// Offset returns the offset in the original text of the byte at off in the
+// rewritten one. Offsets within a replacement map to the start of what it
+// replaced.
+func (m *Map) Offset(off int) int {
+	if m == nil {
+		return off
+	}
+	return m.prev.Offset(m.offset(off))
+}
+
+func (m *Map) offset(off int) int {
+	if off <= 0 {
+		return 0
+	}
+	sp, ok := m.find(off)
+	if !ok {
+		return m.inLen
+	}
+	if sp.kept {
+		return sp.in + off - sp.out
+	}
+	return sp.in
+}
+
+// Range returns the range in the original text of the bytes [start, end) in
+// the rewritten one. A range touching a replacement covers all of what it
+// replaced.
+func (m *Map) Range(start, end int) (int, int) {
+	if m == nil {
+		return start, end
+	}
+	if end <= start {
+		off := m.offset(start)
+		return m.prev.Range(off, off)
+	}
+	s := m.offset(start)
+	e := m.inLen
+	if sp, ok := m.find(end - 1); ok {
+		if sp.kept {
+			e = sp.in + end - sp.out
+		} else {
+			e = sp.in + sp.inLen
+		}
+	}
+	return m.prev.Range(s, e)
+}
+
+// find returns the span holding the byte at off of the rewritten text.
+func (m *Map) find(off int) (span, bool) {
+	i := sort.Search(len(m.spans), func(i int) bool {
+		return m.spans[i].out+m.spans[i].outLen > off
+	})
+	if i == len(m.spans) {
+		return span{}, false
+	}
+	return m.spans[i], true
+}
+
+// Compose returns the Map of a text rewritten by first, then by second.
+// Either may be nil.
+func Compose(first, second *Map) *Map {
+	if first == nil {
+		return second
+	}
+	if second == nil {
+		return first
+	}
+	cp := *second
+	cp.prev = Compose(first, second.prev)
+	return &cp
+}
+
+// Builder builds a rewritten text, along with its Map, walking the original
+// from start to end.
+type Builder struct {
+	buf   strings.Builder
+	spans []span
+	in    int
+}
+
+// Keep copies s, the next part of the original, as is.
+func (b *Builder) Keep(s string) {
+	if s == "" {
+		return
+	}
+	if n := len(b.spans); n > 0 && b.spans[n-1].kept {
+		b.spans[n-1].outLen += len(s)
+		b.spans[n-1].inLen += len(s)
+	} else {
+		b.spans = append(b.spans, span{out: b.buf.Len(), outLen: len(s), in: b.in, inLen: len(s), kept: true})
+	}
+	b.buf.WriteString(s)
+	b.in += len(s)
+}
+
+// Replace writes repl in place of orig, the next part of the original. An
+// empty orig inserts repl, an empty repl deletes orig.
+func (b *Builder) Replace(orig, repl string) {
+	if orig == "" && repl == "" {
+		return
+	}
+	b.spans = append(b.spans, span{out: b.buf.Len(), outLen: len(repl), in: b.in, inLen: len(orig)})
+	b.buf.WriteString(repl)
+	b.in += len(orig)
+}
+
+// Rewrite writes repl in place of orig, the next part of the original, m
+// mapping repl to orig. A nil m keeps orig as is, ignoring repl. If m is
+// itself composed, repl replaces orig as a whole.
+func (b *Builder) Rewrite(orig, repl string, m *Map) {
+	switch {
+	case m == nil:
+		b.Keep(orig)
+		return
+	case m.prev != nil:
+		b.Replace(orig, repl)
+		return
+	}
+	for _, sp := range m.spans {
+		if sp.kept {
+			b.Keep(orig[sp.in : sp.in+sp.inLen])
+		} else {
+			b.Replace(orig[sp.in:sp.in+sp.inLen], repl[sp.out:sp.out+sp.outLen])
+		}
+	}
+}
+
+// String returns the rewritten text.
+func (b *Builder) String() string {
+	return b.buf.String()
+}
+
+// Map returns the Map of the rewritten text to the original.
+func (b *Builder) Map() *Map {
+	spans := make([]span, len(b.spans))
+	copy(spans, b.spans)
+	return &Map{spans: spans, outLen: b.buf.Len(), inLen: b.in}
+}
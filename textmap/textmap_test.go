@@ -0,0 +1,112 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package textmap
+
+import "testing"
+
+func TestBuilder(t *testing.T) {
+	// "I live at 12 Main St." -> "I live at twelve Main Street."
+	orig := "I live at 12 Main St."
+	var b Builder
+	b.Keep("I live at ")
+	b.Replace("12", "twelve")
+	b.Keep(" Main ")
+	b.Replace("St.", "Street")
+	b.Replace("", ".")
+	out := b.String()
+	if want := "I live at twelve Main Street."; out != want {
+		t.Fatalf("expected %q got %q", want, out)
+	}
+	m := b.Map()
+
+	for _, tt := range []struct {
+		name       string
+		start, end int
+		want       string
+	}{
+		{"kept", 2, 6, "live"},
+		{"replacement", 10, 16, "12"},
+		{"within replacement", 12, 14, "12"},
+		{"across", 7, 16, "at 12"},
+		{"after replacement", 17, 21, "Main"},
+		{"last", 22, 28, "St."},
+		{"insertion", 28, 29, ""},
+		{"empty", 2, 2, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s, e := m.Range(tt.start, tt.end)
+			if got := orig[s:e]; got != tt.want {
+				t.Errorf("%q: expected %q got %q (%d, %d)", out[tt.start:tt.end], tt.want, got, s, e)
+			}
+		})
+	}
+
+	if got := m.Offset(len(out)); got != len(orig) {
+		t.Errorf("end: expected %d got %d", len(orig), got)
+	}
+}
+
+func TestCompose(t *testing.T) {
+	// "Dr. 5" -> "Dr. five" -> "Doctor five"
+	var b1 Builder
+	b1.Keep("Dr. ")
+	b1.Replace("5", "five")
+	var b2 Builder
+	b2.Replace("Dr.", "Doctor")
+	b2.Keep(" five")
+	m := Compose(b1.Map(), b2.Map())
+
+	orig := "Dr. 5"
+	for _, tt := range []struct {
+		start, end int
+		want       string
+	}{
+		{0, 6, "Dr."},
+		{7, 11, "5"},
+		{0, 11, "Dr. 5"},
+	} {
+		s, e := m.Range(tt.start, tt.end)
+		if got := orig[s:e]; got != tt.want {
+			t.Errorf("[%d, %d): expected %q got %q", tt.start, tt.end, tt.want, got)
+		}
+	}
+
+	var nilMap *Map
+	if s, e := nilMap.Range(3, 5); s != 3 || e != 5 {
+		t.Errorf("nil map: expected 3, 5 got %d, %d", s, e)
+	}
+	if Compose(nil, m) != m || Compose(m, nil) != m {
+		t.Error("composing with nil should return the other map")
+	}
+}
+
+func TestBuilder_Rewrite(t *testing.T) {
+	// "<s>Dr. 5</s>", rewriting the text between the tags.
+	var inner Builder
+	inner.Replace("Dr.", "Doctor")
+	inner.Keep(" ")
+	inner.Replace("5", "five")
+	var b Builder
+	b.Keep("<s>")
+	b.Rewrite("Dr. 5", inner.String(), inner.Map())
+	b.Rewrite("</s>", "ignored", nil)
+	out := b.String()
+	if want := "<s>Doctor five</s>"; out != want {
+		t.Fatalf("expected %q got %q", want, out)
+	}
+	orig := "<s>Dr. 5</s>"
+	m := b.Map()
+	for _, tt := range []struct {
+		start, end int
+		want       string
+	}{
+		{3, 9, "Dr."},
+		{10, 14, "5"},
+		{14, 18, "</s>"},
+	} {
+		s, e := m.Range(tt.start, tt.end)
+		if got := orig[s:e]; got != tt.want {
+			t.Errorf("[%d, %d): expected %q got %q", tt.start, tt.end, tt.want, got)
+		}
+	}
+}
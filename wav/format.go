@@ -0,0 +1,233 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// FormatTag .wav format code, as found in the fmt chunk.
+type FormatTag uint16
+
+const (
+	// PCM integer samples.
+	PCM FormatTag = 1
+	// IEEEFloat floating point samples.
+	IEEEFloat FormatTag = 3
+	// ALaw G.711 A-law.
+	ALaw FormatTag = 6
+	// MuLaw G.711 mu-law.
+	MuLaw FormatTag = 7
+	// Extensible WAVE_FORMAT_EXTENSIBLE, the actual format is in the fmt
+	// chunk's extension.
+	Extensible FormatTag = 0xfffe
+)
+
+func (t FormatTag) String() string {
+	switch t {
+	case PCM:
+		return "PCM"
+	case IEEEFloat:
+		return "IEEE float"
+	case ALaw:
+		return "A-law"
+	case MuLaw:
+		return "mu-law"
+	case Extensible:
+		return "extensible"
+	default:
+		return fmt.Sprintf("unknown (%#x)", uint16(t))
+	}
+}
+
+// Format of the audio in a .wav file.
+type Format struct {
+	// Tag the sample encoding. Extensible files report their sub format.
+	Tag FormatTag
+	// Channels number of interleaved channels.
+	Channels int
+	// SampleRate in Hz.
+	SampleRate int32
+	// BitsPerSample size of a single channel's sample.
+	BitsPerSample int
+}
+
+// blockAlign size in bytes of a frame (a sample of every channel).
+func (f Format) blockAlign() int {
+	return f.Channels * f.BitsPerSample / 8
+}
+
+func (f Format) validate() error {
+	if f.Channels < 1 {
+		return fmt.Errorf("%w: %d channels", ErrUnsupportedFormat, f.Channels)
+	}
+	if f.blockAlign() > math.MaxUint16 {
+		return fmt.Errorf("%w: %d channels", ErrUnsupportedFormat, f.Channels)
+	}
+	if f.SampleRate < 1 {
+		return fmt.Errorf("%w: sample rate %d", ErrUnsupportedFormat, f.SampleRate)
+	}
+	ok := false
+	switch f.Tag {
+	case PCM:
+		ok = f.BitsPerSample == 8 || f.BitsPerSample == 16 ||
+			f.BitsPerSample == 24 || f.BitsPerSample == 32
+	case IEEEFloat:
+		ok = f.BitsPerSample == 32 || f.BitsPerSample == 64
+	case ALaw, MuLaw:
+		ok = f.BitsPerSample == 8
+	}
+	if !ok {
+		return fmt.Errorf("%w: %d bit %s", ErrUnsupportedFormat, f.BitsPerSample, f.Tag)
+	}
+	return nil
+}
+
+// PCM16 returns the format of espeak's samples, 16 bit mono PCM, at
+// sampleRate.
+func PCM16(sampleRate int32) Format {
+	return Format{Tag: PCM, Channels: 1, SampleRate: sampleRate, BitsPerSample: 16}
+}
+
+// subFormatGUID the KSDATAFORMAT_SUBTYPE GUID of an extensible fmt chunk,
+// after its first 2 bytes, which hold the format tag.
+const subFormatGUID = "\x00\x00\x00\x00\x10\x00\x80\x00\x00\xaa\x00\x38\x9b\x71"
+
+// channelMasks speaker positions of the usual channel layouts.
+var channelMasks = map[int]uint32{
+	1: 0x4,   // front center
+	2: 0x3,   // front left, right
+	4: 0x33,  // quad
+	6: 0x3f,  // 5.1
+	8: 0x63f, // 7.1
+}
+
+// extensible whether f needs a WAVE_FORMAT_EXTENSIBLE fmt chunk, which is
+// the case for more than 2 channels, or PCM deeper than 16 bit.
+func (f Format) extensible() bool {
+	return f.Channels > 2 || (f.Tag == PCM && f.BitsPerSample > 16)
+}
+
+// header builds the header of a .wav file of format f: the RIFF, fmt and,
+// for non-PCM formats, fact chunks, followed by the data chunk's header.
+// Sizes are left as 0, factPos and dataPos are the offsets of the fact and
+// data sizes; factPos is 0 if there is no fact chunk.
+func (f Format) header() (h []byte, factPos, dataPos int) {
+	wh := newWavHeader()
+	binary.LittleEndian.PutUint16(wh[20:22], uint16(f.Tag))
+	binary.LittleEndian.PutUint16(wh[22:24], uint16(f.Channels))
+	wh.writeSampleRate(f.SampleRate)
+	wh.writeByteRate(f.SampleRate * int32(f.blockAlign()))
+	binary.LittleEndian.PutUint16(wh[32:34], uint16(f.blockAlign()))
+	binary.LittleEndian.PutUint16(wh[34:36], uint16(f.BitsPerSample))
+	if f.Tag == PCM && !f.extensible() {
+		return wh[:], 0, 40
+	}
+
+	// cbSize, and the extension it sizes.
+	ext := make([]byte, 2, 24)
+	if f.extensible() {
+		binary.LittleEndian.PutUint16(wh[20:22], uint16(Extensible))
+		ext = ext[:24]
+		binary.LittleEndian.PutUint16(ext[0:2], 22)
+		binary.LittleEndian.PutUint16(ext[2:4], uint16(f.BitsPerSample))
+		binary.LittleEndian.PutUint32(ext[4:8], channelMasks[f.Channels])
+		binary.LittleEndian.PutUint16(ext[8:10], uint16(f.Tag))
+		copy(ext[10:], subFormatGUID)
+	}
+	binary.LittleEndian.PutUint32(wh[16:20], uint32(16+len(ext)))
+	h = append(wh[:36:36], ext...)
+	if f.Tag != PCM {
+		h = append(h, 'f', 'a', 'c', 't', 4, 0, 0, 0, 0, 0, 0, 0)
+		factPos = len(h) - 4
+	}
+	h = append(h, 'd', 'a', 't', 'a', 0, 0, 0, 0)
+	return h, factPos, len(h) - 4
+}
+
+// encodeInt16 appends s to dst, converted to f's encoding.
+func (f Format) encodeInt16(dst []byte, s []int16) []byte {
+	for _, v := range s {
+		switch f.Tag {
+		case PCM:
+			switch f.BitsPerSample {
+			case 8:
+				dst = append(dst, byte(int(v>>8)+128))
+			case 16:
+				dst = append(dst, byte(v), byte(v>>8))
+			case 24:
+				dst = append(dst, 0, byte(v), byte(v>>8))
+			default:
+				dst = append(dst, 0, 0, byte(v), byte(v>>8))
+			}
+		case IEEEFloat:
+			dst = f.appendFloat(dst, float64(v)/(1<<15))
+		case ALaw:
+			dst = append(dst, linearToAlaw(v))
+		case MuLaw:
+			dst = append(dst, linearToMulaw(v))
+		}
+	}
+	return dst
+}
+
+// encodeFloat32 appends s, in the range [-1, 1), to dst, converted to f's
+// encoding. Integer formats clip samples out of range.
+func (f Format) encodeFloat32(dst []byte, s []float32) []byte {
+	for _, v := range s {
+		switch f.Tag {
+		case PCM:
+			max := float64(int64(1)<<(f.BitsPerSample-1) - 1)
+			x := math.Round(float64(v) * (max + 1))
+			switch {
+			case x > max:
+				x = max
+			case x < -max-1, math.IsNaN(x):
+				x = -max - 1
+			}
+			i := int32(x)
+			switch f.BitsPerSample {
+			case 8:
+				dst = append(dst, byte(i+128))
+			case 16:
+				dst = append(dst, byte(i), byte(i>>8))
+			case 24:
+				dst = append(dst, byte(i), byte(i>>8), byte(i>>16))
+			default:
+				dst = append(dst, byte(i), byte(i>>8), byte(i>>16), byte(i>>24))
+			}
+		case IEEEFloat:
+			dst = f.appendFloat(dst, float64(v))
+		case ALaw:
+			dst = append(dst, linearToAlaw(floatToInt16(float64(v))))
+		case MuLaw:
+			dst = append(dst, linearToMulaw(floatToInt16(float64(v))))
+		}
+	}
+	return dst
+}
+
+func (f Format) appendFloat(dst []byte, v float64) []byte {
+	var b [8]byte
+	if f.BitsPerSample == 32 {
+		binary.LittleEndian.PutUint32(b[:], math.Float32bits(float32(v)))
+		return append(dst, b[:4]...)
+	}
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(v))
+	return append(dst, b[:]...)
+}
+
+// Upmix copies every sample of mono into channels interleaved channels, e.g.
+// to write espeak's output as stereo.
+func Upmix(mono []int16, channels int) []int16 {
+	out := make([]int16, 0, len(mono)*channels)
+	for _, v := range mono {
+		for c := 0; c < channels; c++ {
+			out = append(out, v)
+		}
+	}
+	return out
+}
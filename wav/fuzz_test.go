@@ -0,0 +1,40 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package wav
+
+import (
+	"bytes"
+	"testing"
+)
+
+func FuzzReader(f *testing.F) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, 22050)
+	w.WriteSamples([]int16{0, 1, -1, 1000, -1000})
+	w.Close()
+	f.Add(buf.Bytes())
+	f.Add(riff(fmtChunk(ALaw, 2, 8000, 8), chunk("LIST", []byte("INFOINAM\x01\x00\x00\x00a")), chunk("data", []byte{1, 2})))
+	f.Add(riff(extensibleChunk(IEEEFloat, 1, 8000, 32), chunk("data", make([]byte, 8))))
+	f.Fuzz(func(t *testing.T, in []byte) {
+		r, err := NewReader(bytes.NewReader(in))
+		if err != nil {
+			return
+		}
+		if err := r.Format().validate(); err != nil {
+			t.Fatalf("reader accepted an invalid format: %v", err)
+		}
+		samples, err := r.ReadSamples()
+		frames := len(samples) / r.Channels()
+		if len(samples)%r.Channels() != 0 {
+			t.Fatalf("%d samples is not a whole number of %d channel frames", len(samples), r.Channels())
+		}
+		if err == nil && r.Frames() >= 0 && int64(frames) != r.Frames() {
+			t.Fatalf("read %d frames, header states %d", frames, r.Frames())
+		}
+		r.Info()
+	})
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package wav
+
+// G.711 companding, after the Sun Microsystems reference implementation
+// (g711.c).
+
+const (
+	g711SignBit   = 0x80
+	g711QuantMask = 0x0f
+	g711SegShift  = 4
+	g711SegMask   = 0x70
+	muLawBias     = 0x84
+)
+
+// alawToLinear decodes an A-law byte into a 16 bit sample.
+func alawToLinear(a byte) int16 {
+	a ^= 0x55
+	t := int32(a&g711QuantMask) << 4
+	seg := (a & g711SegMask) >> g711SegShift
+	switch seg {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t += 0x108
+		t <<= seg - 1
+	}
+	if a&g711SignBit != 0 {
+		return int16(t)
+	}
+	return int16(-t)
+}
+
+// mulawToLinear decodes a mu-law byte into a 16 bit sample.
+func mulawToLinear(u byte) int16 {
+	u = ^u
+	t := (int32(u&g711QuantMask) << 3) + muLawBias
+	t <<= (u & g711SegMask) >> g711SegShift
+	if u&g711SignBit != 0 {
+		return int16(muLawBias - t)
+	}
+	return int16(t - muLawBias)
+}
+
+var (
+	alawSegEnds  = [8]int32{0x1f, 0x3f, 0x7f, 0xff, 0x1ff, 0x3ff, 0x7ff, 0xfff}
+	mulawSegEnds = [8]int32{0x3f, 0x7f, 0xff, 0x1ff, 0x3ff, 0x7ff, 0xfff, 0x1fff}
+)
+
+// muLawClip largest magnitude encoded by mu-law, after dropping the 2 least
+// significant bits.
+const muLawClip = 8159
+
+// segment returns the index of the first segment end >= v, or 8.
+func segment(v int32, ends *[8]int32) int32 {
+	for i, end := range ends {
+		if v <= end {
+			return int32(i)
+		}
+	}
+	return 8
+}
+
+// linearToAlaw encodes a 16 bit sample into A-law.
+func linearToAlaw(s int16) byte {
+	v := int32(s) >> 3
+	var mask byte = 0xd5
+	if v < 0 {
+		mask = 0x55
+		v = -v - 1
+	}
+	seg := segment(v, &alawSegEnds)
+	if seg >= 8 {
+		return 0x7f ^ mask
+	}
+	a := byte(seg << g711SegShift)
+	if seg < 2 {
+		a |= byte(v>>1) & g711QuantMask
+	} else {
+		a |= byte(v>>seg) & g711QuantMask
+	}
+	return a ^ mask
+}
+
+// linearToMulaw encodes a 16 bit sample into mu-law.
+func linearToMulaw(s int16) byte {
+	v := int32(s) >> 2
+	var mask byte = 0xff
+	if v < 0 {
+		v = -v
+		mask = 0x7f
+	}
+	if v > muLawClip {
+		v = muLawClip
+	}
+	v += muLawBias >> 2
+	seg := segment(v, &mulawSegEnds)
+	if seg >= 8 {
+		return 0x7f ^ mask
+	}
+	u := byte(seg<<4) | byte(v>>(seg+1))&g711QuantMask
+	return u ^ mask
+}
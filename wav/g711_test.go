@@ -0,0 +1,38 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package wav
+
+import "testing"
+
+func TestG711(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		decode func(byte) int16
+		encode func(int16) byte
+	}{
+		{"A-law", alawToLinear, linearToAlaw},
+		{"mu-law", mulawToLinear, linearToMulaw},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 256; i++ {
+				c := byte(i)
+				v := tt.decode(c)
+				// mu-law has a positive and a negative zero.
+				if v == 0 && c == 0x7f {
+					continue
+				}
+				if got := tt.encode(v); got != c {
+					t.Errorf("code %#x decodes to %d, which encodes to %#x", c, v, got)
+				}
+			}
+			prev := tt.decode(tt.encode(-32768))
+			for v := -32768; v <= 32767; v += 7 {
+				got := tt.decode(tt.encode(int16(v)))
+				if got < prev {
+					t.Fatalf("not monotonic at %d: %d < %d", v, got, prev)
+				}
+				prev = got
+			}
+		})
+	}
+}
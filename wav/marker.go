@@ -0,0 +1,162 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+)
+
+// ErrNotSeekable markers were added to a Writer whose underlying io.Writer
+// can't seek. They're written after the data, which readers of a streamed
+// file, its size unknown, would take as audio.
+var ErrNotSeekable = errors.New("wav: markers need an io.WriteSeeker")
+
+// Marker a cue point, or a region if Length isn't 0, of a .wav file, stored
+// in its cue and LIST adtl chunks. Audio editors, like Audacity or Reaper,
+// show them as labels.
+type Marker struct {
+	// Position frame the marker is at.
+	Position uint32
+	// Length frames the region spans.
+	Length uint32
+	// Label text of the marker.
+	Label string
+}
+
+// AddMarkers adds m to the markers written by Close, after the data.
+// Positions are in frames of the Writer's format.
+func (w *Writer) AddMarkers(m ...Marker) error {
+	if w.closed {
+		return ErrClosed
+	}
+	w.markers = append(w.markers, m...)
+	return nil
+}
+
+// writeMarkers writes the cue and LIST adtl chunks of w's markers.
+func (w *Writer) writeMarkers() {
+	if len(w.markers) == 0 || w.err != nil {
+		return
+	}
+	if w.seeker == nil {
+		w.err = ErrNotSeekable
+		return
+	}
+	w.write(markerChunks(w.markers))
+}
+
+// markerChunks encodes markers as a cue chunk, followed by a LIST adtl
+// chunk of their labels. Cue point ids count from 1.
+func markerChunks(markers []Marker) []byte {
+	le := binary.LittleEndian
+	cue := make([]byte, 12, 12+24*len(markers))
+	copy(cue, "cue ")
+	le.PutUint32(cue[4:], uint32(4+24*len(markers)))
+	le.PutUint32(cue[8:], uint32(len(markers)))
+	list := []byte("LIST\x00\x00\x00\x00adtl")
+	for i, m := range markers {
+		id := uint32(i + 1)
+		var p [24]byte
+		le.PutUint32(p[0:], id)
+		le.PutUint32(p[4:], m.Position)
+		copy(p[8:], "data")
+		le.PutUint32(p[20:], m.Position)
+		cue = append(cue, p[:]...)
+
+		var h [12]byte
+		copy(h[:], "labl")
+		le.PutUint32(h[4:], uint32(4+len(m.Label)+1))
+		le.PutUint32(h[8:], id)
+		list = append(list, h[:]...)
+		list = append(list, m.Label...)
+		list = append(list, 0)
+		if len(m.Label)%2 == 0 {
+			list = append(list, 0)
+		}
+		if m.Length > 0 {
+			// purpose "rgn ", country, language, dialect and code page 0.
+			var l [28]byte
+			copy(l[:], "ltxt")
+			le.PutUint32(l[4:], 20)
+			le.PutUint32(l[8:], id)
+			le.PutUint32(l[12:], m.Length)
+			copy(l[16:], "rgn ")
+			list = append(list, l[:]...)
+		}
+	}
+	le.PutUint32(list[4:], uint32(len(list)-8))
+	return append(cue, list...)
+}
+
+// parseCue reads the cue points of a cue chunk.
+func (wr *Reader) parseCue(b []byte) {
+	if len(b) < 4 {
+		return
+	}
+	n := int(binary.LittleEndian.Uint32(b))
+	b = b[4:]
+	for i := 0; i < n && len(b) >= 24; i++ {
+		id := binary.LittleEndian.Uint32(b)
+		m := wr.marker(id)
+		m.Position = binary.LittleEndian.Uint32(b[20:])
+		b = b[24:]
+	}
+}
+
+// parseAdtl reads the labels and region lengths of a LIST adtl chunk,
+// without its list type.
+func (wr *Reader) parseAdtl(b []byte) {
+	for len(b) >= 12 {
+		id := string(b[0:4])
+		size := int(binary.LittleEndian.Uint32(b[4:8]))
+		b = b[8:]
+		if size < 4 || size > len(b) {
+			return
+		}
+		m := wr.marker(binary.LittleEndian.Uint32(b))
+		switch id {
+		case "labl":
+			m.Label = string(bytes.TrimRight(b[4:size], "\x00"))
+		case "ltxt":
+			if size >= 8 {
+				m.Length = binary.LittleEndian.Uint32(b[4:8])
+			}
+		}
+		if size%2 == 1 && size < len(b) {
+			size++
+		}
+		b = b[size:]
+	}
+}
+
+// marker returns the marker of cue point id, adding it if new.
+func (wr *Reader) marker(id uint32) *Marker {
+	if wr.markers == nil {
+		wr.markers = make(map[uint32]*Marker)
+	}
+	m, ok := wr.markers[id]
+	if !ok {
+		m = &Marker{}
+		wr.markers[id] = m
+	}
+	return m
+}
+
+// Markers returns the markers found so far, by cue point id. Chunks after
+// the data are found once it has been read.
+func (wr *Reader) Markers() []Marker {
+	ids := make([]uint32, 0, len(wr.markers))
+	for id := range wr.markers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	markers := make([]Marker, len(ids))
+	for i, id := range ids {
+		markers[i] = *wr.markers[id]
+	}
+	return markers
+}
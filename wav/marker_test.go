@@ -0,0 +1,93 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package wav
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestWriter_AddMarkers(t *testing.T) {
+	fh, err := ioutil.TempFile("", "go-espeak-wav-test-*.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(fh.Name())
+	defer fh.Close()
+
+	markers := []Marker{
+		{Position: 0, Length: 3, Label: "hello"},
+		{Position: 3, Label: "mark"},
+		{Position: 4, Length: 1, Label: "world!"},
+	}
+	// 8 bit, an odd number of bytes of data, padded before the markers.
+	w, err := NewFormatWriter(fh, Format{Tag: PCM, Channels: 1, SampleRate: 8000, BitsPerSample: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddMarkers(markers[0]); err != nil {
+		t.Fatal(err)
+	}
+	samples := []int16{1, 2, 3, 4, 5}
+	if _, err := w.WriteSamples(samples); err != nil {
+		t.Fatal(err)
+	}
+	w.AddMarkers(markers[1:]...)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddMarkers(Marker{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected %v got %v", ErrClosed, err)
+	}
+
+	data, err := ioutil.ReadFile(fh.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uint64(len(data)) != w.Size() {
+		t.Errorf("expected %d bytes got %d", w.Size(), len(data))
+	}
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.ReadSamples()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(samples) {
+		t.Errorf("expected %d samples got %d", len(samples), len(got))
+	}
+	gotMarkers := r.Markers()
+	if len(gotMarkers) != len(markers) {
+		t.Fatalf("expected %d markers got %d: %+v", len(markers), len(gotMarkers), gotMarkers)
+	}
+	for i := range markers {
+		if gotMarkers[i] != markers[i] {
+			t.Errorf("marker %d: expected %+v got %+v", i, markers[i], gotMarkers[i])
+		}
+	}
+
+	t.Run("before the data", func(t *testing.T) {
+		cues := markerChunks(markers)
+		r, err := NewReader(bytes.NewReader(riff(fmtChunk(PCM, 1, 8000, 16), cues, chunk("data", []byte{1, 0}))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := r.Markers(); len(got) != len(markers) || got[2] != markers[2] {
+			t.Errorf("unexpected markers %+v", got)
+		}
+	})
+	t.Run("not seekable", func(t *testing.T) {
+		var buf bytes.Buffer
+		w := NewWriter(&buf, 8000)
+		w.AddMarkers(markers...)
+		w.WriteSamples(samples)
+		if err := w.Close(); !errors.Is(err, ErrNotSeekable) {
+			t.Errorf("expected %v got %v", ErrNotSeekable, err)
+		}
+	})
+}
@@ -0,0 +1,453 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+
+package wav
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"time"
+)
+
+// Errors
+var (
+	// ErrNotRIFF the data is not a RIFF file.
+	ErrNotRIFF = errors.New("wav: not a RIFF file")
+	// ErrNotWAVE the RIFF file is not of type WAVE.
+	ErrNotWAVE = errors.New("wav: not a WAVE file")
+	// ErrMalformed a chunk is invalid.
+	ErrMalformed = errors.New("wav: malformed file")
+	// ErrNoFormat a data chunk was found before the fmt chunk.
+	ErrNoFormat = errors.New("wav: missing fmt chunk")
+	// ErrNoData the file has no data chunk.
+	ErrNoData = errors.New("wav: missing data chunk")
+	// ErrUnsupportedFormat the audio format can't be read or written.
+	ErrUnsupportedFormat = errors.New("wav: unsupported format")
+)
+
+const (
+	// unknownSize the size used by streamed files, whose length is unknown
+	// when the header is written.
+	unknownSize = 0xffffffff
+	// maxChunkRead the largest non-data chunk read into memory, bigger ones
+	// are skipped.
+	maxChunkRead = 1 << 20
+)
+
+// Reader decodes .wav files.
+type Reader struct {
+	r      io.Reader
+	format Format
+	// remaining bytes of the data chunk, -1 if unknown, see Read.
+	remaining int64
+	dataSize  int64
+	padded    bool
+	info      map[string]string
+	// markers by cue point id.
+	markers map[uint32]*Marker
+	err     error
+	// br is r, buffered, for streamed data, for Read to look for the chunks
+	// following it. pos bytes of it were read.
+	br  *bufio.Reader
+	pos int64
+}
+
+// NewReader reads the RIFF header of r, up to the start of the data chunk.
+// Samples can then be read through the returned *Reader.
+func NewReader(r io.Reader) (*Reader, error) {
+	wr := &Reader{r: r, info: make(map[string]string)}
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if string(riff[0:4]) != "RIFF" {
+		return nil, ErrNotRIFF
+	}
+	if string(riff[8:12]) != "WAVE" {
+		return nil, ErrNotWAVE
+	}
+
+	hasFormat := false
+	for {
+		id, size, err := wr.readChunkHeader()
+		if err == io.EOF {
+			return nil, ErrNoData
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch id {
+		case "fmt ":
+			if size < 16 || size > maxChunkRead {
+				return nil, fmt.Errorf("%w: fmt chunk of %d bytes", ErrMalformed, size)
+			}
+			b, err := wr.readChunk(size)
+			if err != nil {
+				return nil, err
+			}
+			if err := wr.parseFormat(b); err != nil {
+				return nil, err
+			}
+			hasFormat = true
+		case "data":
+			if !hasFormat {
+				return nil, ErrNoFormat
+			}
+			wr.dataSize = int64(size)
+			wr.remaining = int64(size)
+			wr.padded = size%2 == 1
+			if size == unknownSize {
+				wr.dataSize, wr.remaining = -1, -1
+				wr.padded = false
+				wr.br = bufio.NewReader(wr.r)
+				wr.r = wr.br
+			}
+			return wr, nil
+		case "LIST":
+			if size > maxChunkRead {
+				if err := wr.skip(size); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			b, err := wr.readChunk(size)
+			if err != nil {
+				return nil, err
+			}
+			wr.parseList(b)
+		case "cue ":
+			if size > maxChunkRead {
+				if err := wr.skip(size); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			b, err := wr.readChunk(size)
+			if err != nil {
+				return nil, err
+			}
+			wr.parseCue(b)
+		default:
+			if err := wr.skip(size); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// readChunkHeader reads a chunk's id and size. Returns io.EOF if there are
+// no more chunks.
+func (wr *Reader) readChunkHeader() (string, uint32, error) {
+	var h [8]byte
+	n, err := io.ReadFull(wr.r, h[:])
+	if err != nil {
+		if n == 0 {
+			return "", 0, io.EOF
+		}
+		return "", 0, io.ErrUnexpectedEOF
+	}
+	return string(h[0:4]), binary.LittleEndian.Uint32(h[4:8]), nil
+}
+
+// readChunk reads a chunk's body, along with its padding byte, if any.
+func (wr *Reader) readChunk(size uint32) ([]byte, error) {
+	b := make([]byte, size+size%2)
+	if _, err := io.ReadFull(wr.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return b[:size], nil
+}
+
+func (wr *Reader) skip(size uint32) error {
+	n := int64(size) + int64(size%2)
+	if _, err := io.CopyN(ioutil.Discard, wr.r, n); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
+
+func (wr *Reader) parseFormat(b []byte) error {
+	f := Format{
+		Tag:           FormatTag(binary.LittleEndian.Uint16(b[0:2])),
+		Channels:      int(binary.LittleEndian.Uint16(b[2:4])),
+		SampleRate:    int32(binary.LittleEndian.Uint32(b[4:8])),
+		BitsPerSample: int(binary.LittleEndian.Uint16(b[14:16])),
+	}
+	blockAlign := int(binary.LittleEndian.Uint16(b[12:14]))
+	if f.Tag == Extensible {
+		// cbSize(2) validBits(2) channelMask(4) subFormat GUID(16), the
+		// first 2 bytes of the GUID being the actual format tag.
+		if len(b) < 40 {
+			return fmt.Errorf("%w: short extensible fmt chunk", ErrMalformed)
+		}
+		f.Tag = FormatTag(binary.LittleEndian.Uint16(b[24:26]))
+	}
+	if err := f.validate(); err != nil {
+		return err
+	}
+	if blockAlign != f.blockAlign() {
+		return fmt.Errorf("%w: block align %d, expected %d", ErrMalformed, blockAlign, f.blockAlign())
+	}
+	wr.format = f
+	return nil
+}
+
+// parseList reads the INFO entries, or adtl labels, of a LIST chunk. Other
+// list types are ignored.
+func (wr *Reader) parseList(b []byte) {
+	if len(b) >= 4 && string(b[0:4]) == "adtl" {
+		wr.parseAdtl(b[4:])
+		return
+	}
+	if len(b) < 4 || string(b[0:4]) != "INFO" {
+		return
+	}
+	b = b[4:]
+	for len(b) >= 8 {
+		id := string(b[0:4])
+		size := int(binary.LittleEndian.Uint32(b[4:8]))
+		b = b[8:]
+		if size > len(b) {
+			return
+		}
+		wr.info[id] = string(bytes.TrimRight(b[:size], "\x00"))
+		if size%2 == 1 && size < len(b) {
+			size++
+		}
+		b = b[size:]
+	}
+}
+
+// Format returns the format of the audio.
+func (wr *Reader) Format() Format {
+	return wr.format
+}
+
+// SampleRate in Hz.
+func (wr *Reader) SampleRate() int32 {
+	return wr.format.SampleRate
+}
+
+// Channels number of channels.
+func (wr *Reader) Channels() int {
+	return wr.format.Channels
+}
+
+// BitsPerSample bit depth of the samples.
+func (wr *Reader) BitsPerSample() int {
+	return wr.format.BitsPerSample
+}
+
+// Frames returns the number of frames (a sample for every channel) in the
+// data chunk, as stated by the header. Returns -1 for streamed files, whose
+// size is unknown.
+func (wr *Reader) Frames() int64 {
+	if wr.dataSize < 0 {
+		return -1
+	}
+	return wr.dataSize / int64(wr.format.blockAlign())
+}
+
+// Duration of the audio, as stated by the header. Returns 0 for streamed
+// files, whose size is unknown.
+func (wr *Reader) Duration() time.Duration {
+	frames := wr.Frames()
+	if frames < 0 {
+		return 0
+	}
+	return time.Duration(frames) * time.Second / time.Duration(wr.format.SampleRate)
+}
+
+// Info returns the entries of LIST INFO chunks found so far, e.g. "INAM"
+// (title) or "ISFT" (software). Chunks after the data are found once it has
+// been read.
+func (wr *Reader) Info() map[string]string {
+	return wr.info
+}
+
+// Read implements the io.Reader interface, reading the raw contents of the
+// data chunk. The data of streamed files ends at EOF, or at the first frame
+// that reads as the header of a LIST or cue chunk, taken as following it.
+func (wr *Reader) Read(p []byte) (int, error) {
+	if wr.err != nil {
+		return 0, wr.err
+	}
+	if wr.remaining < 0 {
+		p = wr.streamed(p)
+	}
+	if wr.remaining == 0 {
+		wr.err = io.EOF
+		wr.readTrailer()
+		return 0, io.EOF
+	}
+	if wr.remaining > 0 && int64(len(p)) > wr.remaining {
+		p = p[:wr.remaining]
+	}
+	n, err := wr.r.Read(p)
+	wr.pos += int64(n)
+	if wr.remaining > 0 {
+		wr.remaining -= int64(n)
+		if err == io.EOF && wr.remaining > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+	if err != nil {
+		wr.err = err
+	}
+	return n, err
+}
+
+// streamed returns the part of p to read of streamed data, up to the header
+// of a LIST or cue chunk at a frame, if any. If the data is at one, it ends.
+func (wr *Reader) streamed(p []byte) []byte {
+	if max := wr.br.Size() - 8; len(p) > max {
+		p = p[:max]
+	}
+	b, _ := wr.br.Peek(len(p) + 8)
+	block := int64(wr.format.blockAlign())
+	for i := int((block - wr.pos%block) % block); i < len(p) && i+8 <= len(b); i += int(block) {
+		id, size := string(b[i:i+4]), binary.LittleEndian.Uint32(b[i+4:i+8])
+		if (id == "LIST" || id == "cue ") && size <= maxChunkRead {
+			if i == 0 {
+				wr.remaining = 0
+			}
+			return p[:i]
+		}
+	}
+	return p
+}
+
+// readTrailer reads the chunks following the data, keeping LIST INFO
+// entries and markers. Errors are ignored, as trailing data is often junk.
+func (wr *Reader) readTrailer() {
+	if wr.padded {
+		var pad [1]byte
+		if _, err := io.ReadFull(wr.r, pad[:]); err != nil {
+			return
+		}
+	}
+	for {
+		id, size, err := wr.readChunkHeader()
+		if err != nil {
+			return
+		}
+		if id != "LIST" && id != "cue " || size > maxChunkRead {
+			if wr.skip(size) != nil {
+				return
+			}
+			continue
+		}
+		b, err := wr.readChunk(size)
+		if err != nil {
+			return
+		}
+		if id == "cue " {
+			wr.parseCue(b)
+		} else {
+			wr.parseList(b)
+		}
+	}
+}
+
+// readFrames reads the rest of the data chunk, whole frames only. If the
+// data is truncated, what could be read is returned along with
+// io.ErrUnexpectedEOF.
+func (wr *Reader) readFrames() ([]byte, error) {
+	b, err := ioutil.ReadAll(wr)
+	b = b[:len(b)-len(b)%wr.format.blockAlign()]
+	return b, err
+}
+
+// ReadFloats reads and decodes the rest of the samples into []float32 in
+// the range [-1, 1). Channels are interleaved.
+func (wr *Reader) ReadFloats() ([]float32, error) {
+	b, err := wr.readFrames()
+	size := wr.format.BitsPerSample / 8
+	out := make([]float32, len(b)/size)
+	for i := range out {
+		out[i] = float32(wr.decode(b[i*size : (i+1)*size]))
+	}
+	return out, err
+}
+
+// ReadSamples reads and decodes the rest of the samples into []int16.
+// Channels are interleaved. Samples deeper than 16 bit are truncated.
+func (wr *Reader) ReadSamples() ([]int16, error) {
+	b, err := wr.readFrames()
+	size := wr.format.BitsPerSample / 8
+	out := make([]int16, len(b)/size)
+	for i := range out {
+		s := b[i*size : (i+1)*size]
+		switch wr.format.Tag {
+		case PCM:
+			// most significant 2 bytes, or the unsigned 8 bit sample.
+			switch size {
+			case 1:
+				out[i] = int16(s[0]-128) << 8
+			default:
+				out[i] = int16(binary.LittleEndian.Uint16(s[size-2:]))
+			}
+		case ALaw:
+			out[i] = alawToLinear(s[0])
+		case MuLaw:
+			out[i] = mulawToLinear(s[0])
+		default:
+			out[i] = floatToInt16(wr.decode(s))
+		}
+	}
+	return out, err
+}
+
+// decode a single sample into [-1, 1).
+func (wr *Reader) decode(s []byte) float64 {
+	switch wr.format.Tag {
+	case PCM:
+		switch len(s) {
+		case 1:
+			return float64(int8(s[0]-128)) / (1 << 7)
+		case 2:
+			return float64(int16(binary.LittleEndian.Uint16(s))) / (1 << 15)
+		case 3:
+			v := int32(uint32(s[0])<<8|uint32(s[1])<<16|uint32(s[2])<<24) >> 8
+			return float64(v) / (1 << 23)
+		default:
+			return float64(int32(binary.LittleEndian.Uint32(s))) / (1 << 31)
+		}
+	case IEEEFloat:
+		if len(s) == 4 {
+			return float64(math.Float32frombits(binary.LittleEndian.Uint32(s)))
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(s))
+	case ALaw:
+		return float64(alawToLinear(s[0])) / (1 << 15)
+	default: // MuLaw
+		return float64(mulawToLinear(s[0])) / (1 << 15)
+	}
+}
+
+// floatToInt16 converts a [-1, 1) sample, clipping it if out of range.
+func floatToInt16(v float64) int16 {
+	v = math.Round(v * (1 << 15))
+	switch {
+	case v > math.MaxInt16:
+		return math.MaxInt16
+	case v < math.MinInt16, math.IsNaN(v):
+		return math.MinInt16
+	}
+	return int16(v)
+}
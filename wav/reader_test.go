@@ -0,0 +1,346 @@
+// Copyright 2020 djangulo. All rights reserved. Use of this source code is
+// governed by an MIT license that can be found in the LICENSE file.
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+// chunk builds a RIFF chunk, padded to an even size.
+func chunk(id string, body []byte) []byte {
+	b := make([]byte, 8, 8+len(body)+1)
+	copy(b, id)
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(body)))
+	b = append(b, body...)
+	if len(body)%2 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+// riff builds a RIFF/WAVE file out of chunks.
+func riff(chunks ...[]byte) []byte {
+	body := []byte("WAVE")
+	for _, c := range chunks {
+		body = append(body, c...)
+	}
+	return chunk("RIFF", body)
+}
+
+func fmtChunk(tag FormatTag, channels int, sampleRate int32, bits int) []byte {
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint16(b[0:], uint16(tag))
+	binary.LittleEndian.PutUint16(b[2:], uint16(channels))
+	binary.LittleEndian.PutUint32(b[4:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(b[8:], uint32(int(sampleRate)*channels*bits/8))
+	binary.LittleEndian.PutUint16(b[12:], uint16(channels*bits/8))
+	binary.LittleEndian.PutUint16(b[14:], uint16(bits))
+	return chunk("fmt ", b)
+}
+
+func extensibleChunk(tag FormatTag, channels int, sampleRate int32, bits int) []byte {
+	b := fmtChunk(Extensible, channels, sampleRate, bits)[8:]
+	ext := make([]byte, 24)
+	binary.LittleEndian.PutUint16(ext[0:], 22)
+	binary.LittleEndian.PutUint16(ext[2:], uint16(bits))
+	binary.LittleEndian.PutUint16(ext[8:], uint16(tag))
+	copy(ext[10:], "\x00\x00\x00\x00\x10\x00\x80\x00\x00\xaa\x00\x38\x9b\x71")
+	return chunk("fmt ", append(b, ext...))
+}
+
+func TestReader_roundTrip(t *testing.T) {
+	fh, err := ioutil.TempFile("", "go-espeak-wav-test-*.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(fh.Name())
+	defer fh.Close()
+	in := []int16{0, 1, -1, math.MaxInt16, math.MinInt16, 1000, -1000, 42}
+	w := NewWriter(fh, 22050)
+	if _, err := w.WriteSamples(in); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fh.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(fh)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.SampleRate() != 22050 {
+		t.Errorf("expected sample rate %d got %d", 22050, r.SampleRate())
+	}
+	if r.Channels() != 1 {
+		t.Errorf("expected %d channels got %d", 1, r.Channels())
+	}
+	if r.BitsPerSample() != 16 {
+		t.Errorf("expected %d bits per sample got %d", 16, r.BitsPerSample())
+	}
+	if r.Frames() != int64(len(in)) {
+		t.Errorf("expected %d frames got %d", len(in), r.Frames())
+	}
+	want := time.Duration(len(in)) * time.Second / 22050
+	if r.Duration() != want {
+		t.Errorf("expected duration %v got %v", want, r.Duration())
+	}
+	got, err := r.ReadSamples()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != len(in) {
+		t.Fatalf("expected %d samples got %d", len(in), len(got))
+	}
+	for i := range in {
+		if got[i] != in[i] {
+			t.Errorf("sample %d: expected %d got %d", i, in[i], got[i])
+		}
+	}
+}
+
+func TestReader_formats(t *testing.T) {
+	f32 := make([]byte, 8)
+	binary.LittleEndian.PutUint32(f32[0:], math.Float32bits(0.5))
+	binary.LittleEndian.PutUint32(f32[4:], math.Float32bits(-2))
+	f64 := make([]byte, 16)
+	binary.LittleEndian.PutUint64(f64[0:], math.Float64bits(0.5))
+	binary.LittleEndian.PutUint64(f64[8:], math.Float64bits(-0.25))
+
+	for _, tt := range []struct {
+		name    string
+		fmt     []byte
+		data    []byte
+		samples []int16
+		floats  []float32
+	}{
+		{
+			"8 bit PCM", fmtChunk(PCM, 1, 8000, 8),
+			[]byte{128, 255, 0},
+			[]int16{0, 127 << 8, math.MinInt16},
+			[]float32{0, 127.0 / 128, -1},
+		},
+		{
+			"16 bit stereo PCM", fmtChunk(PCM, 2, 8000, 16),
+			[]byte{0x00, 0x40, 0x00, 0xc0},
+			[]int16{1 << 14, -1 << 14},
+			[]float32{0.5, -0.5},
+		},
+		{
+			"24 bit PCM", fmtChunk(PCM, 1, 8000, 24),
+			[]byte{0xff, 0x00, 0x40, 0x00, 0x00, 0x80},
+			[]int16{1 << 14, math.MinInt16},
+			[]float32{float32(0x4000ff) / (1 << 23), -1},
+		},
+		{
+			"32 bit PCM", fmtChunk(PCM, 1, 8000, 32),
+			[]byte{0, 0, 0, 0x40},
+			[]int16{1 << 14},
+			[]float32{0.5},
+		},
+		{
+			"32 bit float", fmtChunk(IEEEFloat, 1, 8000, 32),
+			f32,
+			[]int16{1 << 14, math.MinInt16},
+			[]float32{0.5, -2},
+		},
+		{
+			"64 bit float", fmtChunk(IEEEFloat, 1, 8000, 64),
+			f64,
+			[]int16{1 << 14, -1 << 13},
+			[]float32{0.5, -0.25},
+		},
+		{
+			"A-law", fmtChunk(ALaw, 1, 8000, 8),
+			[]byte{0xd5, 0x55, 0xaa, 0x2a},
+			[]int16{8, -8, 32256, -32256},
+			[]float32{8.0 / (1 << 15), -8.0 / (1 << 15), 32256.0 / (1 << 15), -32256.0 / (1 << 15)},
+		},
+		{
+			"mu-law", fmtChunk(MuLaw, 1, 8000, 8),
+			[]byte{0xff, 0x7f, 0x80, 0x00},
+			[]int16{0, 0, 32124, -32124},
+			[]float32{0, 0, 32124.0 / (1 << 15), -32124.0 / (1 << 15)},
+		},
+		{
+			"extensible", extensibleChunk(PCM, 2, 8000, 16),
+			[]byte{0x00, 0x40, 0x00, 0xc0},
+			[]int16{1 << 14, -1 << 14},
+			[]float32{0.5, -0.5},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			file := riff(tt.fmt, chunk("data", tt.data))
+			r, err := NewReader(bytes.NewReader(file))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			samples, err := r.ReadSamples()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(samples) != len(tt.samples) {
+				t.Fatalf("expected %d samples got %d", len(tt.samples), len(samples))
+			}
+			for i := range samples {
+				if samples[i] != tt.samples[i] {
+					t.Errorf("sample %d: expected %d got %d", i, tt.samples[i], samples[i])
+				}
+			}
+
+			r, _ = NewReader(bytes.NewReader(file))
+			floats, err := r.ReadFloats()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(floats) != len(tt.floats) {
+				t.Fatalf("expected %d floats got %d", len(tt.floats), len(floats))
+			}
+			for i := range floats {
+				if floats[i] != tt.floats[i] {
+					t.Errorf("float %d: expected %v got %v", i, tt.floats[i], floats[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReader_chunks(t *testing.T) {
+	info := append([]byte("INFO"), chunk("INAM", []byte("title\x00"))...)
+	info = append(info, chunk("ISFT", []byte("espeak"))...)
+	trailer := append([]byte("INFO"), chunk("ICMT", []byte("odd"))...)
+	file := riff(
+		chunk("JUNK", []byte{1, 2, 3}),
+		fmtChunk(PCM, 1, 8000, 8),
+		chunk("LIST", info),
+		chunk("data", []byte{128, 129, 130}),
+		chunk("LIST", trailer),
+	)
+	r, err := NewReader(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := r.Info()["INAM"]; got != "title" {
+		t.Errorf("expected INAM %q got %q", "title", got)
+	}
+	if got := r.Info()["ISFT"]; got != "espeak" {
+		t.Errorf("expected ISFT %q got %q", "espeak", got)
+	}
+	samples, err := r.ReadSamples()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(samples) != 3 {
+		t.Errorf("expected %d samples got %d", 3, len(samples))
+	}
+	if got := r.Info()["ICMT"]; got != "odd" {
+		t.Errorf("expected ICMT %q got %q", "odd", got)
+	}
+}
+
+func TestReader_streamed(t *testing.T) {
+	file := riff(fmtChunk(PCM, 1, 8000, 16))
+	file = append(file, "data\xff\xff\xff\xff\x01\x00\x02\x00"...)
+	r, err := NewReader(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Frames() != -1 || r.Duration() != 0 {
+		t.Errorf("expected unknown length, got %d frames, %v", r.Frames(), r.Duration())
+	}
+	samples, err := r.ReadSamples()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(samples) != 2 || samples[0] != 1 || samples[1] != 2 {
+		t.Errorf("expected [1 2] got %v", samples)
+	}
+}
+
+func TestReader_streamedTrailer(t *testing.T) {
+	file := riff(fmtChunk(PCM, 1, 8000, 16))
+	file = append(file, "data\xff\xff\xff\xff"...)
+	// enough samples to take several reads.
+	for i := 0; i < 10000; i++ {
+		file = append(file, byte(i), byte(i>>8))
+	}
+	file = append(file, markerChunks([]Marker{{Position: 5, Label: "five"}})...)
+	file = append(file, chunk("LIST", append([]byte("INFO"), chunk("ICMT", []byte("streamed"))...))...)
+	r, err := NewReader(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	samples, err := r.ReadSamples()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(samples) != 10000 || samples[9999] != 9999 {
+		t.Errorf("expected 10000 samples, the last 9999, got %d", len(samples))
+	}
+	if got := r.Info()["ICMT"]; got != "streamed" {
+		t.Errorf("expected ICMT %q got %q", "streamed", got)
+	}
+	if got := r.Markers(); len(got) != 1 || got[0].Position != 5 || got[0].Label != "five" {
+		t.Errorf("expected marker five at 5 got %+v", got)
+	}
+}
+
+func TestReader_errors(t *testing.T) {
+	valid := riff(fmtChunk(PCM, 1, 8000, 16), chunk("data", []byte{1, 0, 2, 0}))
+	badAlign := fmtChunk(PCM, 1, 8000, 16)
+	badAlign[8+12] = 4
+	for _, tt := range []struct {
+		name string
+		in   []byte
+		want error
+	}{
+		{"empty", nil, io.ErrUnexpectedEOF},
+		{"short header", []byte("RIFF\x00"), io.ErrUnexpectedEOF},
+		{"not RIFF", []byte("RIFX\x00\x00\x00\x00WAVE"), ErrNotRIFF},
+		{"not WAVE", []byte("RIFF\x00\x00\x00\x00AVI "), ErrNotWAVE},
+		{"no data", riff(fmtChunk(PCM, 1, 8000, 16)), ErrNoData},
+		{"data before fmt", riff(chunk("data", []byte{0, 0})), ErrNoFormat},
+		{"truncated chunk header", valid[:16], io.ErrUnexpectedEOF},
+		{"truncated fmt", valid[:30], io.ErrUnexpectedEOF},
+		{"truncated skipped chunk", riff(chunk("JUNK", make([]byte, 10)))[:20], io.ErrUnexpectedEOF},
+		{"short fmt", riff(chunk("fmt ", make([]byte, 14))), ErrMalformed},
+		{"bad block align", riff(badAlign, chunk("data", nil)), ErrMalformed},
+		{"no channels", riff(fmtChunk(PCM, 0, 8000, 16)), ErrUnsupportedFormat},
+		{"no sample rate", riff(fmtChunk(PCM, 1, 0, 16)), ErrUnsupportedFormat},
+		{"12 bit PCM", riff(fmtChunk(PCM, 1, 8000, 12)), ErrUnsupportedFormat},
+		{"16 bit float", riff(fmtChunk(IEEEFloat, 1, 8000, 16)), ErrUnsupportedFormat},
+		{"16 bit mu-law", riff(fmtChunk(MuLaw, 1, 8000, 16)), ErrUnsupportedFormat},
+		{"ADPCM", riff(fmtChunk(2, 1, 8000, 4)), ErrUnsupportedFormat},
+		{"short extensible", riff(fmtChunk(Extensible, 1, 8000, 16)), ErrMalformed},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewReader(bytes.NewReader(tt.in))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected %v got %v", tt.want, err)
+			}
+		})
+	}
+
+	t.Run("truncated data", func(t *testing.T) {
+		r, err := NewReader(bytes.NewReader(valid[:len(valid)-1]))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		samples, err := r.ReadSamples()
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("expected %v got %v", io.ErrUnexpectedEOF, err)
+		}
+		if len(samples) != 1 || samples[0] != 1 {
+			t.Errorf("expected the whole samples read, got %v", samples)
+		}
+	})
+}
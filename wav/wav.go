@@ -1,7 +1,7 @@
 // Copyright 2020 djangulo. All rights reserved. Use of this source code is
 // governed by an MIT license that can be found in the LICENSE file.
 
-//Package wav implements basic utilities for writing .wav files.
+//Package wav implements basic utilities for reading and writing .wav files.
 // See http://www.topherlee.com/software/pcm-tut-wavformat.html for .wav
 // documentation, and
 // 	https://github.com/mondhs/espeak-sample/blob/master/audacityLabelSpeak.cpp
@@ -10,7 +10,11 @@ package wav
 
 import (
 	"encoding/binary"
+	"errors"
 	"io"
+	"math"
+
+	"github.com/djangulo/go-espeak/resample"
 )
 
 type wavHeader [44]byte
@@ -44,10 +48,6 @@ func (w *wavHeader) littleEndianInt32ToBytesBinary(pos int, value int32) {
 	binary.LittleEndian.PutUint32(w[pos:(pos+4)], uint32(value))
 }
 
-func (w *wavHeader) writeSize(size int32) {
-	w.littleEndianInt32ToBytes(4, size)
-}
-
 func (w *wavHeader) writeSampleRate(rate int32) {
 	w.littleEndianInt32ToBytes(24, rate)
 }
@@ -55,45 +55,267 @@ func (w *wavHeader) writeByteRate(rate int32) {
 	w.littleEndianInt32ToBytes(28, rate)
 }
 
-func (w *wavHeader) writeDataBytes(bytes int32) {
-	w.littleEndianInt32ToBytes(40, bytes)
-}
-
-// Writer has utilities for writing to .wav files.
+// Writer writes .wav files incrementally. The header is written along with
+// the first samples, with placeholder sizes that Close fixes, seeking back
+// if the underlying io.Writer is an io.WriteSeeker. Sinks that can't seek,
+// like an HTTP response, get sizes of 0xFFFFFFFF instead, which readers
+// take as "read to the end of the stream".
 type Writer struct {
 	out          io.Writer
 	err          error
 	bytesWritten uint64
-	sampleRate   int32
+	dataBytes    uint64
+	format       Format
+	buf          []byte
+	header       bool
+	closed       bool
+	// rs converts WriteSamples and WriteFloats input to the format's
+	// sample rate, if set.
+	rs *resample.Resampler
+	// resumed the Writer was returned by Resume, and hasn't written since.
+	resumed bool
+	// factPos and dataPos offsets of the fact and data sizes in the header.
+	factPos int
+	dataPos int
+	// seeker is out, if it can seek. start is the offset of the header.
+	seeker io.WriteSeeker
+	start  int64
+	// markers written after the data, see AddMarkers.
+	markers []Marker
 }
 
-// NewWriter returns a *Writer.
+// Errors
+var (
+	// ErrClosed the Writer was closed.
+	ErrClosed = errors.New("wav: writer is closed")
+	// ErrWriting the operation must be done before writing.
+	ErrWriting = errors.New("wav: writer has started writing")
+)
+
+// NewWriter returns a *Writer of 16 bit mono PCM, espeak's output. Close
+// must be called once all samples are written.
 func NewWriter(w io.Writer, sampleRate int32) *Writer {
-	return &Writer{out: w, sampleRate: sampleRate}
+	return &Writer{out: w, format: PCM16(sampleRate)}
+}
+
+// NewFormatWriter returns a *Writer of audio in format f. Samples passed to
+// WriteSamples and WriteFloats are converted to it. Close must be called
+// once all samples are written.
+func NewFormatWriter(w io.Writer, f Format) (*Writer, error) {
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	return &Writer{out: w, format: f}, nil
+}
+
+// Resume returns a *Writer appending to a .wav file of format f, as written
+// by a Writer that didn't get to Close, e.g. because the process crashed.
+// Its header starts at w's current offset, and the first dataBytes of its
+// data chunk are kept. w is positioned past them. Anything after them is
+// cut off if w has a Truncate method, like *os.File does, or else it's only
+// overwritten, and what's left past the end of the new file is the caller's
+// to truncate. ResampleFrom may still be called before writing.
+func Resume(w io.WriteSeeker, f Format, dataBytes uint64) (*Writer, error) {
+	if err := f.validate(); err != nil {
+		return nil, err
+	}
+	start, err := w.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	h, factPos, dataPos := f.header()
+	dataBytes -= dataBytes % uint64(f.blockAlign())
+	size := uint64(len(h)) + dataBytes
+	if _, err := w.Seek(start+int64(size), io.SeekStart); err != nil {
+		return nil, err
+	}
+	if t, ok := w.(interface{ Truncate(int64) error }); ok {
+		if err := t.Truncate(start + int64(size)); err != nil {
+			return nil, err
+		}
+	}
+	return &Writer{
+		out:          w,
+		format:       f,
+		header:       true,
+		factPos:      factPos,
+		dataPos:      dataPos,
+		seeker:       w,
+		start:        start,
+		bytesWritten: size,
+		dataBytes:    dataBytes,
+		resumed:      true,
+	}, nil
+}
+
+// Format returns the format of the audio written.
+func (w *Writer) Format() Format {
+	return w.format
 }
 
-// Write implements the io.Writer interface.
+// ResampleFrom converts the samples passed to WriteSamples and WriteFloats
+// from rate to the format's sample rate, with quality q. Must be called
+// before writing.
+func (w *Writer) ResampleFrom(rate int32, q resample.Quality) error {
+	if w.header && !w.resumed {
+		return ErrWriting
+	}
+	if rate == w.format.SampleRate {
+		w.rs = nil
+		return nil
+	}
+	rs, err := resample.New(rate, w.format.SampleRate, w.format.Channels, q)
+	if err != nil {
+		return err
+	}
+	w.rs = rs
+	return nil
+}
+
+// Write implements the io.Writer interface, writing data, already in the
+// Writer's format and sample rate, to the data chunk. The header is written
+// first, if it hasn't been.
 func (w *Writer) Write(data []byte) (int, error) {
-	if w.err != nil {
-		return 0, w.err
+	if w.closed {
+		return 0, ErrClosed
 	}
-	n, err := w.out.Write(data)
-	w.bytesWritten += uint64(n)
-	w.err = err
+	w.resumed = false
+	if !w.header {
+		if err := w.writeHeader(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.write(data)
+	w.dataBytes += uint64(n)
 	return n, err
 }
 
-// WriteSamples writes the .wav header and an []int16 to the file.
+// WriteSamples writes data to the data chunk, returning the number of bytes
+// written so far, header included. It can be called any number of times.
+// Samples are interleaved if the format has several channels, see Upmix.
 func (w *Writer) WriteSamples(data []int16) (uint64, error) {
-	h := newWavHeader()
-	h.writeSampleRate(w.sampleRate)
-	h.writeByteRate(w.sampleRate * 2)
-	h.writeDataBytes(int32(len(data) * 2))
-	h.writeSize(int32(len(data)*2+binary.Size(h)) - 8)
+	if w.rs != nil {
+		data = w.rs.ProcessInt16(data)
+	}
+	w.buf = w.format.encodeInt16(w.buf[:0], data)
+	_, err := w.Write(w.buf)
+	return w.bytesWritten, err
+}
+
+// WriteFloats is like WriteSamples, for samples in the range [-1, 1).
+func (w *Writer) WriteFloats(data []float32) (uint64, error) {
+	if w.rs != nil {
+		data = w.rs.Process(data)
+	}
+	w.buf = w.format.encodeFloat32(w.buf[:0], data)
+	_, err := w.Write(w.buf)
+	return w.bytesWritten, err
+}
 
-	binary.Write(w, binary.LittleEndian, h)
-	w.bytesWritten += uint64(binary.Size(h))
-	binary.Write(w, binary.LittleEndian, data)
+// Size returns the number of bytes written to the underlying io.Writer,
+// header included.
+func (w *Writer) Size() uint64 {
+	return w.bytesWritten
+}
+
+// DataSize returns the number of bytes written to the data chunk.
+func (w *Writer) DataSize() uint64 {
+	return w.dataBytes
+}
+
+// Flush writes the samples held back by the resampler, if any, as if the
+// input ended there, so that DataSize accounts for every sample written.
+// Samples written afterwards are resampled as a new stream.
+func (w *Writer) Flush() error {
+	if w.closed {
+		return ErrClosed
+	}
+	if w.rs == nil {
+		return w.err
+	}
+	w.buf = w.format.encodeFloat32(w.buf[:0], w.rs.Flush())
+	_, err := w.Write(w.buf)
+	return err
+}
+
+// Close writes the samples held back by the resampler, if any, pads the
+// data chunk to an even size, writes the markers, if any, and fixes the
+// sizes in the header. The header is written if no samples were. The
+// underlying io.Writer is not closed.
+func (w *Writer) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.Flush()
+	if !w.header {
+		w.writeHeader()
+	}
+	w.closed = true
+	if w.dataBytes%2 == 1 {
+		w.write([]byte{0})
+	}
+	w.writeMarkers()
+	if w.err != nil || w.seeker == nil {
+		return w.err
+	}
+
+	riffSize := w.bytesWritten - 8
+	dataSize := w.dataBytes
+	frames := w.dataBytes / uint64(w.format.blockAlign())
+	if riffSize > math.MaxUint32 {
+		riffSize, dataSize, frames = unknownSize, unknownSize, unknownSize
+	}
+	w.patch(4, riffSize)
+	w.patch(w.dataPos, dataSize)
+	if w.factPos > 0 {
+		w.patch(w.factPos, frames)
+	}
+	if w.err != nil {
+		return w.err
+	}
+	_, w.err = w.seeker.Seek(w.start+int64(w.bytesWritten), io.SeekStart)
+	return w.err
+}
 
-	return w.bytesWritten, w.err
+// patch overwrites the header's size at pos.
+func (w *Writer) patch(pos int, size uint64) {
+	if w.err != nil {
+		return
+	}
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], uint32(size))
+	if _, w.err = w.seeker.Seek(w.start+int64(pos), io.SeekStart); w.err == nil {
+		_, w.err = w.seeker.Write(b[:])
+	}
+}
+
+func (w *Writer) writeHeader() error {
+	w.header = true
+	var h []byte
+	h, w.factPos, w.dataPos = w.format.header()
+	if ws, ok := w.out.(io.WriteSeeker); ok {
+		// an *os.File may be a pipe, which can't seek.
+		if off, err := ws.Seek(0, io.SeekCurrent); err == nil {
+			w.seeker, w.start = ws, off
+		}
+	}
+	if w.seeker == nil {
+		for _, pos := range []int{4, w.factPos, w.dataPos} {
+			if pos > 0 {
+				binary.LittleEndian.PutUint32(h[pos:], unknownSize)
+			}
+		}
+	}
+	_, err := w.write(h)
+	return err
+}
+
+func (w *Writer) write(data []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.out.Write(data)
+	w.bytesWritten += uint64(n)
+	w.err = err
+	return n, err
 }
@@ -3,11 +3,16 @@
 package wav
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/djangulo/go-espeak/resample"
 )
 
 func BenchmarkInt32ToBytes(b *testing.B) {
@@ -37,6 +42,7 @@ func TestWriter_WriteSamples(t *testing.T) {
 	w := NewWriter(fh, 44100)
 	in := []int16{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
 	w.WriteSamples(in)
+	w.Close()
 	fh.Close()
 
 	fh, err = os.Open(filepath.Join(tmp, "test-wav.wav"))
@@ -56,3 +62,328 @@ func TestWriter_WriteSamples(t *testing.T) {
 		t.Errorf("expected byte 40 to be %v, instead got %v", len(in), got[40])
 	}
 }
+
+func TestWriter_incremental(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "go-espeak-wav-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	fh, err := os.Create(filepath.Join(tmp, "test-wav.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fh.Close()
+
+	w := NewWriter(fh, 22050)
+	in := []int16{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	for i := 0; i < len(in); i += 3 {
+		end := i + 3
+		if end > len(in) {
+			end = len(in)
+		}
+		if _, err := w.WriteSamples(in[i:end]); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := w.WriteSamples(in); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected %v got %v", ErrClosed, err)
+	}
+
+	if _, err := fh.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 44+len(in)*2 {
+		t.Fatalf("expected %d bytes got %d", 44+len(in)*2, len(b))
+	}
+	if got := binary.LittleEndian.Uint32(b[4:]); got != uint32(len(b)-8) {
+		t.Errorf("expected RIFF size %d got %d", len(b)-8, got)
+	}
+	if got := binary.LittleEndian.Uint32(b[40:]); got != uint32(len(in)*2) {
+		t.Errorf("expected data size %d got %d", len(in)*2, got)
+	}
+	r, err := NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := r.ReadSamples()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := range in {
+		if got[i] != in[i] {
+			t.Errorf("sample %d: expected %d got %d", i, in[i], got[i])
+		}
+	}
+}
+
+func TestWriter_Close(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		write    func(w *Writer)
+		size     int
+		riffSize uint32
+		dataSize uint32
+	}{
+		{"unseekable", func(w *Writer) { w.WriteSamples([]int16{1, 2}) }, 48, unknownSize, unknownSize},
+		{"empty", func(w *Writer) {}, 44, unknownSize, unknownSize},
+		{"odd size", func(w *Writer) { w.Write([]byte{1, 2, 3}) }, 48, unknownSize, unknownSize},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf, 22050)
+			tt.write(w)
+			if err := w.Close(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			b := buf.Bytes()
+			if len(b) != tt.size {
+				t.Fatalf("expected %d bytes got %d", tt.size, len(b))
+			}
+			if got := binary.LittleEndian.Uint32(b[4:]); got != tt.riffSize {
+				t.Errorf("expected RIFF size %#x got %#x", tt.riffSize, got)
+			}
+			if got := binary.LittleEndian.Uint32(b[40:]); got != tt.dataSize {
+				t.Errorf("expected data size %#x got %#x", tt.dataSize, got)
+			}
+			if _, err := NewReader(bytes.NewReader(b)); err != nil {
+				t.Errorf("unexpected error reading the output: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewFormatWriter(t *testing.T) {
+	in := []int16{0, 1 << 14, -1 << 14, 1000, -1000, 32767, -32768}
+	for _, tt := range []struct {
+		name string
+		f    Format
+		// headerSize size of the header, fmt extension and fact included.
+		headerSize int
+		// exact whether samples survive the round trip unchanged.
+		exact bool
+	}{
+		{"8 bit PCM", Format{PCM, 1, 8000, 8}, 44, false},
+		{"16 bit stereo PCM", Format{PCM, 2, 44100, 16}, 44, true},
+		{"24 bit PCM", Format{PCM, 1, 48000, 24}, 68, true},
+		{"32 bit PCM", Format{PCM, 1, 48000, 32}, 68, true},
+		{"32 bit float", Format{IEEEFloat, 1, 48000, 32}, 58, true},
+		{"64 bit float", Format{IEEEFloat, 6, 48000, 64}, 80, true},
+		{"A-law", Format{ALaw, 1, 8000, 8}, 58, false},
+		{"mu-law", Format{MuLaw, 1, 8000, 8}, 58, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fh, err := ioutil.TempFile("", "go-espeak-wav-test-*.wav")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(fh.Name())
+			defer fh.Close()
+			w, err := NewFormatWriter(fh, tt.f)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			samples := Upmix(in, tt.f.Channels)
+			if _, err := w.WriteSamples(samples); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			size := tt.headerSize + len(samples)*tt.f.BitsPerSample/8
+			size += size % 2
+			if w.Size() != uint64(size) {
+				t.Errorf("expected %d bytes got %d", size, w.Size())
+			}
+
+			fh.Seek(0, io.SeekStart)
+			r, err := NewReader(fh)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if r.Format() != tt.f {
+				t.Errorf("expected format %+v got %+v", tt.f, r.Format())
+			}
+			if r.Frames() != int64(len(in)) {
+				t.Errorf("expected %d frames got %d", len(in), r.Frames())
+			}
+			got, err := r.ReadSamples()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(samples) {
+				t.Fatalf("expected %d samples got %d", len(samples), len(got))
+			}
+			for i := range samples {
+				diff := int(got[i]) - int(samples[i])
+				if (tt.exact && diff != 0) || diff > 1024 || diff < -1024 {
+					t.Errorf("sample %d: expected %d got %d", i, samples[i], got[i])
+				}
+			}
+		})
+	}
+
+	t.Run("fact chunk", func(t *testing.T) {
+		var buf bytes.Buffer
+		w, _ := NewFormatWriter(&buf, Format{MuLaw, 1, 8000, 8})
+		w.WriteSamples(in)
+		w.Close()
+		b := buf.Bytes()
+		if string(b[38:42]) != "fact" {
+			t.Fatalf("expected a fact chunk, got %q", b[38:42])
+		}
+		if got := binary.LittleEndian.Uint32(b[46:]); got != unknownSize {
+			t.Errorf("expected unknown fact size on an unseekable writer, got %d", got)
+		}
+	})
+
+	t.Run("WriteFloats", func(t *testing.T) {
+		var buf bytes.Buffer
+		w, _ := NewFormatWriter(&buf, Format{PCM, 1, 8000, 24})
+		w.WriteFloats([]float32{0.5, -1, 2})
+		w.Close()
+		data := buf.Bytes()[68:77]
+		want := []byte{0, 0, 0x40, 0, 0, 0x80, 0xff, 0xff, 0x7f}
+		if !bytes.Equal(data, want) {
+			t.Errorf("expected %x got %x", want, data)
+		}
+	})
+
+	for _, f := range []Format{
+		{PCM, 1, 8000, 12},
+		{IEEEFloat, 1, 8000, 16},
+		{MuLaw, 0, 8000, 8},
+		{Extensible, 1, 8000, 16},
+	} {
+		if _, err := NewFormatWriter(ioutil.Discard, f); !errors.Is(err, ErrUnsupportedFormat) {
+			t.Errorf("%+v: expected %v got %v", f, ErrUnsupportedFormat, err)
+		}
+	}
+}
+
+func TestWriter_ResampleFrom(t *testing.T) {
+	fh, err := ioutil.TempFile("", "go-espeak-wav-test-*.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(fh.Name())
+	defer fh.Close()
+	w, err := NewFormatWriter(fh, Format{MuLaw, 2, 8000, 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.ResampleFrom(22050, resample.Default); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	in := Upmix(make([]int16, 22050), 2)
+	for i := 0; i < len(in); i += 1000 {
+		end := i + 1000
+		if end > len(in) {
+			end = len(in)
+		}
+		w.WriteSamples(in[i:end])
+	}
+	if err := w.ResampleFrom(16000, resample.Default); !errors.Is(err, ErrWriting) {
+		t.Errorf("expected %v got %v", ErrWriting, err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fh.Seek(0, io.SeekStart)
+	r, err := NewReader(fh)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Frames() != 8000 {
+		t.Errorf("expected %d frames got %d", 8000, r.Frames())
+	}
+}
+
+func TestWriter_Flush(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, 8000)
+	if err := w.ResampleFrom(22050, resample.Default); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w.WriteSamples(make([]int16, 2205))
+	held := w.DataSize()
+	if err := w.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// 100ms at 8kHz.
+	if w.DataSize() != 1600 || held >= 1600 {
+		t.Errorf("expected %d bytes of data after %d got %d", 1600, held, w.DataSize())
+	}
+	if err := w.Flush(); err != nil || w.DataSize() != 1600 {
+		t.Errorf("expected nothing more to flush got %d bytes, %v", w.DataSize(), err)
+	}
+	w.WriteSamples(make([]int16, 2205))
+	w.Close()
+	if w.DataSize() != 3200 {
+		t.Errorf("expected %d bytes of data got %d", 3200, w.DataSize())
+	}
+	if err := w.Flush(); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected %v got %v", ErrClosed, err)
+	}
+}
+
+func TestResume(t *testing.T) {
+	fh, err := ioutil.TempFile("", "go-espeak-wav-test-*.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(fh.Name())
+	defer fh.Close()
+	f := Format{PCM, 1, 22050, 16}
+	w, _ := NewFormatWriter(fh, f)
+	w.WriteSamples([]int16{1, 2, 3})
+	// a crash mid-write: never closed, with a sample and a half of garbage.
+	fh.Write([]byte{9, 9, 9})
+
+	fh.Seek(0, io.SeekStart)
+	w, err = Resume(fh, f, 6)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.Size() != 50 {
+		t.Errorf("expected 50 bytes got %d", w.Size())
+	}
+	w.WriteSamples([]int16{4, 5})
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the garbage past the resumed data is gone.
+	fi, err := fh.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != int64(w.Size()) {
+		t.Errorf("expected a file of %d bytes got %d", w.Size(), fi.Size())
+	}
+
+	fh.Seek(0, io.SeekStart)
+	r, err := NewReader(fh)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := r.ReadSamples()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []int16{1, 2, 3, 4, 5}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("sample %d: expected %d got %d", i, want[i], got[i])
+		}
+	}
+}